@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/benchmark"
+)
+
+// runBench implements `monkey bench [-run regexp] [-benchtime d] file ...`.
+// It runs the benchmark functions of the scripts, each until the calls take
+// the benchmark time, and exits with status 1 if one fails. What the
+// scripts log goes to stderr, so that only the results go to stdout.
+func runBench(args []string) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	run := flags.String("run", "", "only run benchmarks whose names match this regular expression")
+	benchtime := flags.Duration("benchtime", time.Second, "run each benchmark for at least `d`")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		log.Println("usage: monkey bench [-run regexp] [-benchtime d] file ...")
+		return 2
+	}
+
+	log.SetFlags(0)
+
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			log.Println(err)
+			return 2
+		}
+	}
+
+	files, err := collectMonkeyFiles(flags.Args())
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	var results []benchmark.Result
+	status := 0
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+
+		benchmarks, err := benchmark.Load(string(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 1
+			continue
+		}
+
+		for _, b := range benchmarks {
+			if filter == nil || filter.MatchString(b.Name) {
+				results = append(results, benchmark.Run(b, *benchtime))
+			}
+		}
+	}
+
+	if err := benchmark.WriteText(os.Stdout, results); err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			status = 1
+		}
+	}
+	return status
+}
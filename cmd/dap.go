@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/dap"
+)
+
+// runDap implements `monkey dap [--listen address]`, a debug adapter speaking
+// over stdio, or over the first connection accepted on a loopback TCP
+// address.
+func runDap(args []string) int {
+	flags := flag.NewFlagSet("dap", flag.ContinueOnError)
+	address := flags.String("listen", "", "serve one client on this loopback TCP `address` (like :4711) instead of stdio")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	var out io.Writer = os.Stdout
+
+	if *address != "" {
+		listener, err := dap.Listen(*address)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		log.Printf("listening on %s", listener.Addr())
+
+		conn, err := listener.Accept()
+		_ = listener.Close()
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer conn.Close()
+		in, out = conn, conn
+	}
+
+	server := dap.NewServer()
+
+	// The program prints through the standard logger; send that to the client.
+	log.SetOutput(server.Output())
+
+	if err := server.Serve(in, out); err != nil {
+		log.SetOutput(os.Stderr)
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
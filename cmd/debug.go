@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/debugger"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+)
+
+// runDebug implements `monkey debug [--errors] file`, which runs the file
+// under an interactive debugger prompt.
+func runDebug(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	stopOnError := flags.Bool("errors", false, "pause where errors are raised")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		log.Println("usage: monkey debug [--errors] file")
+		return 2
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(data)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		for _, err := range p.Errors() {
+			fmt.Printf("%s:%d:%d: %s\n", flags.Arg(0), err.Line, err.Column, err.Message)
+		}
+		return 1
+	}
+
+	console := debugger.NewConsole(string(data), os.Stdin, os.Stdout)
+	console.Debugger().StopOnError = *stopOnError
+
+	evaluated := console.Debugger().Run(program, object.NewEnvironment())
+	if err, ok := evaluated.(object.Error); ok && err.Message != debugger.TerminatedMessage {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/formatter"
+)
+
+// runFmt implements `monkey fmt [--check | --write] [path ...]`.
+// Without paths it formats standard input. Directories are searched
+// recursively for .monkey files.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	check := flags.Bool("check", false, "list files whose formatting differs and exit with status 1")
+	write := flags.Bool("write", false, "write the result to the source files instead of stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *check && *write {
+		log.Println("fmt: --check and --write are mutually exclusive")
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		return formatSource("<stdin>", string(data), *check, false)
+	}
+
+	files, err := collectMonkeyFiles(flags.Args())
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	status := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Println(err)
+			status = 1
+			continue
+		}
+
+		if code := formatSource(file, string(data), *check, *write); code != 0 {
+			status = code
+		}
+	}
+
+	return status
+}
+
+func formatSource(name, source string, check, write bool) int {
+	formatted, err := formatter.Format(source)
+	if err != nil {
+		log.Printf("%s: %v\n", name, err)
+		return 1
+	}
+
+	switch {
+	case check:
+		if formatted != source {
+			fmt.Println(name)
+			return 1
+		}
+	case write:
+		if formatted != source {
+			if err := os.WriteFile(name, []byte(formatted), 0o644); err != nil {
+				log.Println(err)
+				return 1
+			}
+		}
+	default:
+		fmt.Print(formatted)
+	}
+
+	return 0
+}
+
+func collectMonkeyFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(file) == ".monkey" {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
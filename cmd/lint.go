@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/linter"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+)
+
+// fileDiagnostic is a lint result as printed by `monkey lint --json`.
+type fileDiagnostic struct {
+	File string `json:"file"`
+	linter.Diagnostic
+}
+
+// runLint implements `monkey lint [--json] path ...`. It exits with status 1
+// if any file has syntax errors or lint diagnostics.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print diagnostics as a JSON array")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	files, err := collectMonkeyFiles(flags.Args())
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	diagnostics := []fileDiagnostic{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+
+		for _, d := range lintSource(string(data)) {
+			diagnostics = append(diagnostics, fileDiagnostic{File: file, Diagnostic: d})
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diagnostics); err != nil {
+			log.Println(err)
+			return 1
+		}
+	} else {
+		for _, d := range diagnostics {
+			fmt.Printf("%s:%s\n", d.File, d.Diagnostic)
+		}
+	}
+
+	if len(diagnostics) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// lintSource reports syntax errors if the source does not parse,
+// and lint diagnostics otherwise.
+func lintSource(source string) []linter.Diagnostic {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		return linter.Lint(program)
+	}
+
+	diagnostics := make([]linter.Diagnostic, len(p.Errors()))
+	for i, err := range p.Errors() {
+		diagnostics[i] = linter.Diagnostic{
+			Line:     err.Line,
+			Column:   err.Column,
+			Severity: linter.Error,
+			Check:    "syntax",
+			Message:  err.Message,
+		}
+	}
+	return diagnostics
+}
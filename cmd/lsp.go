@@ -0,0 +1,17 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/lsp"
+)
+
+// runLsp implements `monkey lsp`, a language server speaking over stdio.
+func runLsp() int {
+	if err := lsp.NewServer().Serve(os.Stdin, os.Stdout); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
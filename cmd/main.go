@@ -4,10 +4,6 @@ import (
 	"log"
 	"os"
 
-	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
-	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
-	"github.com/timur-makarov/monkey-interpreter/internal/object"
-	"github.com/timur-makarov/monkey-interpreter/internal/parser"
 	"github.com/timur-makarov/monkey-interpreter/internal/repl"
 )
 
@@ -15,19 +11,25 @@ func main() {
 	args := os.Args
 
 	if len(args) > 1 {
-		data, err := os.ReadFile(args[1])
-		if err != nil {
-			panic(err)
-		}
-
-		l := lexer.New(string(data))
-		p := parser.New(l)
-		program := p.ParseProgram()
-		env := object.NewEnvironment()
-		evaluated := evaluator.Eval(program, env)
-
-		if evaluated.Type() == object.ErrorType {
-			log.Fatalln(evaluated)
+		switch args[1] {
+		case "fmt":
+			os.Exit(runFmt(args[2:]))
+		case "lint":
+			os.Exit(runLint(args[2:]))
+		case "lsp":
+			os.Exit(runLsp())
+		case "dap":
+			os.Exit(runDap(args[2:]))
+		case "test":
+			os.Exit(runTest(args[2:]))
+		case "debug":
+			os.Exit(runDebug(args[2:]))
+		case "bench":
+			os.Exit(runBench(args[2:]))
+		case "run":
+			os.Exit(runRun(args[2:]))
+		default:
+			os.Exit(runFile(args[1], runOptions{}))
 		}
 	} else {
 		log.Println("Enter your Monkey code:")
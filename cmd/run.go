@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/optimizer"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/profiler"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+	"github.com/timur-makarov/monkey-interpreter/internal/tracing"
+)
+
+// runOptions name the files runFile writes what it records to, if any.
+type runOptions struct {
+	profile  string
+	trace    string
+	spans    string
+	maxDepth int
+	// noOptimize runs the script as written, without optimizing it first.
+	noOptimize bool
+	// deterministic runs the tasks of the script one at a time, in an
+	// order drawn from seed.
+	deterministic bool
+	seed          uint64
+}
+
+// runRun implements `monkey run [--profile file] [--trace file] [--spans file] [--max-depth n] [--no-optimize] [--deterministic [--seed n]] script`.
+// With a profile file, it writes a pprof profile of the script there and a
+// report to stderr. The trace file gets a JSON line for every step of the
+// evaluation, the spans file a span for every function call. Deterministic
+// runs switch tasks only when one blocks or ends, and fail with an error
+// when all of them are blocked instead of hanging.
+func runRun(args []string) int {
+	var options runOptions
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.StringVar(&options.profile, "profile", "", "write a pprof profile to `file` and a report to stderr")
+	flags.StringVar(&options.trace, "trace", "", "write a JSON lines trace of the evaluation to `file`")
+	flags.StringVar(&options.spans, "spans", "", "write OpenTelemetry spans of the function calls to `file`")
+	flags.IntVar(&options.maxDepth, "max-depth", evaluator.MaxCallDepth, "fail with a stack overflow after `n` nested calls")
+	flags.BoolVar(&options.noOptimize, "no-optimize", false, "run the script without optimizing it")
+	flags.BoolVar(&options.deterministic, "deterministic", false, "run the tasks of the script one at a time, the same way on every run")
+	flags.Uint64Var(&options.seed, "seed", 0, "with --deterministic, run ready tasks in an order drawn from `n` instead of the order they became ready")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		log.Println("usage: monkey run [--profile file] [--trace file] [--spans file] [--max-depth n] [--no-optimize] [--deterministic [--seed n]] script")
+		return 2
+	}
+
+	return runFile(flags.Arg(0), options)
+}
+
+// runFile runs a script. What it logs is its output and goes to stdout;
+// syntax and runtime errors go to stderr and make it exit with status 1.
+func runFile(path string, options runOptions) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	l := lexer.New(string(data))
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) > 0 {
+		for _, err := range p.Errors() {
+			fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n", path, err.Line, err.Column, err.Message)
+		}
+		return 1
+	}
+
+	if !options.noOptimize {
+		program = optimizer.Optimize(program)
+	}
+	program = resolver.Bind(program)
+
+	log.SetFlags(0)
+	log.SetOutput(os.Stdout)
+
+	env := object.NewEnvironment()
+	if options.maxDepth != evaluator.MaxCallDepth {
+		evaluator.LimitCallDepth(env, options.maxDepth)
+	}
+
+	var prof *profiler.Profiler
+	if options.profile != "" {
+		prof = profiler.New(path)
+		prof.Attach(env)
+	}
+
+	tracers, err := startTracers(env, path, options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var scheduler *evaluator.Scheduler
+	if options.deterministic {
+		scheduler = evaluator.NewScheduler(options.seed)
+		evaluator.Schedule(env, scheduler)
+	}
+
+	evaluated := evaluator.Eval(program, env)
+	if scheduler != nil {
+		scheduler.Stop()
+	}
+
+	status := 0
+	if evaluated.Type() == object.ErrorType {
+		fmt.Fprintln(os.Stderr, evaluated)
+		status = 1
+	}
+
+	if err := tracers.close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if prof != nil {
+		prof.Stop()
+		if err := writeProfile(prof, options.profile, string(data)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	return status
+}
+
+// runTracers are the tracers of a run and the files they write to.
+type runTracers struct {
+	writer   *tracing.JSONWriter
+	exporter *tracing.SpanExporter
+	files    []*os.File
+}
+
+// startTracers creates the files of the tracers the options ask for and
+// makes them trace the evaluations in env.
+func startTracers(env *object.Environment, path string, options runOptions) (*runTracers, error) {
+	t := &runTracers{}
+	var tracers tracing.Multi
+
+	if options.trace != "" {
+		file, err := os.Create(options.trace)
+		if err != nil {
+			return nil, err
+		}
+		t.files = append(t.files, file)
+		t.writer = tracing.NewJSONWriter(file)
+		tracers = append(tracers, t.writer)
+	}
+
+	if options.spans != "" {
+		file, err := os.Create(options.spans)
+		if err != nil {
+			t.close()
+			return nil, err
+		}
+		t.files = append(t.files, file)
+		t.exporter = tracing.NewSpanExporter(file, path)
+		tracers = append(tracers, t.exporter)
+	}
+
+	if len(tracers) > 0 {
+		evaluator.Trace(env, tracers)
+	}
+	return t, nil
+}
+
+// close writes what is left to write and closes the files, returning the
+// first error.
+func (t *runTracers) close() error {
+	var errs []error
+
+	if t.writer != nil {
+		errs = append(errs, t.writer.Err())
+	}
+	if t.exporter != nil {
+		errs = append(errs, t.exporter.Close())
+	}
+	for _, file := range t.files {
+		errs = append(errs, file.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+func writeProfile(prof *profiler.Profiler, path, source string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := prof.WritePprof(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return prof.WriteText(os.Stderr, source)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/coverage"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/testrunner"
+)
+
+// runTest implements `monkey test [flags] [path ...]`. Directories are
+// searched for *_test.monkey files. It exits with status 1 if a test fails.
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	run := flags.String("run", "", "only run tests whose names match this regular expression")
+	parallel := flags.Int("parallel", 1, "number of tests to run at the same time")
+	format := flags.String("format", "text", "report format: text, tap or junit")
+	verbose := flags.Bool("v", false, "also list passed tests")
+	cover := flags.Bool("cover", false, "record the coverage of the test files")
+	coverProfile := flags.String("coverprofile", "lcov.info", "with -cover, write an lcov report to `file`")
+	coverHTML := flags.String("coverhtml", "coverage.html", "with -cover, write annotated sources to `file`")
+	deterministic := flags.Bool("deterministic", false, "run the tasks of each test one at a time, the same way on every run")
+	seed := flags.Uint64("seed", 0, "with -deterministic, run ready tasks in an order drawn from `n` instead of the order they became ready")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	options := testrunner.Options{Parallel: *parallel, Deterministic: *deterministic, Seed: *seed}
+	if *run != "" {
+		filter, err := regexp.Compile(*run)
+		if err != nil {
+			log.Println(err)
+			return 2
+		}
+		options.Filter = filter
+	}
+
+	files, err := collectTestFiles(flags.Args())
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	var profile *coverage.Profile
+	if *cover {
+		profile = coverage.New()
+		for _, file := range files {
+			profile.AddFile(file.Path, file.Source)
+		}
+		options.Prepare = func(test testrunner.Test, env *object.Environment) {
+			profile.Attach(test.File, env)
+		}
+	}
+
+	results := testrunner.Run(files, options)
+
+	switch *format {
+	case "text":
+		err = testrunner.WriteText(os.Stdout, results, *verbose)
+	case "tap":
+		err = testrunner.WriteTAP(os.Stdout, results)
+	case "junit":
+		err = testrunner.WriteJUnit(os.Stdout, results)
+	default:
+		log.Printf("unknown report format %q", *format)
+		return 2
+	}
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	if profile != nil {
+		if err := writeCoverage(profile, *coverProfile, *coverHTML); err != nil {
+			log.Println(err)
+			return 1
+		}
+	}
+
+	if testrunner.Failed(results) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writeCoverage writes the lcov and the HTML report of the coverage and a
+// summary to stderr, so that it does not mix with TAP or JUnit reports.
+func writeCoverage(profile *coverage.Profile, lcovPath, htmlPath string) error {
+	if err := writeFile(lcovPath, profile.WriteLcov); err != nil {
+		return err
+	}
+	if err := writeFile(htmlPath, profile.WriteHTML); err != nil {
+		return err
+	}
+	return profile.WriteSummary(os.Stderr)
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// collectTestFiles reads the test files among the paths, which default to
+// the current directory. Files named explicitly are read whatever their name.
+func collectTestFiles(paths []string) ([]testrunner.File, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	found, err := collectMonkeyFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := make(map[string]bool)
+	for _, path := range paths {
+		explicit[path] = true
+	}
+
+	var files []testrunner.File
+	for _, path := range found {
+		if !explicit[path] && !strings.HasSuffix(path, testrunner.FileSuffix) {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, testrunner.File{Path: path, Source: string(data)})
+	}
+
+	return files, nil
+}
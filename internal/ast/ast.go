@@ -3,7 +3,9 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/timur-makarov/monkey-interpreter/internal/token"
 )
@@ -39,6 +41,23 @@ func (p *Program) String() string {
 type Identifier struct {
 	Token token.Token
 	Value string
+	// Local is where the variable named is stored, if resolver.Bind found
+	// it declared in a function or a block. Other names are looked up by
+	// name when evaluated.
+	Local *Local
+}
+
+// Local locates a variable: in the environment Depth levels out from the
+// one an identifier is evaluated in, at index Slot.
+type Local struct {
+	Depth int
+	Slot  int
+}
+
+// Scope lists the names bound by a block or by the parameters of a function,
+// in the order of their slots.
+type Scope struct {
+	Names []string
 }
 
 func (i Identifier) TokenLiteral() string {
@@ -76,8 +95,9 @@ func (s String) String() string {
 }
 
 type Array struct {
-	Token token.Token
-	Items []Expression
+	Token   token.Token
+	Items   []Expression
+	Closing token.Token
 }
 
 func (a Array) TokenLiteral() string {
@@ -91,6 +111,9 @@ func (a Array) String() string {
 type HashTable struct {
 	Token token.Token
 	Items map[Expression]Expression
+	// Keys holds the keys of Items in source order.
+	Keys    []Expression
+	Closing token.Token
 }
 
 func (ht HashTable) TokenLiteral() string {
@@ -147,10 +170,256 @@ func (w While) String() string {
 	)
 }
 
+// For evaluates its body for every item of an array, a string, a hash
+// table or an iterator, destructured with the pattern. The names the
+// pattern binds are visible in the body only.
+type For struct {
+	Token    token.Token
+	Pattern  Node
+	Iterable Expression
+	Body     BlockStatement
+}
+
+func (f For) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f For) String() string {
+	return fmt.Sprintf("for (%s in %s) {%s}", f.Pattern, f.Iterable, f.Body)
+}
+
+// Spawn runs a call, or a function without arguments, as a task of its
+// own and evaluates to the task. The function and the arguments of the
+// call are evaluated before the task starts.
+type Spawn struct {
+	Token token.Token
+	Value Expression
+}
+
+func (s Spawn) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s Spawn) String() string {
+	return fmt.Sprintf("spawn %s", s.Value)
+}
+
+// Select waits until one of its cases can receive from or send to its
+// channel, does so and evaluates the result of the case. With a default
+// case, it evaluates that one instead of waiting.
+type Select struct {
+	Token token.Token
+	Cases []SelectCase
+}
+
+// SelectCase is `pattern = channel.receive() => result`, where the pattern
+// is optional, `channel.send(value) => result`, or `_ => result` for the
+// default case, whose Operation is nil. The names the pattern binds are
+// visible in the result only.
+type SelectCase struct {
+	Token     token.Token
+	Pattern   Node
+	Operation *Call
+	Result    Expression
+	// End is the last token of the result.
+	End token.Token
+	// Scope is the scope of the names the pattern binds, set by
+	// resolver.Bind.
+	Scope *Scope
+}
+
+// Channel is the expression of the channel the case receives from or
+// sends to.
+func (sc SelectCase) Channel() Expression {
+	return sc.Operation.Function.(FieldAccess).Left
+}
+
+// Sends reports whether the case sends to its channel rather than
+// receives from it.
+func (sc SelectCase) Sends() bool {
+	return sc.Operation.Function.(FieldAccess).Field.Value == "send"
+}
+
+func (s Select) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s Select) String() string {
+	cases := make([]string, len(s.Cases))
+	for i, c := range s.Cases {
+		switch {
+		case c.Operation == nil:
+			cases[i] = "_"
+		case c.Pattern != nil:
+			cases[i] = fmt.Sprintf("%s = %s", c.Pattern, c.Operation)
+		default:
+			cases[i] = c.Operation.String()
+		}
+		cases[i] += fmt.Sprintf(" => %s", c.Result)
+	}
+	return fmt.Sprintf("select {%s}", strings.Join(cases, ", "))
+}
+
+// Match evaluates the result of the first arm whose pattern matches the
+// subject and whose guard, if it has one, is true.
+type Match struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []MatchArm
+}
+
+// MatchArm is `pattern if guard => result`, without a guard if Guard is nil.
+// The names the pattern binds are visible in the guard and the result only.
+type MatchArm struct {
+	Token   token.Token
+	Pattern Node
+	Guard   Expression
+	Result  Expression
+	// End is the last token of the result.
+	End token.Token
+	// Scope is the scope of the names the pattern binds, set by
+	// resolver.Bind.
+	Scope *Scope
+}
+
+func (m Match) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m Match) String() string {
+	arms := make([]string, len(m.Arms))
+	for i, arm := range m.Arms {
+		arms[i] = arm.Pattern.String()
+		if arm.Guard != nil {
+			arms[i] += fmt.Sprintf(" if %s", arm.Guard)
+		}
+		arms[i] += fmt.Sprintf(" => %s", arm.Result)
+	}
+	return fmt.Sprintf("match (%s) {%s}", m.Subject, strings.Join(arms, ", "))
+}
+
+// ArrayPattern matches arrays item by item, and if it has a rest binds the
+// items left over: [first, second, ...rest].
+type ArrayPattern struct {
+	Token token.Token
+	Items []Node
+	Rest  *Identifier
+}
+
+func (ap ArrayPattern) TokenLiteral() string {
+	return ap.Token.Literal
+}
+
+func (ap ArrayPattern) String() string {
+	items := make([]string, len(ap.Items), len(ap.Items)+1)
+	for i, item := range ap.Items {
+		items[i] = item.String()
+	}
+	if ap.Rest != nil {
+		items = append(items, "..."+ap.Rest.Value)
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// HashTablePattern matches hash tables that have all of its keys, with
+// values matching theirs: {"name": name, version}, where a name alone
+// stands for the key of the name bound to its value.
+type HashTablePattern struct {
+	Token  token.Token
+	Keys   []String
+	Values []Node
+}
+
+func (hp HashTablePattern) TokenLiteral() string {
+	return hp.Token.Literal
+}
+
+func (hp HashTablePattern) String() string {
+	items := make([]string, len(hp.Keys))
+	for i, key := range hp.Keys {
+		items[i] = fmt.Sprintf("%s: %s", key, hp.Values[i])
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// DefaultPattern is an item of an array pattern or a value of a hash table
+// pattern with a default: the pattern is matched with the value of Default
+// if the array has no such item or the hash table no such key. Token is the
+// `=`.
+type DefaultPattern struct {
+	Token   token.Token
+	Pattern Node
+	Default Expression
+}
+
+func (dp DefaultPattern) TokenLiteral() string {
+	return dp.Token.Literal
+}
+
+func (dp DefaultPattern) String() string {
+	return fmt.Sprintf("%s = %s", dp.Pattern, dp.Default)
+}
+
+// Names returns the names a pattern binds, in the order they appear in. The
+// name _ binds nothing.
+func Names(pattern Node) []Identifier {
+	var names []Identifier
+	switch p := pattern.(type) {
+	case Identifier:
+		if p.Value != "_" {
+			names = append(names, p)
+		}
+	case DefaultPattern:
+		names = Names(p.Pattern)
+	case ArrayPattern:
+		for _, item := range p.Items {
+			names = append(names, Names(item)...)
+		}
+		if p.Rest != nil {
+			names = append(names, Names(*p.Rest)...)
+		}
+	case HashTablePattern:
+		for _, value := range p.Values {
+			names = append(names, Names(value)...)
+		}
+	}
+	return names
+}
+
+// Defaults returns the default expressions of a pattern, in the order they
+// appear in.
+func Defaults(pattern Node) []Expression {
+	var defaults []Expression
+	switch p := pattern.(type) {
+	case DefaultPattern:
+		defaults = append(Defaults(p.Pattern), p.Default)
+	case ArrayPattern:
+		for _, item := range p.Items {
+			defaults = append(defaults, Defaults(item)...)
+		}
+	case HashTablePattern:
+		for _, value := range p.Values {
+			defaults = append(defaults, Defaults(value)...)
+		}
+	}
+	return defaults
+}
+
 type Function struct {
-	Token      token.Token
-	Parameters []Identifier
-	Body       BlockStatement
+	Token token.Token
+	// Parameters are names or array and hash table patterns, which the
+	// arguments are destructured with, possibly with defaults for the
+	// arguments left out.
+	Parameters []Node
+	// Rest is bound to an array of the arguments after those of the
+	// parameters, if it is set.
+	Rest *Identifier
+	// Generator is set for fn*, whose calls return an iterator over the
+	// values the body yields.
+	Generator bool
+	Body      BlockStatement
+	// Scope is the scope of the parameters, set by resolver.Bind.
+	Scope *Scope
 }
 
 func (f Function) TokenLiteral() string {
@@ -158,7 +427,15 @@ func (f Function) TokenLiteral() string {
 }
 
 func (f Function) String() string {
-	return fmt.Sprintf("fn (%+v) {%s}", f.Parameters, f.Body)
+	parameters := f.Parameters
+	if f.Rest != nil {
+		parameters = append(slices.Clip(parameters), Identifier{Token: f.Rest.Token, Value: "..." + f.Rest.Value})
+	}
+	keyword := "fn"
+	if f.Generator {
+		keyword = "fn*"
+	}
+	return fmt.Sprintf("%s (%+v) {%s}", keyword, parameters, f.Body)
 }
 
 type Call struct {
@@ -167,6 +444,21 @@ type Call struct {
 	Arguments []Expression
 }
 
+// Spread is an argument of a call passing the items of an array as
+// arguments: f(...args).
+type Spread struct {
+	Token token.Token
+	Value Expression
+}
+
+func (s Spread) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s Spread) String() string {
+	return "..." + s.Value.String()
+}
+
 func (f Call) TokenLiteral() string {
 	return f.Token.Literal
 }
@@ -189,6 +481,43 @@ func (f AccessByExpression) String() string {
 	return fmt.Sprintf("(%s)[%s]", f.Left, f.Index)
 }
 
+// StructLiteral creates a value of the struct type named: Point{x: 1, y: 2}.
+type StructLiteral struct {
+	Token token.Token
+	Type  Identifier
+	// Fields names the fields given, in source order, and Values holds
+	// their values.
+	Fields []Identifier
+	Values []Expression
+}
+
+func (sl StructLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+
+func (sl StructLiteral) String() string {
+	fields := make([]string, len(sl.Fields))
+	for i, field := range sl.Fields {
+		fields[i] = fmt.Sprintf("%s: %s", field.Value, sl.Values[i])
+	}
+	return fmt.Sprintf("%s{%s}", sl.Type, strings.Join(fields, ", "))
+}
+
+// FieldAccess reads a field of a struct: point.x.
+type FieldAccess struct {
+	Token token.Token
+	Left  Expression
+	Field Identifier
+}
+
+func (fa FieldAccess) TokenLiteral() string {
+	return fa.Token.Literal
+}
+
+func (fa FieldAccess) String() string {
+	return fmt.Sprintf("(%s).%s", fa.Left, fa.Field)
+}
+
 type Prefix struct {
 	Token    token.Token
 	Operator string
@@ -218,10 +547,19 @@ func (i Infix) String() string {
 	return fmt.Sprintf("%s %s %s", i.Left, i.Operator, i.Right)
 }
 
+// LetStatement binds the value to Name, or destructures it with Pattern,
+// an array or hash table pattern, if Name is nil.
 type LetStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token
+	Name    *Identifier
+	Pattern Node
+	Value   Expression
+}
+
+// Constant reports whether the statement declares a constant, which cannot
+// be assigned to.
+func (ls LetStatement) Constant() bool {
+	return ls.Token.Type == token.CONST
 }
 
 func (ls LetStatement) TokenLiteral() string {
@@ -229,9 +567,33 @@ func (ls LetStatement) TokenLiteral() string {
 }
 
 func (ls LetStatement) String() string {
+	if ls.Name == nil && ls.Pattern != nil {
+		return fmt.Sprintf("%s %s = %+v", ls.Token.Literal, ls.Pattern, ls.Value)
+	}
 	return fmt.Sprintf("%s %s = %+v", ls.Token.Literal, ls.Name, ls.Value)
 }
 
+// StructStatement declares a struct type and the names of its fields:
+// struct Point { x, y }.
+type StructStatement struct {
+	Token   token.Token
+	Name    *Identifier
+	Fields  []Identifier
+	Closing token.Token
+}
+
+func (ss StructStatement) TokenLiteral() string {
+	return ss.Token.Literal
+}
+
+func (ss StructStatement) String() string {
+	fields := make([]string, len(ss.Fields))
+	for i, field := range ss.Fields {
+		fields[i] = field.Value
+	}
+	return fmt.Sprintf("struct %s { %s }", ss.Name, strings.Join(fields, ", "))
+}
+
 type ReturnStatement struct {
 	Token token.Token
 	Value Expression
@@ -245,6 +607,20 @@ func (rs ReturnStatement) String() string {
 	return fmt.Sprintf("%s %v", rs.Token.Literal, rs.Value)
 }
 
+// YieldStatement suspends the generator it is in, which produces the value.
+type YieldStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ys YieldStatement) TokenLiteral() string {
+	return ys.Token.Literal
+}
+
+func (ys YieldStatement) String() string {
+	return fmt.Sprintf("%s %v", ys.Token.Literal, ys.Value)
+}
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -261,6 +637,10 @@ func (es ExpressionStatement) String() string {
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	Closing    token.Token
+	// Scope is set by resolver.Bind. A block with an empty scope binds no
+	// names, so it is evaluated in the environment of the enclosing one.
+	Scope *Scope
 }
 
 func (bs BlockStatement) TokenLiteral() string {
@@ -274,3 +654,69 @@ func (bs BlockStatement) String() string {
 	}
 	return out.String()
 }
+
+// StartToken returns the token a statement or an expression starts with.
+func StartToken(node Node) token.Token {
+	switch n := node.(type) {
+	case LetStatement:
+		return n.Token
+	case StructStatement:
+		return n.Token
+	case ReturnStatement:
+		return n.Token
+	case YieldStatement:
+		return n.Token
+	case ExpressionStatement:
+		return n.Token
+	case BlockStatement:
+		return n.Token
+	case Identifier:
+		return n.Token
+	case Integer:
+		return n.Token
+	case String:
+		return n.Token
+	case Boolean:
+		return n.Token
+	case Array:
+		return n.Token
+	case HashTable:
+		return n.Token
+	case If:
+		return n.Token
+	case While:
+		return n.Token
+	case For:
+		return n.Token
+	case Match:
+		return n.Token
+	case Spawn:
+		return n.Token
+	case Select:
+		return n.Token
+	case ArrayPattern:
+		return n.Token
+	case HashTablePattern:
+		return n.Token
+	case DefaultPattern:
+		return StartToken(n.Pattern)
+	case Spread:
+		return n.Token
+	case Function:
+		return n.Token
+	case Prefix:
+		return n.Token
+	case Infix:
+		return StartToken(n.Left)
+	case Call:
+		return StartToken(n.Function)
+	case AccessByExpression:
+		return StartToken(n.Left)
+	case StructLiteral:
+		return n.Type.Token
+	case FieldAccess:
+		return StartToken(n.Left)
+	default:
+		return token.Token{}
+	}
+}
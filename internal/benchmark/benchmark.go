@@ -0,0 +1,158 @@
+// Package benchmark runs the benchmark functions of scripts repeatedly and
+// measures how long a call takes and how much it allocates.
+package benchmark
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/optimizer"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+)
+
+// Prefix starts the names of benchmark functions: functions without
+// parameters bound by a top-level let.
+const Prefix = "bench"
+
+// maxCalls bounds the number of calls a benchmark is run for.
+const maxCalls = 1_000_000_000
+
+// Benchmark is a benchmark function of a script that has been evaluated.
+type Benchmark struct {
+	Name string
+	Line int
+
+	env  *object.Environment
+	call *ast.Program
+}
+
+// Load parses a script, optimized as it would be run, evaluates it and
+// returns its benchmark functions in source order. The benchmarks share the
+// environment of the script.
+func Load(source string) ([]*Benchmark, error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) > 0 {
+		messages := make([]string, len(errors))
+		for i, err := range errors {
+			messages[i] = fmt.Sprintf("%d:%d: %s", err.Line, err.Column, err.Message)
+		}
+		return nil, fmt.Errorf("syntax errors:\n%s", strings.Join(messages, "\n"))
+	}
+
+	env := object.NewEnvironment()
+	if err, ok := evaluator.Eval(resolver.Bind(optimizer.Optimize(program)), env).(object.Error); ok {
+		return nil, fmt.Errorf("evaluating the file: %s", err.Message)
+	}
+
+	var benchmarks []*Benchmark
+	for _, statement := range program.Statements {
+		let, ok := statement.(ast.LetStatement)
+		if !ok || let.Name == nil || !strings.HasPrefix(let.Name.Value, Prefix) {
+			continue
+		}
+
+		if fn, ok := let.Value.(ast.Function); ok && len(fn.Parameters) == 0 {
+			benchmarks = append(benchmarks, &Benchmark{
+				Name: let.Name.Value,
+				Line: let.Token.Line,
+				env:  env,
+				call: &ast.Program{Statements: []ast.Statement{
+					ast.ExpressionStatement{Expression: ast.Call{Function: ast.Identifier{Value: let.Name.Value}}},
+				}},
+			})
+		}
+	}
+	return benchmarks, nil
+}
+
+// Call calls the benchmark function once and returns what it returned.
+func (b *Benchmark) Call() object.Object {
+	return evaluator.Eval(b.call, b.env)
+}
+
+// Result is the measurement of a benchmark: N calls took Duration in
+// total and allocated Allocations times, Bytes bytes, in the interpreter.
+type Result struct {
+	Benchmark   *Benchmark
+	N           int
+	Duration    time.Duration
+	Allocations uint64
+	Bytes       uint64
+	// Err is set if a call failed, and nothing was measured.
+	Err error
+}
+
+func (r Result) NsPerOp() int64 {
+	if r.N == 0 {
+		return 0
+	}
+	return r.Duration.Nanoseconds() / int64(r.N)
+}
+
+func (r Result) AllocationsPerOp() uint64 {
+	if r.N == 0 {
+		return 0
+	}
+	return r.Allocations / uint64(r.N)
+}
+
+func (r Result) BytesPerOp() uint64 {
+	if r.N == 0 {
+		return 0
+	}
+	return r.Bytes / uint64(r.N)
+}
+
+// Run calls a benchmark function more and more times, the way Go
+// benchmarks are run, until the calls take at least duration, and returns
+// the measurement of the last round.
+func Run(b *Benchmark, duration time.Duration) Result {
+	n := 1
+	for {
+		result := b.measure(n)
+		if result.Err != nil || result.Duration >= duration || n >= maxCalls {
+			return result
+		}
+
+		// Aim for the duration, growing by at least one call and by at
+		// most a hundred times, as the first calls may be slow.
+		next := n * 100
+		if result.Duration > 0 {
+			next = min(next, int(1.2*float64(n)*float64(duration)/float64(result.Duration)))
+		}
+		n = min(max(next, n+1), maxCalls)
+	}
+}
+
+func (b *Benchmark) measure(n int) Result {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	for range n {
+		if err, ok := b.Call().(object.Error); ok {
+			return Result{Benchmark: b, Err: fmt.Errorf("%s", err.Message)}
+		}
+	}
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return Result{
+		Benchmark:   b,
+		N:           n,
+		Duration:    elapsed,
+		Allocations: after.Mallocs - before.Mallocs,
+		Bytes:       after.TotalAlloc - before.TotalAlloc,
+	}
+}
@@ -0,0 +1,34 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteText writes a line for every result, laid out like the results of
+// Go benchmarks, and a failure report for every benchmark that failed.
+func WriteText(w io.Writer, results []Result) error {
+	var b strings.Builder
+
+	width := 0
+	for _, result := range results {
+		width = max(width, len(result.Benchmark.Name))
+	}
+
+	for _, result := range results {
+		if result.Err == nil {
+			fmt.Fprintf(&b, "%-*s %10d %12d ns/op %10d B/op %8d allocs/op\n",
+				width, result.Benchmark.Name, result.N, result.NsPerOp(), result.BytesPerOp(), result.AllocationsPerOp())
+		}
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(&b, "--- FAIL: %s (line %d)\n    %s\n", result.Benchmark.Name, result.Benchmark.Line, result.Err)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
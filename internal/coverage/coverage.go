@@ -0,0 +1,270 @@
+// Package coverage records which statements of scripts, and which branches
+// of their if expressions, are evaluated, and reports it in the lcov format
+// and as annotated source.
+package coverage
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+)
+
+// Position is where a statement or an if expression starts.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// File is the coverage of a script.
+type File struct {
+	Path   string
+	Source string
+	// Statements counts the evaluations of every statement.
+	Statements map[Position]int
+	// Branches counts, for every if expression, how often each of its
+	// branches was taken: the consequences in order, then the alternative.
+	Branches map[Position][]int
+}
+
+// Profile collects the coverage of scripts. It is safe for concurrent use,
+// so tests running in parallel can share one.
+type Profile struct {
+	mu    sync.Mutex
+	files map[string]*File
+}
+
+func New() *Profile {
+	return &Profile{files: make(map[string]*File)}
+}
+
+// AddFile registers a script, so that statements and branches that are
+// never evaluated are reported too. Scripts that do not parse are left out.
+func (p *Profile) AddFile(path, source string) {
+	parse := parser.New(lexer.New(source))
+	program := parse.ParseProgram()
+	if len(parse.Errors()) > 0 {
+		return
+	}
+
+	file := &File{
+		Path:       path,
+		Source:     source,
+		Statements: make(map[Position]int),
+		Branches:   make(map[Position][]int),
+	}
+	file.statements(program.Statements)
+
+	p.mu.Lock()
+	p.files[path] = file
+	p.mu.Unlock()
+}
+
+// Attach records the coverage of the script with the path, which must have
+// been added, by the evaluations in env.
+func (p *Profile) Attach(path string, env *object.Environment) {
+	evaluator.Attach(env, &recorder{profile: p, path: path})
+}
+
+// Files returns the scripts added, sorted by path.
+func (p *Profile) Files() []*File {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	files := make([]*File, 0, len(p.files))
+	for _, file := range p.files {
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// recorder is the hook counting the statements and branches of one script.
+type recorder struct {
+	profile *Profile
+	path    string
+}
+
+func (r *recorder) Statement(statement ast.Statement, _ []*evaluator.Frame) object.Object {
+	tok := ast.StartToken(statement)
+	position := Position{Line: tok.Line, Column: tok.Column}
+
+	r.profile.mu.Lock()
+	defer r.profile.mu.Unlock()
+
+	// Statements not in the file, like the calls of test functions, have
+	// no count to increment.
+	if file, ok := r.profile.files[r.path]; ok {
+		if _, ok := file.Statements[position]; ok {
+			file.Statements[position]++
+		}
+	}
+	return nil
+}
+
+func (r *recorder) Error(object.Error, []*evaluator.Frame) {}
+
+func (r *recorder) Branch(expression ast.If, branch int, _ []*evaluator.Frame) {
+	position := Position{Line: expression.Token.Line, Column: expression.Token.Column}
+
+	r.profile.mu.Lock()
+	defer r.profile.mu.Unlock()
+
+	if file, ok := r.profile.files[r.path]; ok {
+		if branches, ok := file.Branches[position]; ok && branch < len(branches) {
+			branches[branch]++
+		}
+	}
+}
+
+// statements registers the statements and if expressions of the file.
+func (f *File) statements(statements []ast.Statement) {
+	for _, statement := range statements {
+		tok := ast.StartToken(statement)
+		f.Statements[Position{Line: tok.Line, Column: tok.Column}] = 0
+
+		switch st := statement.(type) {
+		case ast.LetStatement:
+			f.expression(st.Value)
+			f.defaults(st.Pattern)
+		case ast.ReturnStatement:
+			f.expression(st.Value)
+		case ast.YieldStatement:
+			f.expression(st.Value)
+		case ast.ExpressionStatement:
+			f.expression(st.Expression)
+		}
+	}
+}
+
+func (f *File) expression(expression ast.Expression) {
+	switch e := expression.(type) {
+	case ast.Prefix:
+		f.expression(e.Right)
+	case ast.Infix:
+		f.expression(e.Left)
+		f.expression(e.Right)
+	case ast.Array:
+		for _, item := range e.Items {
+			f.expression(item)
+		}
+	case ast.HashTable:
+		for _, key := range e.Keys {
+			f.expression(e.Items[key])
+		}
+	case ast.AccessByExpression:
+		f.expression(e.Left)
+		f.expression(e.Index)
+	case ast.StructLiteral:
+		for _, value := range e.Values {
+			f.expression(value)
+		}
+	case ast.FieldAccess:
+		f.expression(e.Left)
+	case ast.Call:
+		f.expression(e.Function)
+		for _, argument := range e.Arguments {
+			f.expression(argument)
+		}
+	case ast.Spread:
+		f.expression(e.Value)
+	case ast.If:
+		f.Branches[Position{Line: e.Token.Line, Column: e.Token.Column}] = make([]int, len(e.Consequences)+1)
+		for _, condition := range e.Conditions {
+			f.expression(condition)
+		}
+		for _, consequence := range e.Consequences {
+			f.statements(consequence.Statements)
+		}
+		f.statements(e.Alternative.Statements)
+	case ast.While:
+		f.expression(e.Condition)
+		f.statements(e.Body.Statements)
+	case ast.For:
+		f.expression(e.Iterable)
+		f.defaults(e.Pattern)
+		f.statements(e.Body.Statements)
+	case ast.Match:
+		f.expression(e.Subject)
+		for _, arm := range e.Arms {
+			f.defaults(arm.Pattern)
+			f.expression(arm.Guard)
+			f.expression(arm.Result)
+		}
+	case ast.Spawn:
+		f.expression(e.Value)
+	case ast.Select:
+		for _, c := range e.Cases {
+			if c.Operation != nil {
+				f.expression(*c.Operation)
+			}
+			if c.Pattern != nil {
+				f.defaults(c.Pattern)
+			}
+			f.expression(c.Result)
+		}
+	case ast.Function:
+		for _, parameter := range e.Parameters {
+			f.defaults(parameter)
+		}
+		f.statements(e.Body.Statements)
+	}
+}
+
+func (f *File) defaults(pattern ast.Node) {
+	for _, value := range ast.Defaults(pattern) {
+		f.expression(value)
+	}
+}
+
+// Lines returns the hits of every line a statement starts on: how often
+// the statement evaluated most often on the line was.
+func (f *File) Lines() map[int]int {
+	lines := make(map[int]int)
+	for position, hits := range f.Statements {
+		lines[position.Line] = max(lines[position.Line], hits)
+	}
+	return lines
+}
+
+// Summary counts the statements and the branches of the file, and those
+// of them that were evaluated.
+func (f *File) Summary() (statements, statementsHit, branches, branchesHit int) {
+	for _, hits := range f.Statements {
+		statements++
+		if hits > 0 {
+			statementsHit++
+		}
+	}
+
+	for _, counts := range f.Branches {
+		for _, hits := range counts {
+			branches++
+			if hits > 0 {
+				branchesHit++
+			}
+		}
+	}
+	return statements, statementsHit, branches, branchesHit
+}
+
+// sortedBranches returns the positions of the if expressions in order.
+func (f *File) sortedBranches() []Position {
+	positions := make([]Position, 0, len(f.Branches))
+	for position := range f.Branches {
+		positions = append(positions, position)
+	}
+
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Line != positions[j].Line {
+			return positions[i].Line < positions[j].Line
+		}
+		return positions[i].Column < positions[j].Column
+	})
+	return positions
+}
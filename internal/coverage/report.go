@@ -0,0 +1,174 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteLcov writes the coverage in the lcov tracefile format, with line
+// and branch records for every file.
+func (p *Profile) WriteLcov(w io.Writer) error {
+	var b strings.Builder
+
+	for _, file := range p.Files() {
+		fmt.Fprintf(&b, "TN:\nSF:%s\n", file.Path)
+
+		for i, position := range file.sortedBranches() {
+			counts := file.Branches[position]
+
+			evaluated := false
+			for _, hits := range counts {
+				evaluated = evaluated || hits > 0
+			}
+
+			for branch, hits := range counts {
+				// A branch of an if expression that was never evaluated
+				// is reported as "-" rather than as not taken.
+				taken := "-"
+				if evaluated {
+					taken = strconv.Itoa(hits)
+				}
+				fmt.Fprintf(&b, "BRDA:%d,%d,%d,%s\n", position.Line, i, branch, taken)
+			}
+		}
+
+		_, _, branches, branchesHit := file.Summary()
+		fmt.Fprintf(&b, "BRF:%d\nBRH:%d\n", branches, branchesHit)
+
+		lines := file.Lines()
+		numbers := make([]int, 0, len(lines))
+		linesHit := 0
+		for line, hits := range lines {
+			numbers = append(numbers, line)
+			if hits > 0 {
+				linesHit++
+			}
+		}
+		sort.Ints(numbers)
+
+		for _, line := range numbers {
+			fmt.Fprintf(&b, "DA:%d,%d\n", line, lines[line])
+		}
+		fmt.Fprintf(&b, "LF:%d\nLH:%d\nend_of_record\n", len(lines), linesHit)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteSummary writes the share of statements and branches evaluated in
+// every file.
+func (p *Profile) WriteSummary(w io.Writer) error {
+	var b strings.Builder
+
+	for _, file := range p.Files() {
+		statements, statementsHit, branches, branchesHit := file.Summary()
+		fmt.Fprintf(&b, "%s: %s of statements, %s of branches\n",
+			file.Path, percent(statementsHit, statements), percent(branchesHit, branches))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func percent(part, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(part)/float64(total))
+}
+
+type htmlFile struct {
+	Path       string
+	Statements string
+	Branches   string
+	Lines      []htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	// Hits is empty for lines no statement starts on.
+	Hits string
+	// Class is covered, uncovered or partial, where some branch of an if
+	// expression on the line was never taken, or empty.
+	Class  string
+	Source string
+}
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 8px; white-space: pre; }
+td.number, td.hits { text-align: right; color: #666; }
+tr.covered td.source { background: #dfd; }
+tr.uncovered td.source { background: #fdd; }
+tr.partial td.source { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>Coverage</h1>
+<ul>
+{{range $i, $file := .}}<li><a href="#file{{$i}}">{{$file.Path}}</a>: {{$file.Statements}} of statements, {{$file.Branches}} of branches</li>
+{{end}}</ul>
+{{range $i, $file := .}}<h2 id="file{{$i}}">{{$file.Path}}</h2>
+<table>
+{{range $file.Lines}}<tr class="{{.Class}}"><td class="number">{{.Number}}</td><td class="hits">{{.Hits}}</td><td class="source">{{.Source}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// WriteHTML writes the sources of the files as a web page, marking the
+// lines that were evaluated, those that were not and those with branches
+// that were never taken.
+func (p *Profile) WriteHTML(w io.Writer) error {
+	var files []htmlFile
+
+	for _, file := range p.Files() {
+		statements, statementsHit, branches, branchesHit := file.Summary()
+		f := htmlFile{
+			Path:       file.Path,
+			Statements: percent(statementsHit, statements),
+			Branches:   percent(branchesHit, branches),
+		}
+
+		partial := make(map[int]bool)
+		for position, counts := range file.Branches {
+			for _, hits := range counts {
+				partial[position.Line] = partial[position.Line] || hits == 0
+			}
+		}
+
+		lines := file.Lines()
+		for i, source := range strings.Split(file.Source, "\n") {
+			line := htmlLine{Number: i + 1, Source: source}
+
+			if hits, ok := lines[line.Number]; ok {
+				line.Hits = strconv.Itoa(hits)
+				switch {
+				case hits == 0:
+					line.Class = "uncovered"
+				case partial[line.Number]:
+					line.Class = "partial"
+				default:
+					line.Class = "covered"
+				}
+			}
+
+			f.Lines = append(f.Lines, line)
+		}
+
+		files = append(files, f)
+	}
+
+	return htmlTemplate.Execute(w, files)
+}
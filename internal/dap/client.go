@@ -0,0 +1,65 @@
+package dap
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/lsp"
+)
+
+// Client is a minimal synchronous client for a debug adapter, used to
+// drive the server in-process from tests and tools. Events are kept until
+// they are asked for.
+type Client struct {
+	writer  io.Writer
+	lastSeq int
+	inbox   *lsp.Inbox[Message]
+}
+
+func NewClient(in io.Reader, out io.Writer) *Client {
+	return &Client{writer: out, inbox: lsp.NewInbox[Message](in)}
+}
+
+// Call sends a request and decodes the body of its response into body,
+// unless body is nil. A failed response is returned as an error.
+func (c *Client) Call(command string, arguments, body any) error {
+	c.lastSeq++
+	seq := c.lastSeq
+
+	if err := writeMessage(c.writer, request{Seq: seq, Type: "request", Command: command, Arguments: arguments}); err != nil {
+		return err
+	}
+
+	response, err := c.inbox.Next(func(message Message) bool {
+		return message.Type == "response" && message.RequestSeq == seq
+	})
+	if err != nil {
+		return err
+	}
+
+	if !response.Success {
+		return errors.New(response.Message)
+	}
+
+	if body == nil || len(response.Body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Body, body)
+}
+
+// Event decodes the body of the oldest event with the name into body,
+// waiting for the server to send one if none has been received yet.
+func (c *Client) Event(name string, body any) error {
+	e, err := c.inbox.Next(func(message Message) bool {
+		return message.Type == "event" && message.Event == name
+	})
+	if err != nil {
+		return err
+	}
+
+	if body == nil || len(e.Body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(e.Body, body)
+}
@@ -0,0 +1,27 @@
+package dap
+
+import (
+	"fmt"
+	"net"
+)
+
+// Listen listens for clients on a TCP address of the local machine. A client
+// can launch any program the user can read, so an address without a host
+// listens on the loopback interface, and addresses on other interfaces are
+// rejected.
+func Listen(address string) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ip := net.ParseIP(host); {
+	case host == "":
+		host = "127.0.0.1"
+	case host == "localhost":
+	case ip == nil || !ip.IsLoopback():
+		return nil, fmt.Errorf("cannot listen on %s: not a loopback address", address)
+	}
+
+	return net.Listen("tcp", net.JoinHostPort(host, port))
+}
@@ -0,0 +1,646 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/debugger"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/lsp"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+)
+
+// threadID identifies the thread a Monkey program is shown as. All of its
+// tasks are shown as this one thread: the debugger runs them one at a time,
+// so that the one paused stops them all.
+const threadID = 1
+
+// errorFilter is the exception breakpoint filter that pauses on errors.
+const errorFilter = "error"
+
+// Server is a debug adapter for one launch of a Monkey program.
+//
+// Requests are handled one at a time as they are read, while the program
+// runs on its own goroutine; when it stops, it waits for a request that
+// resumes it.
+type Server struct {
+	out     io.Writer
+	writeMu sync.Mutex
+	lastSeq int
+
+	// lineBase and columnBase are what the client counts lines and columns from.
+	lineBase   int
+	columnBase int
+
+	debugger   *debugger.Debugger
+	path       string
+	program    *ast.Program
+	noDebug    bool
+	launched   bool
+	configured bool
+	started    bool
+
+	// afterResponse runs once the response to the current request is written.
+	afterResponse func()
+	disconnected  bool
+
+	mu        sync.Mutex
+	stop      *debugger.Stop
+	handles   []any
+	terminate bool
+	resume    chan debugger.Action
+}
+
+type handler func(s *Server, arguments json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":              (*Server).initialize,
+	"launch":                  (*Server).launch,
+	"setBreakpoints":          (*Server).setBreakpoints,
+	"setExceptionBreakpoints": (*Server).setExceptionBreakpoints,
+	"configurationDone":       (*Server).configurationDone,
+	"threads":                 (*Server).threads,
+	"continue":                (*Server).continueRequest,
+	"next":                    (*Server).next,
+	"stepIn":                  (*Server).stepIn,
+	"stepOut":                 (*Server).stepOut,
+	"pause":                   (*Server).pause,
+	"stackTrace":              (*Server).stackTrace,
+	"scopes":                  (*Server).scopes,
+	"variables":               (*Server).variables,
+	"evaluate":                (*Server).evaluate,
+	"terminate":               (*Server).terminateRequest,
+	"disconnect":              (*Server).disconnect,
+}
+
+func NewServer() *Server {
+	s := &Server{
+		lineBase:   1,
+		columnBase: 1,
+		debugger:   debugger.New(),
+		resume:     make(chan debugger.Action, 1),
+	}
+	s.debugger.OnStop = s.stopped
+	return s
+}
+
+// Serve handles requests read from in and writes responses and events to
+// out until the client disconnects or in is exhausted.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	reader := bufio.NewReader(in)
+
+	for !s.disconnected {
+		content, err := lsp.ReadMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var message Message
+		if err := json.Unmarshal(content, &message); err != nil {
+			return err
+		}
+
+		if message.Type != "request" {
+			continue
+		}
+
+		if err := s.handle(message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) handle(message Message) error {
+	h, ok := handlers[message.Command]
+	if !ok {
+		return s.reply(message, nil, fmt.Errorf("unsupported request: %s", message.Command))
+	}
+
+	body, err := s.call(h, message.Arguments)
+	if err := s.reply(message, body, err); err != nil {
+		return err
+	}
+
+	if after := s.afterResponse; after != nil {
+		s.afterResponse = nil
+		after()
+	}
+	return nil
+}
+
+// call runs a handler, turning a panic into a failed response.
+func (s *Server) call(h handler, arguments json.RawMessage) (body any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			body, err = nil, fmt.Errorf("internal error: %v", r)
+		}
+	}()
+
+	return h(s, arguments)
+}
+
+func (s *Server) reply(request Message, body any, err error) error {
+	r := response{Type: "response", RequestSeq: request.Seq, Success: err == nil, Command: request.Command, Body: body}
+	if err != nil {
+		r.Message = err.Error()
+	}
+
+	return s.write(func(seq int) any {
+		r.Seq = seq
+		return r
+	})
+}
+
+func (s *Server) event(name string, body any) error {
+	return s.write(func(seq int) any {
+		return event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+// write numbers and writes a message. Both the request loop and the
+// program's goroutine write messages.
+func (s *Server) write(message func(seq int) any) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.lastSeq++
+	return writeMessage(s.out, message(s.lastSeq))
+}
+
+func writeMessage(w io.Writer, message any) error {
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// Output returns a writer that sends what the program prints to the client.
+func (s *Server) Output() io.Writer {
+	return outputWriter{s}
+}
+
+type outputWriter struct {
+	server *Server
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	if err := w.server.event("output", OutputEventBody{Category: "stdout", Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func decode(arguments json.RawMessage, v any) error {
+	if len(arguments) == 0 {
+		return nil
+	}
+	return json.Unmarshal(arguments, v)
+}
+
+func (s *Server) initialize(arguments json.RawMessage) (any, error) {
+	var args InitializeArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	if args.LinesStartAt1 != nil && !*args.LinesStartAt1 {
+		s.lineBase = 0
+	}
+	if args.ColumnsStartAt1 != nil && !*args.ColumnsStartAt1 {
+		s.columnBase = 0
+	}
+
+	s.afterResponse = func() { _ = s.event("initialized", nil) }
+
+	return Capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsConditionalBreakpoints:   true,
+		SupportsEvaluateForHovers:        true,
+		SupportsTerminateRequest:         true,
+		ExceptionBreakpointFilters: []ExceptionBreakpointsFilter{
+			{Filter: errorFilter, Label: "Errors"},
+		},
+	}, nil
+}
+
+func (s *Server) launch(arguments json.RawMessage) (any, error) {
+	var args LaunchArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	if s.launched {
+		return nil, errors.New("a program has already been launched")
+	}
+
+	path, err := filepath.Abs(args.Program)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(string(data)))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, fmt.Errorf("%s:%d:%d: %s", args.Program, errs[0].Line, errs[0].Column, errs[0].Message)
+	}
+
+	s.path, s.program, s.noDebug, s.launched = path, program, args.NoDebug, true
+	s.debugger.StopOnEntry = args.StopOnEntry
+	s.start()
+	return nil, nil
+}
+
+func (s *Server) configurationDone(json.RawMessage) (any, error) {
+	s.configured = true
+	s.start()
+	return nil, nil
+}
+
+// start runs the program once it has been launched and the client is done
+// configuring breakpoints, in whichever order that happens.
+func (s *Server) start() {
+	if !s.launched || !s.configured || s.started {
+		return
+	}
+
+	s.started = true
+	s.afterResponse = func() { go s.run() }
+}
+
+func (s *Server) run() {
+	env := object.NewEnvironment()
+
+	var result object.Object
+	if s.noDebug {
+		result = evaluator.Eval(s.program, env)
+	} else {
+		result = s.debugger.Run(s.program, env)
+	}
+
+	exitCode := 0
+	if err, ok := result.(object.Error); ok && err.Message != debugger.TerminatedMessage {
+		_ = s.event("output", OutputEventBody{Category: "stderr", Output: err.String() + "\n"})
+		exitCode = 1
+	}
+
+	_ = s.event("exited", ExitedEventBody{ExitCode: exitCode})
+	_ = s.event("terminated", nil)
+}
+
+// stopped is called on the program's goroutine when it pauses, and waits
+// for a request to resume it.
+func (s *Server) stopped(stop debugger.Stop) debugger.Action {
+	s.mu.Lock()
+	if s.terminate {
+		s.mu.Unlock()
+		return debugger.Terminate
+	}
+	s.stop, s.handles = &stop, nil
+	s.mu.Unlock()
+
+	body := StoppedEventBody{Reason: string(stop.Reason), ThreadID: threadID, AllThreadsStopped: true}
+	if stop.Breakpoint != nil {
+		body.HitBreakpointIDs = []int{stop.Breakpoint.ID}
+	}
+	if stop.Error != nil {
+		body.Description = "Paused on error"
+		body.Text = stop.Error.Message
+	}
+	_ = s.event("stopped", body)
+
+	return <-s.resume
+}
+
+// currentStop returns the current stop, or fails if the program is not paused.
+func (s *Server) currentStop() (*debugger.Stop, error) {
+	if s.stop == nil {
+		return nil, errors.New("the program is not paused")
+	}
+	return s.stop, nil
+}
+
+// resumeWith resumes the paused program once the response is written.
+func (s *Server) resumeWith(action debugger.Action) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.currentStop(); err != nil {
+		return nil, err
+	}
+
+	s.stop, s.handles = nil, nil
+	s.afterResponse = func() { s.resume <- action }
+	return nil, nil
+}
+
+func (s *Server) setBreakpoints(arguments json.RawMessage) (any, error) {
+	var args SetBreakpointsArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	body := SetBreakpointsResponseBody{Breakpoints: []Breakpoint{}}
+
+	path, err := filepath.Abs(args.Source.Path)
+	if err != nil || s.path != "" && path != s.path {
+		for _, requested := range args.Breakpoints {
+			body.Breakpoints = append(body.Breakpoints, Breakpoint{Line: requested.Line, Message: "not the launched program"})
+		}
+		return body, nil
+	}
+
+	s.debugger.ClearBreakpoints()
+	for _, requested := range args.Breakpoints {
+		breakpoint, err := s.debugger.SetBreakpoint(requested.Line-s.lineBase+1, requested.Condition)
+		if err != nil {
+			body.Breakpoints = append(body.Breakpoints, Breakpoint{Line: requested.Line, Message: err.Error()})
+			continue
+		}
+		body.Breakpoints = append(body.Breakpoints, Breakpoint{ID: breakpoint.ID, Verified: true, Line: requested.Line})
+	}
+
+	return body, nil
+}
+
+func (s *Server) setExceptionBreakpoints(arguments json.RawMessage) (any, error) {
+	var args SetExceptionBreakpointsArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	s.debugger.StopOnError = false
+	for _, filter := range args.Filters {
+		if filter == errorFilter {
+			s.debugger.StopOnError = true
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) threads(json.RawMessage) (any, error) {
+	return ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+}
+
+func (s *Server) continueRequest(json.RawMessage) (any, error) {
+	if _, err := s.resumeWith(debugger.Continue); err != nil {
+		return nil, err
+	}
+	return ContinueResponseBody{AllThreadsContinued: true}, nil
+}
+
+func (s *Server) next(json.RawMessage) (any, error) {
+	return s.resumeWith(debugger.StepOver)
+}
+
+func (s *Server) stepIn(json.RawMessage) (any, error) {
+	return s.resumeWith(debugger.StepIn)
+}
+
+func (s *Server) stepOut(json.RawMessage) (any, error) {
+	return s.resumeWith(debugger.StepOut)
+}
+
+func (s *Server) pause(json.RawMessage) (any, error) {
+	s.debugger.Pause()
+	return nil, nil
+}
+
+func (s *Server) stackTrace(arguments json.RawMessage) (any, error) {
+	var args StackTraceArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stop, err := s.currentStop()
+	if err != nil {
+		return nil, err
+	}
+
+	source := &Source{Name: filepath.Base(s.path), Path: s.path}
+	body := StackTraceResponseBody{StackFrames: []StackFrame{}, TotalFrames: len(stop.Stack)}
+
+	for i := max(args.StartFrame, 0); i < len(stop.Stack); i++ {
+		if args.Levels > 0 && len(body.StackFrames) == args.Levels {
+			break
+		}
+
+		frame := stop.Stack[i]
+		body.StackFrames = append(body.StackFrames, StackFrame{
+			ID:     i + 1,
+			Name:   frame.Function,
+			Source: source,
+			Line:   frame.Line - 1 + s.lineBase,
+			Column: frame.Column - 1 + s.columnBase,
+		})
+	}
+
+	return body, nil
+}
+
+// frame returns the frame of the paused program with the ID the stack
+// trace gave it. An ID of 0 stands for the innermost frame.
+func (s *Server) frame(id int) (evaluator.Frame, error) {
+	stop, err := s.currentStop()
+	if err != nil {
+		return evaluator.Frame{}, err
+	}
+
+	if id == 0 {
+		id = 1
+	}
+	if id < 1 || id > len(stop.Stack) {
+		return evaluator.Frame{}, fmt.Errorf("unknown frame %d", id)
+	}
+	return stop.Stack[id-1], nil
+}
+
+// reference returns a variables reference for a container of variables: a
+// scope's variables, or an array or hash table. References are valid
+// until the program resumes.
+func (s *Server) reference(container any) int {
+	s.handles = append(s.handles, container)
+	return len(s.handles)
+}
+
+func (s *Server) scopes(arguments json.RawMessage) (any, error) {
+	var args ScopesArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frame, err := s.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	body := ScopesResponseBody{Scopes: []Scope{}}
+	for _, scope := range debugger.Scopes(frame) {
+		if len(scope.Variables) == 0 && scope.Name != "Local" {
+			continue
+		}
+		body.Scopes = append(body.Scopes, Scope{Name: scope.Name, VariablesReference: s.reference(scope.Variables)})
+	}
+
+	return body, nil
+}
+
+func (s *Server) variables(arguments json.RawMessage) (any, error) {
+	var args VariablesArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.currentStop(); err != nil {
+		return nil, err
+	}
+
+	if args.VariablesReference < 1 || args.VariablesReference > len(s.handles) {
+		return nil, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+	}
+
+	body := VariablesResponseBody{Variables: []Variable{}}
+
+	switch container := s.handles[args.VariablesReference-1].(type) {
+	case []debugger.Variable:
+		for _, variable := range container {
+			body.Variables = append(body.Variables, s.variable(variable.Name, variable.Value))
+		}
+	case *object.Array:
+		for i, item := range container.Snapshot() {
+			body.Variables = append(body.Variables, s.variable(fmt.Sprintf("[%d]", i), item))
+		}
+	case *object.HashTable:
+		items := container.Snapshot()
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			body.Variables = append(body.Variables, s.variable(key, items[key]))
+		}
+	case *object.Struct:
+		fields := container.Snapshot()
+		for _, field := range container.Definition.Fields {
+			body.Variables = append(body.Variables, s.variable(field, fields[field]))
+		}
+	}
+
+	return body, nil
+}
+
+// variable presents a value, giving arrays, hash tables and structs a
+// reference to their items.
+func (s *Server) variable(name string, value object.Object) Variable {
+	value = evaluator.Unwrap(value)
+	variable := Variable{Name: name, Value: evaluator.Inspect(value), Type: string(value.Type())}
+
+	switch v := value.(type) {
+	case *object.Array:
+		if v.Len() > 0 {
+			variable.VariablesReference = s.reference(v)
+		}
+	case *object.HashTable:
+		if v.Len() > 0 {
+			variable.VariablesReference = s.reference(v)
+		}
+	case *object.Struct:
+		if len(v.Definition.Fields) > 0 {
+			variable.VariablesReference = s.reference(v)
+		}
+	}
+
+	return variable
+}
+
+func (s *Server) evaluate(arguments json.RawMessage) (any, error) {
+	var args EvaluateArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frame, err := s.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := s.debugger.Evaluate(args.Expression, frame)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := value.(object.Error); ok {
+		return nil, errors.New(e.Message)
+	}
+
+	variable := s.variable("", value)
+	return EvaluateResponseBody{Result: variable.Value, Type: variable.Type, VariablesReference: variable.VariablesReference}, nil
+}
+
+// end terminates the program: at once if it is paused, and at its next
+// statement otherwise.
+func (s *Server) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.terminate = true
+
+	if s.stop != nil {
+		s.stop, s.handles = nil, nil
+		s.afterResponse = func() { s.resume <- debugger.Terminate }
+		return
+	}
+
+	s.debugger.Pause()
+}
+
+func (s *Server) terminateRequest(json.RawMessage) (any, error) {
+	s.end()
+	return nil, nil
+}
+
+func (s *Server) disconnect(json.RawMessage) (any, error) {
+	s.end()
+	s.disconnected = true
+	return nil, nil
+}
@@ -0,0 +1,227 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+)
+
+const PROMPT = "(debug) "
+
+const consoleHelp = `Commands:
+  break LINE [if CONDITION]  set a breakpoint (b)
+  delete ID                  remove a breakpoint (d)
+  breakpoints                list breakpoints
+  errors on|off              pause where errors are raised
+  continue                   run to the next breakpoint (c)
+  step                       step into calls (s)
+  next                       step over calls (n)
+  out                        step out of the current function (o)
+  stack                      print the call stack (bt)
+  frame N                    select a stack frame (f)
+  vars                       print the variables of the frame (v)
+  print EXPRESSION           evaluate in the frame (p)
+  list                       print the source around the frame (l)
+  quit                       end the program (q)
+`
+
+// Console is a command line front end for the debugger. The program stops
+// on entry, so breakpoints can be set before it runs.
+type Console struct {
+	debugger *Debugger
+	lines    []string
+	scanner  *bufio.Scanner
+	out      io.Writer
+
+	stop  Stop
+	frame int
+}
+
+func NewConsole(source string, in io.Reader, out io.Writer) *Console {
+	c := &Console{
+		debugger: New(),
+		lines:    strings.Split(source, "\n"),
+		scanner:  bufio.NewScanner(in),
+		out:      out,
+	}
+	c.debugger.StopOnEntry = true
+	c.debugger.OnStop = c.prompt
+	return c
+}
+
+func (c *Console) Debugger() *Debugger {
+	return c.debugger
+}
+
+func (c *Console) printf(format string, a ...any) {
+	_, _ = fmt.Fprintf(c.out, format, a...)
+}
+
+// prompt reads commands until one of them resumes the program. The end
+// of the input terminates it.
+func (c *Console) prompt(stop Stop) Action {
+	c.stop, c.frame = stop, 0
+	c.printStop()
+
+	for {
+		c.printf(PROMPT)
+		if !c.scanner.Scan() {
+			c.printf("\n")
+			return Terminate
+		}
+
+		if action, ok := c.execute(strings.TrimSpace(c.scanner.Text())); ok {
+			return action
+		}
+	}
+}
+
+func (c *Console) printStop() {
+	frame := c.stop.Stack[0]
+
+	switch c.stop.Reason {
+	case BreakpointReason:
+		c.printf("breakpoint %d hit in %s at line %d\n", c.stop.Breakpoint.ID, frame.Function, frame.Line)
+	case ErrorReason:
+		c.printf("error in %s at line %d: %s\n", frame.Function, frame.Line, c.stop.Error.Message)
+	default:
+		c.printf("stopped in %s at line %d\n", frame.Function, frame.Line)
+	}
+
+	c.printLine(frame.Line, true)
+}
+
+func (c *Console) printLine(line int, current bool) {
+	if line < 1 || line > len(c.lines) {
+		return
+	}
+
+	marker := " "
+	if current {
+		marker = ">"
+	}
+	c.printf("%s %4d  %s\n", marker, line, c.lines[line-1])
+}
+
+// execute runs a command and reports whether it resumes the program.
+func (c *Console) execute(command string) (Action, bool) {
+	name, argument, _ := strings.Cut(command, " ")
+	argument = strings.TrimSpace(argument)
+
+	switch name {
+	case "":
+		return 0, false
+	case "c", "continue":
+		return Continue, true
+	case "s", "step":
+		return StepIn, true
+	case "n", "next":
+		return StepOver, true
+	case "o", "out":
+		return StepOut, true
+	case "q", "quit":
+		return Terminate, true
+	case "b", "break":
+		c.setBreakpoint(argument)
+	case "d", "delete":
+		id, err := strconv.Atoi(argument)
+		if err != nil || !c.debugger.ClearBreakpoint(id) {
+			c.printf("no breakpoint %q\n", argument)
+		}
+	case "breakpoints":
+		for _, breakpoint := range c.debugger.Breakpoints() {
+			c.printf("%d: line %d", breakpoint.ID, breakpoint.Line)
+			if breakpoint.Condition != "" {
+				c.printf(" if %s", breakpoint.Condition)
+			}
+			c.printf("\n")
+		}
+	case "errors":
+		switch argument {
+		case "on":
+			c.debugger.StopOnError = true
+		case "off":
+			c.debugger.StopOnError = false
+		default:
+			c.printf("usage: errors on|off\n")
+		}
+	case "bt", "stack":
+		for i, frame := range c.stop.Stack {
+			marker := " "
+			if i == c.frame {
+				marker = "*"
+			}
+			c.printf("%s #%d %s at line %d\n", marker, i, frame.Function, frame.Line)
+		}
+	case "f", "frame":
+		n, err := strconv.Atoi(argument)
+		if err != nil || n < 0 || n >= len(c.stop.Stack) {
+			c.printf("no frame %q\n", argument)
+			break
+		}
+		c.frame = n
+		c.printLine(c.stop.Stack[n].Line, true)
+	case "v", "vars":
+		for _, scope := range Scopes(c.currentFrame()) {
+			if len(scope.Variables) == 0 {
+				continue
+			}
+			c.printf("%s:\n", scope.Name)
+			for _, variable := range scope.Variables {
+				c.printf("  %s = %s\n", variable.Name, evaluator.Inspect(variable.Value))
+			}
+		}
+	case "p", "print":
+		value, err := c.debugger.Evaluate(argument, c.currentFrame())
+		if err != nil {
+			c.printf("%s\n", err)
+			break
+		}
+		c.printf("%s\n", evaluator.Inspect(value))
+	case "l", "list":
+		current := c.currentFrame().Line
+		for line := max(current-3, 1); line <= current+3; line++ {
+			c.printLine(line, line == current)
+		}
+	case "h", "help":
+		c.printf("%s", consoleHelp)
+	default:
+		c.printf("unknown command %q, type help for a list of commands\n", name)
+	}
+
+	return 0, false
+}
+
+func (c *Console) setBreakpoint(argument string) {
+	lineText, condition, _ := strings.Cut(argument, " ")
+	condition = strings.TrimSpace(condition)
+
+	if condition != "" {
+		var ok bool
+		if condition, ok = strings.CutPrefix(condition, "if "); !ok {
+			c.printf("usage: break LINE [if CONDITION]\n")
+			return
+		}
+	}
+
+	line, err := strconv.Atoi(lineText)
+	if err != nil {
+		c.printf("usage: break LINE [if CONDITION]\n")
+		return
+	}
+
+	breakpoint, err := c.debugger.SetBreakpoint(line, condition)
+	if err != nil {
+		c.printf("%s\n", err)
+		return
+	}
+	c.printf("breakpoint %d at line %d\n", breakpoint.ID, breakpoint.Line)
+}
+
+func (c *Console) currentFrame() evaluator.Frame {
+	return c.stop.Stack[c.frame]
+}
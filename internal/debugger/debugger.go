@@ -0,0 +1,332 @@
+package debugger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+)
+
+// Action tells a paused program how to go on.
+type Action int
+
+const (
+	// Continue runs until the next breakpoint or error.
+	Continue Action = iota
+	// StepIn stops at the next statement, entering called functions.
+	StepIn
+	// StepOver stops at the next statement of the current function or a caller.
+	StepOver
+	// StepOut stops at the next statement after the current function returns.
+	StepOut
+	// Terminate ends the program.
+	Terminate
+)
+
+type Reason string
+
+const (
+	EntryReason      Reason = "entry"
+	BreakpointReason Reason = "breakpoint"
+	StepReason       Reason = "step"
+	PauseReason      Reason = "pause"
+	ErrorReason      Reason = "exception"
+)
+
+// TerminatedMessage is the message of the error a terminated program
+// evaluates to.
+const TerminatedMessage = "terminated by debugger"
+
+type Breakpoint struct {
+	ID   int
+	Line int
+	// Condition is a Monkey expression; the breakpoint only stops the
+	// program where it is truthy. An empty condition always holds.
+	Condition string
+
+	condition *ast.Program
+}
+
+// Stop describes where and why the program paused.
+type Stop struct {
+	Reason     Reason
+	Breakpoint *Breakpoint
+	// Error is set when the program paused on an error.
+	Error *object.Error
+	// Stack is a snapshot of the call stack, innermost frame first.
+	Stack []evaluator.Frame
+}
+
+// Debugger controls the execution of a program as its evaluator.Hook.
+//
+// Whenever the program pauses, OnStop is called on the evaluating goroutine
+// and the program resumes as directed by the action it returns. Front ends
+// can inspect the stopped program from OnStop, or from another goroutine
+// while OnStop blocks. Tasks share the debugger: the one reaching a stop
+// pauses, and the steps it is given apply to whichever runs next.
+type Debugger struct {
+	OnStop func(stop Stop) Action
+	// StopOnEntry pauses the program before its first statement.
+	StopOnEntry bool
+	// StopOnError pauses the program where an error is raised.
+	StopOnError bool
+
+	// mu guards the breakpoints, and the state of the stepping, which
+	// the tasks of the program share.
+	mu          sync.Mutex
+	breakpoints []*Breakpoint
+	lastID      int
+
+	started        bool
+	action         Action
+	depth          int
+	pauseRequested atomic.Bool
+}
+
+func New() *Debugger {
+	return &Debugger{}
+}
+
+// Run evaluates the program in env under the control of the debugger. Its
+// tasks run one at a time, so that the others wait while one is paused,
+// and a program whose tasks are all blocked fails instead of hanging.
+func (d *Debugger) Run(program *ast.Program, env *object.Environment) object.Object {
+	evaluator.Attach(env, d)
+
+	scheduler := evaluator.NewScheduler(0)
+	evaluator.Schedule(env, scheduler)
+	defer scheduler.Stop()
+
+	return evaluator.Eval(program, env)
+}
+
+// SetBreakpoint adds a breakpoint on the statements starting on the line.
+// It fails if the condition is not a valid expression.
+func (d *Debugger) SetBreakpoint(line int, condition string) (*Breakpoint, error) {
+	breakpoint := &Breakpoint{Line: line, Condition: strings.TrimSpace(condition)}
+
+	if breakpoint.Condition != "" {
+		program, err := parseExpression(breakpoint.Condition)
+		if err != nil {
+			return nil, err
+		}
+		breakpoint.condition = program
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastID++
+	breakpoint.ID = d.lastID
+	d.breakpoints = append(d.breakpoints, breakpoint)
+	return breakpoint, nil
+}
+
+// ClearBreakpoint removes the breakpoint with the ID and reports whether there was one.
+func (d *Debugger) ClearBreakpoint(id int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, breakpoint := range d.breakpoints {
+		if breakpoint.ID == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Debugger) ClearBreakpoints() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints = nil
+}
+
+// Breakpoints returns the breakpoints ordered by line.
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	breakpoints := append([]*Breakpoint(nil), d.breakpoints...)
+	sort.SliceStable(breakpoints, func(i, j int) bool { return breakpoints[i].Line < breakpoints[j].Line })
+	return breakpoints
+}
+
+// Pause asks the running program to stop before its next statement. It is
+// safe to call from any goroutine.
+func (d *Debugger) Pause() {
+	d.pauseRequested.Store(true)
+}
+
+// Evaluate evaluates an expression in the environment of a frame of the
+// paused program. Breakpoints and steps are ignored while it runs.
+func (d *Debugger) Evaluate(expression string, frame evaluator.Frame) (object.Object, error) {
+	program, err := parseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return d.evaluate(program, frame.Env), nil
+}
+
+// evaluate runs the program in an environment enclosed in env but detached
+// from the execution, so that the debugger does not observe it.
+func (d *Debugger) evaluate(program *ast.Program, env *object.Environment) object.Object {
+	detached := object.NewEnclosedEnvironment(env)
+	detached.SetExecution(nil)
+
+	return evaluator.Eval(program, detached)
+}
+
+func parseExpression(source string) (*ast.Program, error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) > 0 {
+		return nil, fmt.Errorf("invalid expression %q: %s", source, errors[0].Message)
+	}
+
+	if len(program.Statements) != 1 {
+		return nil, fmt.Errorf("invalid expression %q: expected a single expression", source)
+	}
+
+	if _, ok := program.Statements[0].(ast.ExpressionStatement); !ok {
+		return nil, fmt.Errorf("invalid expression %q: expected an expression", source)
+	}
+
+	return program, nil
+}
+
+func (d *Debugger) Statement(statement ast.Statement, stack []*evaluator.Frame) object.Object {
+	depth := len(stack)
+	stop := Stop{}
+
+	d.mu.Lock()
+	first := !d.started
+	d.started = true
+	action, stepDepth := d.action, d.depth
+	d.mu.Unlock()
+
+	switch {
+	case first && d.StopOnEntry:
+		stop.Reason = EntryReason
+	case d.pauseRequested.Swap(false):
+		stop.Reason = PauseReason
+	case action == StepIn,
+		action == StepOver && depth <= stepDepth,
+		action == StepOut && depth < stepDepth:
+		stop.Reason = StepReason
+	default:
+		stop.Breakpoint = d.breakpointHit(ast.StartToken(statement).Line, stack[depth-1].Env)
+		if stop.Breakpoint == nil {
+			return nil
+		}
+		stop.Reason = BreakpointReason
+	}
+
+	return d.pause(stop, stack)
+}
+
+func (d *Debugger) Error(err object.Error, stack []*evaluator.Frame) {
+	if !d.StopOnError {
+		return
+	}
+
+	d.pause(Stop{Reason: ErrorReason, Error: &err}, stack)
+}
+
+// breakpointHit returns the first breakpoint on the line whose condition holds.
+// A condition that fails to evaluate counts as holding, so the user gets to
+// see the failure.
+func (d *Debugger) breakpointHit(line int, env *object.Environment) *Breakpoint {
+	for _, breakpoint := range d.Breakpoints() {
+		if breakpoint.Line != line {
+			continue
+		}
+
+		if breakpoint.condition == nil {
+			return breakpoint
+		}
+
+		result := d.evaluate(breakpoint.condition, env)
+		if result.Type() == object.ErrorType || evaluator.IsTruthy(result) {
+			return breakpoint
+		}
+	}
+
+	return nil
+}
+
+func (d *Debugger) pause(stop Stop, stack []*evaluator.Frame) object.Object {
+	stop.Stack = make([]evaluator.Frame, len(stack))
+	for i, frame := range stack {
+		stop.Stack[len(stack)-1-i] = *frame
+	}
+
+	action := Continue
+	if d.OnStop != nil {
+		action = d.OnStop(stop)
+	}
+
+	d.mu.Lock()
+	d.action, d.depth = action, len(stack)
+	d.mu.Unlock()
+
+	if action == Terminate {
+		return object.Error{Message: TerminatedMessage}
+	}
+	return nil
+}
+
+// Scope is a group of variables visible from a frame.
+type Scope struct {
+	Name      string
+	Variables []Variable
+}
+
+type Variable struct {
+	Name  string
+	Value object.Object
+}
+
+// Scopes lists the variables visible from a frame, innermost first: the
+// locals of the call, the variables of each enclosing function, and the
+// globals. Shadowed variables are left out.
+func Scopes(frame evaluator.Frame) []Scope {
+	scopes := []Scope{{Name: "Local"}}
+	seen := make(map[string]bool)
+	inCall := true
+
+	for env := frame.Env; env != nil; env = env.Outer() {
+		switch {
+		case env.Outer() == nil:
+			scopes = append(scopes, Scope{Name: "Global"})
+		case !inCall:
+			scopes = append(scopes, Scope{Name: "Closure"})
+		}
+		scope := &scopes[len(scopes)-1]
+
+		for _, name := range env.Names() {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			value, _ := env.Get(name)
+			scope.Variables = append(scope.Variables, Variable{Name: name, Value: evaluator.Unwrap(value)})
+		}
+
+		if env == frame.Call {
+			inCall = false
+		}
+	}
+
+	return scopes
+}
@@ -0,0 +1,261 @@
+package evaluator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// The assertion builtins are registered in init, because assertThrows calls
+// back into the evaluator, which looks builtins up.
+func init() {
+	builtins["assert"] = object.Builtin{Function: bf.assert, Arity: object.Arity{Min: 1, Max: 2}}
+	builtins["assertEqual"] = object.Builtin{Function: bf.assertEqual, Arity: object.Arity{Min: 2, Max: 3}}
+	builtins["assertThrows"] = object.Builtin{WithCaller: bf.assertThrows, Arity: object.Arity{Min: 1, Max: 2}}
+}
+
+// assertionMessage names the failed assertion, followed by the message
+// the caller passed in args[index], if any.
+func assertionMessage(name string, args []object.Object, index int) string {
+	if len(args) <= index {
+		return name + " failed"
+	}
+
+	message := Unwrap(args[index])
+	if str, ok := message.(object.String); ok {
+		return name + " failed: " + str.Value
+	}
+	return name + " failed: " + message.String()
+}
+
+func (bf BuiltinFunctions) assert(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments: got=%d, want=1..2", len(args))
+	}
+
+	if !IsTruthy(Unwrap(args[0])) {
+		return newError("%s", assertionMessage("assertion", args, 1))
+	}
+	return NULL
+}
+
+func (bf BuiltinFunctions) assertEqual(args ...object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newError("wrong number of arguments: got=%d, want=2..3", len(args))
+	}
+
+	expected, actual := Unwrap(args[0]), Unwrap(args[1])
+
+	path, difference, ok := compare(expected, actual, "")
+	if ok {
+		return NULL
+	}
+
+	message := fmt.Sprintf("%s\n    expected: %s\n    actual:   %s",
+		assertionMessage("assertEqual", args, 2), Inspect(expected), Inspect(actual))
+	switch {
+	case path != "":
+		message += fmt.Sprintf("\n    at %s: %s", path, difference)
+	case difference != "":
+		message += "\n    " + difference
+	}
+	return object.Error{Message: message}
+}
+
+func (bf BuiltinFunctions) assertThrows(caller *object.Environment, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments: got=%d, want=1..2", len(args))
+	}
+
+	result := applyFunction(args[0], nil, caller)
+
+	err, ok := result.(object.Error)
+	if !ok {
+		return newError("%s\n    expected an error, got: %s", assertionMessage("assertThrows", nil, 0), Inspect(result))
+	}
+
+	if len(args) == 2 {
+		str, ok := Unwrap(args[1]).(object.String)
+		if !ok {
+			return newError("argument type is not supported: got %s", Unwrap(args[1]).Type())
+		}
+		if !strings.Contains(err.Message, str.Value) {
+			return newError("%s\n    expected an error containing: %q\n    got: %q",
+				assertionMessage("assertThrows", nil, 0), str.Value, err.Message)
+		}
+	}
+
+	return NULL
+}
+
+// compare reports whether two values are equal, looking into arrays and
+// hash tables. If they are not, it returns the path to the first
+// difference and a description of it, if there is more to tell than that
+// the values differ.
+func compare(expected, actual object.Object, path string) (string, string, bool) {
+	expected, actual = Unwrap(expected), Unwrap(actual)
+
+	// A mismatch of the values compared at the top says nothing new.
+	mismatch := func() (string, string, bool) {
+		if path == "" {
+			return "", "", false
+		}
+		return path, fmt.Sprintf("expected %s, actual %s", Inspect(expected), Inspect(actual)), false
+	}
+
+	switch e := expected.(type) {
+	case object.Integer:
+		if a, ok := actual.(object.Integer); ok && a.Value == e.Value {
+			return "", "", true
+		}
+	case object.String:
+		if a, ok := actual.(object.String); ok && a.Value == e.Value {
+			return "", "", true
+		}
+	case *object.Boolean:
+		if a, ok := actual.(*object.Boolean); ok && a.Value == e.Value {
+			return "", "", true
+		}
+	case *object.Null, object.Null:
+		if actual.Type() == object.NullType {
+			return "", "", true
+		}
+	case *object.Array:
+		a, ok := actual.(*object.Array)
+		if !ok {
+			return mismatch()
+		}
+		eItems, aItems := e.Snapshot(), a.Snapshot()
+
+		for i := 0; i < len(eItems) && i < len(aItems); i++ {
+			if p, d, ok := compare(eItems[i], aItems[i], fmt.Sprintf("%s[%d]", path, i)); !ok {
+				return p, d, false
+			}
+		}
+
+		if len(eItems) != len(aItems) {
+			return path, fmt.Sprintf("expected length %d, actual %d", len(eItems), len(aItems)), false
+		}
+		return "", "", true
+	case *object.HashTable:
+		a, ok := actual.(*object.HashTable)
+		if !ok {
+			return mismatch()
+		}
+		eItems, aItems := e.Snapshot(), a.Snapshot()
+
+		for _, key := range sortedKeys(eItems) {
+			value, ok := aItems[key]
+			if !ok {
+				return path, fmt.Sprintf("missing key %q", key), false
+			}
+			if p, d, ok := compare(eItems[key], value, fmt.Sprintf("%s[%q]", path, key)); !ok {
+				return p, d, false
+			}
+		}
+
+		for _, key := range sortedKeys(aItems) {
+			if _, ok := eItems[key]; !ok {
+				return path, fmt.Sprintf("unexpected key %q", key), false
+			}
+		}
+		return "", "", true
+	case *object.Struct:
+		a, ok := actual.(*object.Struct)
+		if !ok || a.Definition != e.Definition {
+			return mismatch()
+		}
+		eFields, aFields := e.Snapshot(), a.Snapshot()
+
+		for _, field := range e.Definition.Fields {
+			if p, d, ok := compare(eFields[field], aFields[field], path+"."+field); !ok {
+				return p, d, false
+			}
+		}
+		return "", "", true
+	}
+
+	return mismatch()
+}
+
+func sortedKeys(items map[string]object.Object) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Inspect prints a value the way it is written in Monkey, where possible.
+// An array, hash table or struct inside itself is printed as [...], {...}
+// or as the name of its type followed by {...}.
+func Inspect(value object.Object) string {
+	return inspect(value, make(map[object.Object]bool))
+}
+
+// inspect prints a value inside the collections in printing.
+func inspect(value object.Object, printing map[object.Object]bool) string {
+	switch v := Unwrap(value).(type) {
+	case object.String:
+		return strconv.Quote(v.Value)
+	case *object.Array:
+		if printing[v] {
+			return "[...]"
+		}
+		printing[v] = true
+		defer delete(printing, v)
+
+		array := v.Snapshot()
+		items := make([]string, len(array))
+		for i, item := range array {
+			items[i] = inspect(item, printing)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *object.HashTable:
+		if printing[v] {
+			return "{...}"
+		}
+		printing[v] = true
+		defer delete(printing, v)
+
+		hashTable := v.Snapshot()
+		items := make([]string, 0, len(hashTable))
+		for _, key := range sortedKeys(hashTable) {
+			items = append(items, strconv.Quote(key)+": "+inspect(hashTable[key], printing))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case *object.Struct:
+		if printing[v] {
+			return v.Definition.Name + "{...}"
+		}
+		printing[v] = true
+		defer delete(printing, v)
+
+		values := v.Snapshot()
+		fields := make([]string, len(v.Definition.Fields))
+		for i, field := range v.Definition.Fields {
+			fields[i] = field + ": " + inspect(values[field], printing)
+		}
+		return v.Definition.Name + "{" + strings.Join(fields, ", ") + "}"
+	case *object.Function:
+		parameters := make([]string, len(v.Parameters))
+		for i, parameter := range v.Parameters {
+			parameters[i] = parameter.String()
+		}
+		if v.Rest != nil {
+			parameters = append(parameters, "..."+v.Rest.Value)
+		}
+		if v.Generator {
+			return "fn*(" + strings.Join(parameters, ", ") + ")"
+		}
+		return "fn(" + strings.Join(parameters, ", ") + ")"
+	case object.Builtin:
+		return "builtin"
+	default:
+		return v.String()
+	}
+}
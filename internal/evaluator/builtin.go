@@ -2,6 +2,7 @@ package evaluator
 
 import (
 	"log"
+	"sort"
 
 	"github.com/timur-makarov/monkey-interpreter/internal/object"
 )
@@ -16,8 +17,10 @@ func (bf BuiltinFunctions) len(args ...object.Object) object.Object {
 	switch item := args[0].(type) {
 	case object.String:
 		return object.Integer{Value: len(item.Value)}
-	case object.Array:
-		return object.Integer{Value: len(item.Items)}
+	case *object.Array:
+		return object.Integer{Value: item.Len()}
+	case *object.Iterator:
+		return count(item)
 	case object.Identifier:
 		return bf.len(item.Value)
 	default:
@@ -31,12 +34,14 @@ func (bf BuiltinFunctions) shift(args ...object.Object) object.Object {
 	}
 
 	switch item := args[0].(type) {
-	case object.Array:
-		if len(item.Items) > 0 {
-			return object.Array{Items: append([]object.Object{}, item.Items[1:]...)}
+	case *object.Array:
+		if items := item.Snapshot(); len(items) > 0 {
+			return &object.Array{Items: items[1:]}
 		} else {
-			return item
+			return &object.Array{}
 		}
+	case *object.Iterator:
+		return iteratorSkip(item, object.Integer{Value: 1})
 	case object.Identifier:
 		return bf.shift(item.Value)
 	default:
@@ -50,12 +55,12 @@ func (bf BuiltinFunctions) append(args ...object.Object) object.Object {
 	}
 
 	switch item := args[0].(type) {
-	case object.Array:
-		if len(item.Items) > 0 {
-			return object.Array{Items: append(item.Items, args[1:]...)}
-		} else {
-			return item
-		}
+	case *object.Array:
+		// append returns a new array, with a copy of the items, so that
+		// arrays appended to the same one never share theirs.
+		return &object.Array{Items: append(item.Snapshot(), args[1:]...)}
+	case *object.Iterator:
+		return appended(item, args[1:])
 	case object.Identifier:
 		return bf.append(append([]object.Object{item.Value}, args[1:]...)...)
 	default:
@@ -67,7 +72,11 @@ func (bf BuiltinFunctions) log(args ...object.Object) object.Object {
 	strings := make([]any, len(args))
 
 	for i, arg := range args {
-		strings[i] = arg.String()
+		if str, ok := Unwrap(arg).(object.String); ok {
+			strings[i] = str.Value
+		} else {
+			strings[i] = Inspect(arg)
+		}
 	}
 
 	log.Println(strings...)
@@ -77,8 +86,22 @@ func (bf BuiltinFunctions) log(args ...object.Object) object.Object {
 var bf = BuiltinFunctions{}
 
 var builtins = map[string]object.Builtin{
-	"len":    {bf.len},
-	"shift":  {bf.shift},
-	"append": {bf.append},
-	"log":    {bf.log},
+	"len":    {Function: bf.len, Arity: object.Arity{Min: 1, Max: 1}},
+	"shift":  {Function: bf.shift, Arity: object.Arity{Min: 1, Max: 1}},
+	"append": {Function: bf.append, Arity: object.Arity{Min: 2, Max: -1}},
+	"log":    {Function: bf.log, Arity: object.Arity{Min: 0, Max: -1}},
+}
+
+func LookupBuiltin(name string) (object.Builtin, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,273 @@
+package evaluator
+
+import (
+	"slices"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+func init() {
+	builtins["channel"] = object.Builtin{Function: bf.channel, Arity: object.Arity{Min: 0, Max: 1}}
+}
+
+// channel passes values from tasks sending them to tasks receiving them.
+// A send blocks until a receive takes the value, unless the buffer has room
+// for it, and a receive until there is a value, unless the channel is
+// closed.
+type channel struct {
+	capacity int
+	buffer   []object.Object
+	closed   bool
+	// receivers and senders hold the cases of the waiters blocked on the
+	// channel, in the order they blocked.
+	receivers []pending
+	senders   []pending
+	scheduler *Scheduler
+}
+
+func (ch *channel) Type() object.Type {
+	return object.ChannelType
+}
+
+func (ch *channel) String() string {
+	return "channel"
+}
+
+// pending is a case of a waiter blocked on a channel, with the value it
+// sends if it is a send.
+type pending struct {
+	waiter *waiter
+	index  int
+	value  object.Object
+}
+
+func (bf BuiltinFunctions) channel(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments: got=%d, want=0..1", len(args))
+	}
+
+	capacity := 0
+	if len(args) == 1 {
+		size, ok := Unwrap(args[0]).(object.Integer)
+		if !ok {
+			return newError("argument type is not supported: got %s", Unwrap(args[0]).Type())
+		}
+		if size.Value < 0 {
+			return newError("channel capacity is negative: %d", size.Value)
+		}
+		capacity = size.Value
+	}
+	return &channel{capacity: capacity}
+}
+
+func channelSend(args ...object.Object) object.Object {
+	ch := args[0].(*channel)
+	if _, _, _, err := exchange(ch.scheduler, []selectCase{{channel: ch, send: true, value: Unwrap(args[1])}}, true); err != nil {
+		return err
+	}
+	return NULL
+}
+
+// channelReceive returns the next value sent to a channel, or null once it
+// is closed and every value sent before has been received.
+func channelReceive(args ...object.Object) object.Object {
+	ch := args[0].(*channel)
+	_, value, _, err := exchange(ch.scheduler, []selectCase{{channel: ch}}, true)
+	if err != nil {
+		return err
+	}
+	return value
+}
+
+func channelClose(args ...object.Object) object.Object {
+	lock.Lock()
+	defer lock.Unlock()
+	return args[0].(*channel).close()
+}
+
+// send hands a value to a blocked receiver or puts it into the buffer, and
+// reports whether it could.
+func (ch *channel) send(value object.Object) (bool, object.Object) {
+	if ch.closed {
+		return false, newError("send on a closed channel")
+	}
+	if receiver, ok := first(&ch.receivers); ok {
+		receiver.waiter.resume(receiver.index, value, true, nil)
+		return true, nil
+	}
+	if len(ch.buffer) < ch.capacity {
+		ch.buffer = append(ch.buffer, value)
+		return true, nil
+	}
+	return false, nil
+}
+
+// receive takes a value from the buffer or a blocked sender, or null if
+// the channel is closed, and reports whether it could and whether the value
+// was sent.
+func (ch *channel) receive() (value object.Object, sent, received bool) {
+	if len(ch.buffer) > 0 {
+		value, ch.buffer = ch.buffer[0], ch.buffer[1:]
+		// The value of a blocked sender takes the place left in the buffer.
+		if sender, ok := first(&ch.senders); ok {
+			ch.buffer = append(ch.buffer, sender.value)
+			sender.waiter.resume(sender.index, NULL, true, nil)
+		}
+		return value, true, true
+	}
+	if sender, ok := first(&ch.senders); ok {
+		sender.waiter.resume(sender.index, NULL, true, nil)
+		return sender.value, true, true
+	}
+	if ch.closed {
+		return NULL, false, true
+	}
+	return nil, false, false
+}
+
+// close lets the blocked receivers go on with null and fails the blocked
+// senders.
+func (ch *channel) close() object.Object {
+	if ch.closed {
+		return newError("close of a closed channel")
+	}
+	ch.closed = true
+
+	for receiver, ok := first(&ch.receivers); ok; receiver, ok = first(&ch.receivers) {
+		receiver.waiter.resume(receiver.index, NULL, false, nil)
+	}
+	for sender, ok := first(&ch.senders); ok; sender, ok = first(&ch.senders) {
+		sender.waiter.resume(sender.index, NULL, false, newError("send on a closed channel"))
+	}
+	return NULL
+}
+
+// first removes the first case of the queue whose waiter has not been
+// resumed yet, dropping those before it, which have.
+func first(queue *[]pending) (pending, bool) {
+	for len(*queue) > 0 {
+		p := (*queue)[0]
+		*queue = (*queue)[1:]
+		if !p.waiter.woken {
+			return p, true
+		}
+	}
+	return pending{}, false
+}
+
+// forget removes the cases of a waiter that has been resumed.
+func (ch *channel) forget(w *waiter) {
+	ch.receivers = slices.DeleteFunc(ch.receivers, func(p pending) bool { return p.waiter == w })
+	ch.senders = slices.DeleteFunc(ch.senders, func(p pending) bool { return p.waiter == w })
+}
+
+// selectCase is a send of a value to a channel, or a receive from it.
+type selectCase struct {
+	channel *channel
+	send    bool
+	value   object.Object
+	// node is the index of the case among those of the select expression.
+	node int
+}
+
+// exchange proceeds with one of the cases that can, trying them in the
+// order of the scheduler. If none can, it waits until one can, or returns
+// at once with an index of -1 if it must not block. It returns the index of
+// the case, the value received and whether it was sent rather than the
+// channel closed, or the error of the case.
+func exchange(s *Scheduler, cases []selectCase, block bool) (int, object.Object, bool, object.Object) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, i := range s.order(len(cases)) {
+		c := cases[i]
+		if c.send {
+			if sent, err := c.channel.send(c.value); sent || err != nil {
+				return i, NULL, true, err
+			}
+		} else if value, sent, received := c.channel.receive(); received {
+			return i, value, sent, nil
+		}
+	}
+
+	if !block {
+		return -1, NULL, false, nil
+	}
+
+	w := newWaiter(s)
+	for i, c := range cases {
+		if c.send {
+			c.channel.senders = append(c.channel.senders, pending{waiter: w, index: i, value: c.value})
+		} else {
+			c.channel.receivers = append(c.channel.receivers, pending{waiter: w, index: i})
+		}
+	}
+
+	s.block(w)
+
+	for _, c := range cases {
+		c.channel.forget(w)
+	}
+	return w.index, w.value, w.ok, w.err
+}
+
+// evalSelect evaluates the channels of the cases, and the values to send,
+// in order, and then the result of the case that proceeds, or of the
+// default case if none can at once. The result is evaluated in an
+// environment of its own holding the names the pattern of its case binds,
+// or, if the case is bound and its pattern binds none, in env.
+func evalSelect(node ast.Select, env *object.Environment) object.Object {
+	var cases []selectCase
+	chosen := -1
+
+	for i, c := range node.Cases {
+		if c.Operation == nil {
+			chosen = i
+			continue
+		}
+
+		value := Eval(c.Channel(), env)
+		if value.Type() == object.ErrorType {
+			return value
+		}
+		ch, ok := Unwrap(value).(*channel)
+		if !ok {
+			return newError("cannot select on %s: not a channel", Unwrap(value).Type())
+		}
+
+		sc := selectCase{channel: ch, node: i}
+		if c.Sends() {
+			value := Eval(c.Operation.Arguments[0], env)
+			if value.Type() == object.ErrorType {
+				return value
+			}
+			sc.send, sc.value = true, Unwrap(value)
+		}
+		cases = append(cases, sc)
+	}
+
+	index, value, _, err := exchange(schedulerOf(env), cases, chosen < 0)
+	if err != nil {
+		return err
+	}
+	if index >= 0 {
+		chosen = cases[index].node
+	}
+
+	c := node.Cases[chosen]
+	caseEnv := env
+	switch {
+	case c.Scope == nil:
+		caseEnv = object.NewEnclosedEnvironment(env)
+	case len(c.Scope.Names) > 0:
+		caseEnv = object.NewScopeEnvironment(env, c.Scope)
+	}
+
+	if c.Pattern != nil {
+		if m := bindPattern(c.Pattern, false, value, caseEnv); m != nil {
+			return m.error()
+		}
+	}
+	return Eval(c.Result, caseEnv)
+}
@@ -0,0 +1,123 @@
+package evaluator
+
+import "github.com/timur-makarov/monkey-interpreter/internal/object"
+
+func init() {
+	builtins["copy"] = object.Builtin{Function: bf.copy, Arity: object.Arity{Min: 1, Max: 1}}
+	builtins["deepCopy"] = object.Builtin{Function: bf.deepCopy, Arity: object.Arity{Min: 1, Max: 1}}
+	builtins["freeze"] = object.Builtin{Function: bf.freeze, Arity: object.Arity{Min: 1, Max: 1}}
+}
+
+func (bf BuiltinFunctions) copy(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+
+	switch v := Unwrap(args[0]).(type) {
+	case *object.Array:
+		return &object.Array{Items: v.Snapshot()}
+	case *object.HashTable:
+		return &object.HashTable{Items: v.Snapshot()}
+	case *object.Struct:
+		return &object.Struct{Definition: v.Definition, Fields: v.Snapshot()}
+	default:
+		return v
+	}
+}
+
+func (bf BuiltinFunctions) deepCopy(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	return DeepCopy(args[0])
+}
+
+func (bf BuiltinFunctions) freeze(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	return Freeze(args[0])
+}
+
+// DeepCopy returns a copy of an array, a hash table or a struct, in which
+// the arrays, hash tables and structs it holds are copied too, so that
+// nothing assigned to it is seen through the original. The copies are never
+// frozen. Other values cannot be changed and are returned as they are.
+func DeepCopy(value object.Object) object.Object {
+	c := copier{copies: make(map[object.Object]object.Object)}
+	return c.copy(Unwrap(value))
+}
+
+// Freeze returns a frozen copy of an array, a hash table or a struct, in
+// which the arrays, hash tables and structs it holds are frozen too, so
+// that no assignment by index or field can change it. Other values cannot
+// be changed anyway and are returned as they are, as are collections
+// already frozen.
+//
+// Hosts can pass frozen values to scripts they do not trust, bound with
+// object.Environment.DefineConstant so that the name cannot be rebound
+// either.
+func Freeze(value object.Object) object.Object {
+	c := copier{frozen: true, copies: make(map[object.Object]object.Object)}
+	return c.copy(Unwrap(value))
+}
+
+// copier copies arrays, hash tables and structs deeply. It maps those
+// copied so far to their copies, so that collections held more than once,
+// or holding themselves, are copied once and keep their shape.
+type copier struct {
+	frozen bool
+	copies map[object.Object]object.Object
+}
+
+func (c copier) copy(value object.Object) object.Object {
+	switch v := value.(type) {
+	case *object.Array:
+		if copied, ok := c.copies[v]; ok {
+			return copied
+		}
+		if c.frozen && v.Frozen {
+			return v
+		}
+
+		items := v.Snapshot()
+		copied := &object.Array{Items: make([]object.Object, len(items)), Frozen: c.frozen}
+		c.copies[v] = copied
+		for i, item := range items {
+			copied.Items[i] = c.copy(item)
+		}
+		return copied
+	case *object.HashTable:
+		if copied, ok := c.copies[v]; ok {
+			return copied
+		}
+		if c.frozen && v.Frozen {
+			return v
+		}
+
+		items := v.Snapshot()
+		copied := &object.HashTable{Items: make(map[string]object.Object, len(items)), Frozen: c.frozen}
+		c.copies[v] = copied
+		for key, item := range items {
+			copied.Items[key] = c.copy(item)
+		}
+		return copied
+	case *object.Struct:
+		if copied, ok := c.copies[v]; ok {
+			return copied
+		}
+		if c.frozen && v.Frozen {
+			return v
+		}
+
+		fields := v.Snapshot()
+		copied := &object.Struct{Definition: v.Definition, Fields: make(map[string]object.Object, len(fields)), Frozen: c.frozen}
+		c.copies[v] = copied
+		for field, value := range fields {
+			copied.Fields[field] = c.copy(value)
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -7,12 +7,28 @@ import (
 
 func evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
+	execution := executionOf(env)
 
 	for _, statement := range statements {
+		if execution != nil {
+			if stop := execution.beforeStatement(statement, env); stop != nil {
+				return stop
+			}
+		}
+
 		result = Eval(statement, env)
 
+		if execution != nil {
+			execution.afterStatement(statement, result)
+		}
+
 		switch res := result.(type) {
 		case object.Return:
+			// There is no function to make a call in tail position in
+			// place of, outside of functions.
+			if tc, ok := res.Value.(tailCall); ok {
+				return evalFunction(tc.function, tc.args, tc.env)
+			}
 			return res.Value
 		case object.Error:
 			return res
@@ -30,12 +46,43 @@ func evalProgram(statements []ast.Statement, env *object.Environment) object.Obj
 	return result
 }
 
-func evalBlock(statements []ast.Statement, env *object.Environment) object.Object {
+// evalBlock evaluates a block in an environment of its own, or, if it is
+// bound and binds no names, in that of the enclosing code.
+func evalBlock(block ast.BlockStatement, env *object.Environment) object.Object {
+	return evalStatements(block.Statements, blockEnvironment(block, env))
+}
+
+// blockEnvironment creates the environment of a block evaluated in env,
+// unless it is bound and binds no names, and returns env itself.
+func blockEnvironment(block ast.BlockStatement, env *object.Environment) *object.Environment {
+	switch {
+	case block.Scope == nil:
+		return object.NewEnclosedEnvironment(env)
+	case len(block.Scope.Names) > 0:
+		return object.NewScopeEnvironment(env, block.Scope)
+	default:
+		return env
+	}
+}
+
+// evalStatements evaluates the statements of a block in its environment,
+// until one returns or fails.
+func evalStatements(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
-	enclosedEnv := object.NewEnclosedEnvironment(env)
+	execution := executionOf(env)
 
 	for _, statement := range statements {
-		result = Eval(statement, enclosedEnv)
+		if execution != nil {
+			if stop := execution.beforeStatement(statement, env); stop != nil {
+				return stop
+			}
+		}
+
+		result = Eval(statement, env)
+
+		if execution != nil {
+			execution.afterStatement(statement, result)
+		}
 
 		rt := result.Type()
 		if rt == object.ReturnType || rt == object.ErrorType {
@@ -58,13 +105,52 @@ func evalExpressions(expressions []ast.Expression, env *object.Environment) []ob
 		if evaluated.Type() == object.ErrorType {
 			return []object.Object{evaluated}
 		}
-		result = append(result, evaluated)
+		result = append(result, Unwrap(evaluated))
+	}
+
+	return result
+}
+
+// evalArguments evaluates the arguments of a call like evalExpressions,
+// passing the items of the arrays and iterators spread in place of them.
+func evalArguments(arguments []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+
+	for _, argument := range arguments {
+		spread, ok := argument.(ast.Spread)
+		if !ok {
+			evaluated := Eval(argument, env)
+			if evaluated.Type() == object.ErrorType {
+				return []object.Object{evaluated}
+			}
+			result = append(result, Unwrap(evaluated))
+			continue
+		}
+
+		evaluated := Eval(spread.Value, env)
+		if evaluated.Type() == object.ErrorType {
+			return []object.Object{evaluated}
+		}
+		switch value := Unwrap(evaluated).(type) {
+		case *object.Array:
+			result = append(result, value.Snapshot()...)
+		case *object.Iterator:
+			items := drain(value)
+			if len(items) == 1 && items[0].Type() == object.ErrorType {
+				return items
+			}
+			result = append(result, items...)
+		default:
+			return []object.Object{newError("cannot spread %s: not an array or an iterator", value.Type())}
+		}
 	}
 
 	return result
 }
 
 func evalPrefix(operator string, right object.Object) object.Object {
+	right = Unwrap(right)
+
 	switch operator {
 	case "!":
 		return evalBangOperator(right)
@@ -76,11 +162,18 @@ func evalPrefix(operator string, right object.Object) object.Object {
 }
 
 func evalInfix(operator string, left, right object.Object, env *object.Environment) object.Object {
+	// The left side of an assignment names what to assign to, otherwise
+	// only the values of the operands matter.
+	right = Unwrap(right)
+	if operator != "=" {
+		left = Unwrap(left)
+	}
+
 	switch {
 	case left.Type() == object.IntegerType && right.Type() == object.IntegerType:
 		return evalIntegerInfixOperators(operator, left.(object.Integer), right.(object.Integer))
 	case left.Type() == object.StringType && right.Type() == object.StringType:
-		return evalStringInfixOperators(operator, left.(object.String), right.(object.String))
+		return allocatedResult(env, evalStringInfixOperators(operator, left.(object.String), right.(object.String)))
 	case left.Type() == object.IdentifierType && right.Type() == object.IdentifierType:
 		return evalInfix(
 			operator, left.(object.Identifier).Value, right.(object.Identifier).Value, env,
@@ -90,22 +183,39 @@ func evalInfix(operator string, left, right object.Object, env *object.Environme
 	case right.Type() == object.AccessByExpressionType:
 		return evalInfix(operator, left, right.(object.AccessByExpression).Value, env)
 	case operator == "==":
-		return nativeBoolToObject(left == right)
+		return nativeBoolToObject(Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToObject(left != right)
+		return nativeBoolToObject(!Equal(left, right))
 	case operator == "=":
 		if left.Type() == object.IdentifierType {
-			env.Set(left.(object.Identifier).Name, right)
+			name := left.(object.Identifier).Name
+			if !env.Set(name, right) {
+				return newError("cannot assign to constant: %s", name)
+			}
 			return NULL
 		} else if left.Type() == object.AccessByExpressionType {
 			access := left.(object.AccessByExpression)
 			switch left := access.Left.(type) {
-			case object.Array:
+			case *object.Array:
+				if left.Frozen {
+					return newError("cannot assign to an index of a frozen array")
+				}
 				index := access.Expression.(object.Integer)
-				left.Items[index.Value] = right
-			case object.HashTable:
+				if !left.Set(index.Value, right) {
+					return newError("index out of bounds: got=%d", index.Value)
+				}
+			case *object.HashTable:
+				if left.Frozen {
+					return newError("cannot assign to a key of a frozen hash table")
+				}
 				key := access.Expression.(object.String)
-				left.Items[key.Value] = right
+				left.Set(key.Value, right)
+			case *object.Struct:
+				if left.Frozen {
+					return newError("cannot assign to a field of a frozen struct")
+				}
+				field := access.Expression.(object.String)
+				left.Set(field.Value, right)
 			}
 			return NULL
 		} else {
@@ -134,6 +244,9 @@ func evalIntegerInfixOperators(operator string, left, right object.Integer) obje
 		left.Value = left.Value * right.Value
 		return left
 	case "/":
+		if right.Value == 0 {
+			return newError("division by zero")
+		}
 		left.Value = left.Value / right.Value
 		return left
 	case ">":
@@ -198,34 +311,75 @@ func evalIf(node ast.If, env *object.Environment) object.Object {
 			return evaluated
 		}
 
-		if isTruthy(evaluated) {
+		if IsTruthy(Unwrap(evaluated)) {
+			branchTaken(env, node, i)
 			return Eval(node.Consequences[i], env)
 		}
 	}
 
+	branchTaken(env, node, len(node.Consequences))
 	return Eval(node.Alternative, env)
 }
 
 func evalWhile(node ast.While, env *object.Environment) object.Object {
-	condition := Eval(node.Condition, env)
-	if condition.Type() == object.ErrorType {
-		return condition
-	}
+	for {
+		condition := Eval(node.Condition, env)
+		if condition.Type() == object.ErrorType {
+			return condition
+		}
 
-	if cond, ok := condition.(*object.Boolean); ok {
-		for cond.Value {
-			evaluated := evalBlock(node.Body.Statements, env)
-			if evaluated.Type() == object.ErrorType {
-				return evaluated
-			}
-			cond = Eval(node.Condition, env).(*object.Boolean)
+		if cond, ok := Unwrap(condition).(*object.Boolean); !ok || !cond.Value {
+			return NULL
+		}
+
+		evaluated := evalBlock(node.Body, env)
+		if rt := evaluated.Type(); rt == object.ReturnType || rt == object.ErrorType {
+			return evaluated
 		}
 	}
+}
 
-	return NULL
+func evalFor(node ast.For, env *object.Environment) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if iterable.Type() == object.ErrorType {
+		return iterable
+	}
+
+	items, err := iterate(Unwrap(iterable))
+	if err != nil {
+		return err
+	}
+
+	for {
+		item := items.Next()
+		if item == nil {
+			return NULL
+		}
+		if item.Type() == object.ErrorType {
+			return item
+		}
+
+		bodyEnv := blockEnvironment(node.Body, env)
+		if m := bindPattern(node.Pattern, false, item, bodyEnv); m != nil {
+			return m.error()
+		}
+
+		evaluated := evalStatements(node.Body.Statements, bodyEnv)
+		if rt := evaluated.Type(); rt == object.ReturnType || rt == object.ErrorType {
+			return evaluated
+		}
+	}
 }
 
 func evalIdentifier(node ast.Identifier, env *object.Environment) object.Object {
+	// A local not bound yet, like a function declared later, may still be
+	// found by name, as a global or a builtin.
+	if local := node.Local; local != nil {
+		if val := env.GetAt(local.Depth, local.Slot); val != nil {
+			return object.Identifier{Value: val, Name: node.Value}
+		}
+	}
+
 	if val, ok := env.Get(node.Value); ok {
 		return object.Identifier{Value: val, Name: node.Value}
 	}
@@ -237,22 +391,39 @@ func evalIdentifier(node ast.Identifier, env *object.Environment) object.Object
 	return newError("identifier not found: %s", node.Value)
 }
 
+// evalLocalAssignment assigns to the slot of a local, the binding the
+// name would be found in, unless the local is not bound yet.
+func evalLocalAssignment(target ast.Identifier, value object.Object, env *object.Environment) object.Object {
+	local := target.Local
+
+	var assigned bool
+	if env.GetAt(local.Depth, local.Slot) == nil {
+		assigned = env.Set(target.Value, Unwrap(value))
+	} else {
+		assigned = env.SetAt(local.Depth, local.Slot, Unwrap(value))
+	}
+
+	if !assigned {
+		return newError("cannot assign to constant: %s", target.Value)
+	}
+	return NULL
+}
+
 func evalAccessByExpression(left object.Object, exp object.Object) object.Object {
 	switch l := left.(type) {
-	case object.Array:
+	case *object.Array:
 		if index, ok := exp.(object.Integer); ok {
-			if len(l.Items) <= index.Value || index.Value < 0 {
+			value, ok := l.Get(index.Value)
+			if !ok {
 				return newError("index out of bounds: got=%d", index.Value)
 			}
-			return object.AccessByExpression{
-				Left: l, Expression: index, Value: l.Items[index.Value],
-			}
+			return object.AccessByExpression{Left: l, Expression: index, Value: value}
 		} else {
 			return newError("access expression is not integer: got %s", index.Type())
 		}
-	case object.HashTable:
+	case *object.HashTable:
 		if key, ok := exp.(object.String); ok {
-			value, ok := l.Items[key.Value]
+			value, ok := l.Get(key.Value)
 			if !ok {
 				return object.AccessByExpression{Left: l, Expression: key, Value: NULL}
 			}
@@ -262,6 +433,8 @@ func evalAccessByExpression(left object.Object, exp object.Object) object.Object
 		}
 	case object.Identifier:
 		return evalAccessByExpression(l.Value, exp)
+	case object.AccessByExpression:
+		return evalAccessByExpression(l.Value, exp)
 	default:
 		return newError("access by expression is not supported for this type: got %s", left.Type())
 	}
@@ -270,7 +443,9 @@ func evalAccessByExpression(left object.Object, exp object.Object) object.Object
 func evalHashTable(node ast.HashTable, env *object.Environment) object.Object {
 	items := make(map[string]object.Object, len(node.Items))
 
-	for key, val := range node.Items {
+	// In source order, so that the last of repeated keys wins.
+	for _, key := range node.Keys {
+		val := node.Items[key]
 		var keyString string
 
 		switch k := key.(type) {
@@ -295,38 +470,208 @@ func evalHashTable(node ast.HashTable, env *object.Environment) object.Object {
 		if evaluated.Type() == object.ErrorType {
 			return evaluated
 		}
-		items[keyString] = evaluated
+		items[keyString] = Unwrap(evaluated)
+	}
+
+	return allocated(env, &object.HashTable{Items: items})
+}
+
+func evalStructLiteral(node ast.StructLiteral, env *object.Environment) object.Object {
+	evaluated := Eval(node.Type, env)
+	if evaluated.Type() == object.ErrorType {
+		return evaluated
+	}
+
+	definition, ok := Unwrap(evaluated).(*object.StructDefinition)
+	if !ok {
+		return newError("not a struct type: %s", node.Type.Value)
+	}
+
+	// Fields not given are null.
+	fields := make(map[string]object.Object, len(definition.Fields))
+	for _, field := range definition.Fields {
+		fields[field] = NULL
+	}
+
+	for i, field := range node.Fields {
+		if !definition.HasField(field.Value) {
+			return newError("unknown field: %s.%s", definition.Name, field.Value)
+		}
+
+		value := Eval(node.Values[i], env)
+		if value.Type() == object.ErrorType {
+			return value
+		}
+		fields[field.Value] = Unwrap(value)
+	}
+
+	return allocated(env, &object.Struct{Definition: definition, Fields: fields})
+}
+
+// evalFieldAccess looks up a field of a struct, or else a method of the
+// value, bound to it.
+func evalFieldAccess(left object.Object, field string) object.Object {
+	s, ok := left.(*object.Struct)
+	if ok {
+		if value, ok := s.Get(field); ok {
+			return object.AccessByExpression{Left: s, Expression: object.String{Value: field}, Value: value}
+		}
+	}
+
+	if method, ok := LookupMethod(left.Type(), field); ok {
+		return object.BoundMethod{Receiver: left, Name: field, Method: method}
+	}
+
+	if ok {
+		return newError("unknown field: %s.%s", s.Definition.Name, field)
+	}
+	return newError("unknown method: %s.%s", left.Type(), field)
+}
+
+// MaxCallDepth is the number of nested function calls after which a call
+// fails with a stack overflow error, unless LimitCallDepth changes it.
+// Calls in tail position do not nest.
+const MaxCallDepth = 10000
+
+// LimitCallDepth changes the number of nested function calls after which a
+// call fails with a stack overflow error in the evaluations in env, or
+// restores MaxCallDepth if depth is not positive.
+func LimitCallDepth(env *object.Environment, depth int) {
+	executionFor(env).maxCallDepth = depth
+}
+
+func maxCallDepth(env *object.Environment) int {
+	if e := executionOf(env); e != nil && e.maxCallDepth > 0 {
+		return e.maxCallDepth
+	}
+	return MaxCallDepth
+}
+
+// tailCall is the value of a return statement whose value is a call: the
+// function returning makes the call in place of its own, so that tail
+// recursion runs in constant space.
+type tailCall struct {
+	function object.Object
+	args     []object.Object
+	// env is where the call was made.
+	env *object.Environment
+}
+
+const tailCallType object.Type = "TAIL_CALL"
+
+func (tc tailCall) Type() object.Type {
+	return tailCallType
+}
+
+func (tc tailCall) String() string {
+	return "tail call to " + tc.function.String()
+}
+
+func evalTailCall(call ast.Call, env *object.Environment) object.Object {
+	function := Eval(call.Function, env)
+	if function.Type() == object.ErrorType {
+		return function
+	}
+
+	args := evalArguments(call.Arguments, env)
+	if len(args) == 1 && args[0].Type() == object.ErrorType {
+		return args[0]
+	}
+
+	// Builtins and methods are called where the call is, since those that
+	// call back the functions they are given must nest those calls.
+	if _, ok := Unwrap(function).(*object.Function); !ok {
+		result := evalFunction(function, args, env)
+		if result.Type() == object.ErrorType {
+			return result
+		}
+		return object.Return{Value: Unwrap(result)}
 	}
 
-	return object.HashTable{Items: items}
+	return object.Return{Value: tailCall{function: function, args: args, env: env}}
 }
 
-func evalFunction(fn object.Object, args []object.Object, env *object.Environment) object.Object {
-	function, ok := fn.(object.Function)
+// evalFunction calls a function, and then the functions it calls in tail
+// position, as a trampoline.
+func evalFunction(fn object.Object, args []object.Object, caller *object.Environment) object.Object {
+	for {
+		result := callFunction(fn, args, caller)
+
+		tc, ok := result.(tailCall)
+		if !ok {
+			return result
+		}
+		fn, args = tc.function, tc.args
+	}
+}
+
+// callFunction makes a single call from caller.
+func callFunction(fn object.Object, args []object.Object, caller *object.Environment) object.Object {
+	switch f := fn.(type) {
+	case object.AccessByExpression:
+		// Functions held by collections and fields are called anonymously.
+		return callFunction(f.Value, args, caller)
+	case object.Builtin:
+		return callBuiltin(caller, "anonymous", f, args)
+	case object.BoundMethod:
+		return callMethod(caller, f, args)
+	}
+
+	function, ok := fn.(*object.Function)
 	if !ok {
 		identifier, ok := fn.(object.Identifier)
 		if !ok {
 			return newError("not a function: %s", fn.String())
 		}
 
-		if value, ok := env.Get(identifier.Name); ok {
-			if function, ok = value.(object.Function); !ok {
-				return newError("not a function: %s", value.String())
-			}
-		} else {
-			if builtin, ok := builtins[identifier.Name]; ok {
-				return builtin.Function(args...)
-			}
-			return newError("no such function: %s", identifier.Name)
+		switch value := identifier.Value.(type) {
+		case *object.Function:
+			function = value
+		case object.Builtin:
+			return callBuiltin(caller, identifier.Name, value, args)
+		case object.BoundMethod:
+			return callMethod(caller, value, args)
+		default:
+			return newError("not a function: %s", value.String())
 		}
 	}
 
-	extendedEnv := extendFunctionEnv(function, args)
-	evaluated := Eval(function.Body, extendedEnv)
+	if arity := function.Arity(); !arity.Accepts(len(args)) {
+		return newError("wrong number of arguments: got=%d, want=%s", len(args), arity)
+	}
 
-	if result, ok := evaluated.(object.Return); ok {
-		return result.Value
+	if limit := maxCallDepth(caller); caller.Depth() >= limit {
+		return newError("stack overflow: more than %d nested calls", limit)
 	}
 
-	return NULL
+	extendedEnv := extendFunctionEnv(function, caller)
+
+	name := "anonymous"
+	if identifier, ok := fn.(object.Identifier); ok {
+		name = identifier.Name
+	}
+
+	if execution := executionOf(caller); execution != nil {
+		execution.enter(name, extendedEnv)
+		defer execution.leave()
+	}
+
+	traceCall(caller, name, args)
+
+	var result object.Object = NULL
+	if err := bindParameters(function, args, extendedEnv); err != nil {
+		result = err
+	} else if function.Generator {
+		result = allocated(extendedEnv, newGenerator(name, function, extendedEnv))
+	} else {
+		switch evaluated := Eval(function.Body, extendedEnv).(type) {
+		case object.Return:
+			result = evaluated.Value
+		case object.Error:
+			result = evaluated
+		}
+	}
+
+	traceReturn(caller, name, result)
+	return result
 }
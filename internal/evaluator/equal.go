@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"reflect"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// Equal reports whether two values are equal, as == compares them: integers,
+// strings, booleans and null by value, arrays, hash tables and structs of
+// the same type by their items, deeply, and functions, iterators, channels,
+// tasks and struct types by identity. Values of different types are never equal.
+func Equal(left, right object.Object) bool {
+	return equality{}.equal(Unwrap(left), Unwrap(right))
+}
+
+// pair is two collections being compared.
+type pair struct {
+	left, right object.Object
+}
+
+// equality holds the pairs of collections compared so far. Collections
+// that hold themselves lead back to a pair being compared, which is then
+// taken to be equal: if they differ, they differ elsewhere too.
+type equality map[pair]bool
+
+func (e equality) equal(left, right object.Object) bool {
+	switch l := left.(type) {
+	case object.Integer:
+		r, ok := right.(object.Integer)
+		return ok && l.Value == r.Value
+	case object.String:
+		r, ok := right.(object.String)
+		return ok && l.Value == r.Value
+	case *object.Boolean:
+		r, ok := right.(*object.Boolean)
+		return ok && l.Value == r.Value
+	case *object.Null, object.Null:
+		return right.Type() == object.NullType
+	case *object.Function:
+		r, ok := right.(*object.Function)
+		return ok && l == r
+	case *object.Iterator:
+		r, ok := right.(*object.Iterator)
+		return ok && l == r
+	case *channel:
+		r, ok := right.(*channel)
+		return ok && l == r
+	case *task:
+		r, ok := right.(*task)
+		return ok && l == r
+	case object.Builtin:
+		r, ok := right.(object.Builtin)
+		return ok && reflect.ValueOf(l.Function).Pointer() == reflect.ValueOf(r.Function).Pointer() &&
+			reflect.ValueOf(l.WithCaller).Pointer() == reflect.ValueOf(r.WithCaller).Pointer()
+	case *object.Array:
+		r, ok := right.(*object.Array)
+		if !ok {
+			return false
+		}
+		if l == r {
+			return true
+		}
+		lItems, rItems := l.Snapshot(), r.Snapshot()
+		if len(lItems) != len(rItems) {
+			return false
+		}
+		if e.visit(l, r) {
+			return true
+		}
+
+		for i := range lItems {
+			if !e.equal(lItems[i], rItems[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.HashTable:
+		r, ok := right.(*object.HashTable)
+		if !ok {
+			return false
+		}
+		if l == r {
+			return true
+		}
+		lItems, rItems := l.Snapshot(), r.Snapshot()
+		if len(lItems) != len(rItems) {
+			return false
+		}
+		if e.visit(l, r) {
+			return true
+		}
+
+		for key, value := range lItems {
+			other, ok := rItems[key]
+			if !ok || !e.equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case *object.StructDefinition:
+		r, ok := right.(*object.StructDefinition)
+		return ok && l == r
+	case *object.Struct:
+		r, ok := right.(*object.Struct)
+		if !ok || l.Definition != r.Definition {
+			return false
+		}
+		if l == r || e.visit(l, r) {
+			return true
+		}
+
+		lFields, rFields := l.Snapshot(), r.Snapshot()
+		for _, field := range l.Definition.Fields {
+			if !e.equal(lFields[field], rFields[field]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// visit records that two collections are being compared, and reports
+// whether they already were.
+func (e equality) visit(left, right object.Object) bool {
+	p := pair{left: left, right: right}
+	if e[p] {
+		return true
+	}
+	e[p] = true
+	return false
+}
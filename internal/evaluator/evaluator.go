@@ -12,19 +12,33 @@ var (
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if e := executionOf(env); e != nil && e.tracer != nil && node != nil {
+		return e.trace(node, env)
+	}
+	return eval(node, env)
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch n := node.(type) {
 	case *ast.Program:
 		return evalProgram(n.Statements, env)
 	case ast.BlockStatement:
-		return evalBlock(n.Statements, env)
+		return evalBlock(n, env)
 	case ast.ExpressionStatement:
 		return Eval(n.Expression, env)
 	case ast.ReturnStatement:
+		if call, ok := n.Value.(ast.Call); ok && env.Depth() > 0 {
+			return evalTailCall(call, env)
+		}
 		val := Eval(n.Value, env)
 		if val.Type() == object.ErrorType {
 			return val
 		}
-		return object.Return{Value: val}
+		return object.Return{Value: Unwrap(val)}
+	case ast.YieldStatement:
+		// Generators run their yields themselves, and the resolver rejects
+		// those anywhere else.
+		return newError("yield outside of a generator")
 	case ast.Integer:
 		return object.Integer{Value: n.Value}
 	case ast.String:
@@ -46,27 +60,54 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if right.Type() == object.ErrorType {
 			return right
 		}
+		if target, ok := n.Left.(ast.Identifier); ok && target.Local != nil && n.Operator == "=" {
+			return evalLocalAssignment(target, right, env)
+		}
 		return evalInfix(n.Operator, left, right, env)
 	case ast.If:
 		return evalIf(n, env)
 	case ast.While:
 		return evalWhile(n, env)
+	case ast.For:
+		return evalFor(n, env)
+	case ast.Match:
+		return evalMatch(n, env)
+	case ast.Spawn:
+		return evalSpawn(n, env)
+	case ast.Select:
+		return evalSelect(n, env)
 	case ast.LetStatement:
 		val := Eval(n.Value, env)
 		if val.Type() == object.ErrorType {
 			return val
 		}
-		env.Set(n.Name.Value, val)
+		if n.Name == nil {
+			if m := bindPattern(n.Pattern, n.Constant(), Unwrap(val), env); m != nil {
+				return m.error()
+			}
+		} else if !bind(n.Name, n.Constant(), Unwrap(val), env) {
+			return newError("cannot assign to constant: %s", n.Name.Value)
+		}
+	case ast.StructStatement:
+		fields := make([]string, len(n.Fields))
+		for i, field := range n.Fields {
+			fields[i] = field.Value
+		}
+		if !bind(n.Name, false, &object.StructDefinition{Name: n.Name.Value, Fields: fields}, env) {
+			return newError("cannot assign to constant: %s", n.Name.Value)
+		}
 	case ast.Identifier:
 		return evalIdentifier(n, env)
 	case ast.Function:
-		return object.Function{Parameters: n.Parameters, Env: env, Body: n.Body}
+		return allocated(env, &object.Function{
+			Parameters: n.Parameters, Rest: n.Rest, Generator: n.Generator, Env: env, Body: n.Body, Scope: n.Scope,
+		})
 	case ast.Call:
 		function := Eval(n.Function, env)
 		if function.Type() == object.ErrorType {
 			return function
 		}
-		args := evalExpressions(n.Arguments, env)
+		args := evalArguments(n.Arguments, env)
 		if len(args) == 1 && args[0].Type() == object.ErrorType {
 			return args[0]
 		}
@@ -76,7 +117,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(items) == 1 && items[0].Type() == object.ErrorType {
 			return items[0]
 		}
-		return object.Array{Items: items}
+		return allocated(env, &object.Array{Items: items})
 	case ast.AccessByExpression:
 		left := Eval(n.Left, env)
 		if left.Type() == object.ErrorType {
@@ -84,11 +125,19 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		exp := Eval(n.Index, env)
 		if exp.Type() == object.ErrorType {
-			return left
+			return exp
 		}
-		return evalAccessByExpression(left, exp)
+		return evalAccessByExpression(left, Unwrap(exp))
 	case ast.HashTable:
 		return evalHashTable(n, env)
+	case ast.StructLiteral:
+		return evalStructLiteral(n, env)
+	case ast.FieldAccess:
+		left := Eval(n.Left, env)
+		if left.Type() == object.ErrorType {
+			return left
+		}
+		return evalFieldAccess(Unwrap(left), n.Field.Value)
 	}
 
 	return NULL
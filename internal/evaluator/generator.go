@@ -0,0 +1,243 @@
+package evaluator
+
+import (
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// generator runs the body of a call to a generator function a statement at
+// a time, up to the next yield. Where it stopped is kept as the blocks it is
+// in the middle of rather than on the stack of a goroutine, so an iterator
+// dropped before it is used up is garbage like any other value.
+//
+// Statements that cannot yield are evaluated as anywhere else. The if, while
+// and for expressions holding yields, which the resolver only allows as
+// statements, are run by the generator block by block.
+type generator struct {
+	name string
+	// env is the environment of the call.
+	env    *object.Environment
+	blocks []*generatorBlock
+	// running is set while the generator runs, which its body cannot
+	// resume it during.
+	running bool
+}
+
+// generatorBlock is a block the generator is in the middle of.
+type generatorBlock struct {
+	statements []ast.Statement
+	// next is the index of the statement to run next.
+	next int
+	env  *object.Environment
+	// loop is the while or for loop, evaluated in outer, that the block
+	// is the body of, and runs again once it ends. items are the items
+	// left to a for loop.
+	loop  ast.Expression
+	outer *object.Environment
+	items *object.Iterator
+}
+
+func newGenerator(name string, fn *object.Function, env *object.Environment) *object.Iterator {
+	g := &generator{name: name, env: env}
+	g.enter(fn.Body, env)
+	return &object.Iterator{Next: g.next}
+}
+
+func (g *generator) next() object.Object {
+	if len(g.blocks) == 0 {
+		return nil
+	}
+	if g.running {
+		return newError("generator %s is already running", g.name)
+	}
+
+	g.running = true
+	defer func() { g.running = false }()
+
+	if execution := executionOf(g.env); execution != nil {
+		execution.enter(g.name, g.env)
+		defer execution.leave()
+	}
+
+	value, suspended := g.resume()
+	if !suspended {
+		g.blocks = nil
+	}
+	return value
+}
+
+// resume runs the generator up to the next yield, and reports whether it
+// got there rather than to the end, to an error or to a return.
+func (g *generator) resume() (object.Object, bool) {
+	execution := executionOf(g.env)
+
+	for len(g.blocks) > 0 {
+		block := g.blocks[len(g.blocks)-1]
+		if block.next == len(block.statements) {
+			if err := g.repeat(block); err != nil {
+				return err, false
+			}
+			continue
+		}
+
+		statement := block.statements[block.next]
+		block.next++
+
+		if execution != nil {
+			if stop := execution.beforeStatement(statement, block.env); stop != nil {
+				return stop, false
+			}
+		}
+
+		result, yielded := g.run(statement, block.env)
+
+		if execution != nil {
+			execution.afterStatement(statement, result)
+		}
+
+		switch res := result.(type) {
+		case object.Return:
+			// What a generator returns is not produced, but a call in
+			// tail position is still made.
+			if tc, ok := res.Value.(tailCall); ok {
+				if err, ok := evalFunction(tc.function, tc.args, tc.env).(object.Error); ok {
+					return err, false
+				}
+			}
+			return nil, false
+		case object.Error:
+			return res, false
+		}
+
+		if yielded {
+			return result, true
+		}
+	}
+
+	return nil, false
+}
+
+// run evaluates a statement, and reports whether it yielded the value it
+// evaluates to.
+func (g *generator) run(statement ast.Statement, env *object.Environment) (object.Object, bool) {
+	switch st := statement.(type) {
+	case ast.YieldStatement:
+		value := Eval(st.Value, env)
+		if value.Type() == object.ErrorType {
+			return value, false
+		}
+		return Unwrap(value), true
+	case ast.ExpressionStatement:
+		if !suspends(st) {
+			break
+		}
+
+		switch e := st.Expression.(type) {
+		case ast.If:
+			return g.enterIf(e, env), false
+		case ast.While:
+			g.blocks = append(g.blocks, &generatorBlock{loop: e, outer: env})
+		case ast.For:
+			iterable := Eval(e.Iterable, env)
+			if iterable.Type() == object.ErrorType {
+				return iterable, false
+			}
+			items, err := iterate(Unwrap(iterable))
+			if err != nil {
+				return err, false
+			}
+			g.blocks = append(g.blocks, &generatorBlock{loop: e, outer: env, items: items})
+		}
+		return NULL, false
+	}
+
+	return Eval(statement, env), false
+}
+
+func (g *generator) enter(block ast.BlockStatement, env *object.Environment) {
+	g.blocks = append(g.blocks, &generatorBlock{statements: block.Statements, env: blockEnvironment(block, env)})
+}
+
+func (g *generator) enterIf(node ast.If, env *object.Environment) object.Object {
+	for i, condition := range node.Conditions {
+		evaluated := Eval(condition, env)
+		if evaluated.Type() == object.ErrorType {
+			return evaluated
+		}
+
+		if IsTruthy(Unwrap(evaluated)) {
+			branchTaken(env, node, i)
+			g.enter(node.Consequences[i], env)
+			return NULL
+		}
+	}
+
+	branchTaken(env, node, len(node.Consequences))
+	g.enter(node.Alternative, env)
+	return NULL
+}
+
+// repeat runs the body of the loop a block has ended in again, if the loop
+// goes on, and leaves the block otherwise.
+func (g *generator) repeat(block *generatorBlock) object.Object {
+	switch loop := block.loop.(type) {
+	case ast.While:
+		condition := Eval(loop.Condition, block.outer)
+		if condition.Type() == object.ErrorType {
+			return condition
+		}
+		if cond, ok := Unwrap(condition).(*object.Boolean); ok && cond.Value {
+			block.statements, block.next = loop.Body.Statements, 0
+			block.env = blockEnvironment(loop.Body, block.outer)
+			return nil
+		}
+	case ast.For:
+		item := block.items.Next()
+		if item != nil && item.Type() == object.ErrorType {
+			return item
+		}
+		if item != nil {
+			env := blockEnvironment(loop.Body, block.outer)
+			if m := bindPattern(loop.Pattern, false, item, env); m != nil {
+				return m.error()
+			}
+			block.statements, block.next, block.env = loop.Body.Statements, 0, env
+			return nil
+		}
+	}
+
+	g.blocks = g.blocks[:len(g.blocks)-1]
+	return nil
+}
+
+// suspends reports whether a statement of a generator may yield.
+func suspends(statement ast.Statement) bool {
+	switch st := statement.(type) {
+	case ast.YieldStatement:
+		return true
+	case ast.ExpressionStatement:
+		switch e := st.Expression.(type) {
+		case ast.If:
+			for _, consequence := range e.Consequences {
+				if blockSuspends(consequence) {
+					return true
+				}
+			}
+			return blockSuspends(e.Alternative)
+		case ast.While:
+			return blockSuspends(e.Body)
+		case ast.For:
+			return blockSuspends(e.Body)
+		}
+	}
+	return false
+}
+
+func blockSuspends(block ast.BlockStatement) bool {
+	for _, statement := range block.Statements {
+		if suspends(statement) {
+			return true
+		}
+	}
+	return false
+}
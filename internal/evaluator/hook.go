@@ -0,0 +1,187 @@
+package evaluator
+
+import (
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// Hook observes an evaluation it has been attached to. It is called
+// synchronously on the evaluating goroutine, so blocking in a hook pauses
+// the program. Tasks the program spawns call it from goroutines of their
+// own, concurrently unless a Scheduler runs them.
+type Hook interface {
+	// Statement is called before each statement is evaluated, with the
+	// call stack ordered from the outermost frame. Returning a non-nil
+	// object stops the evaluation, which then evaluates to that object.
+	Statement(statement ast.Statement, stack []*Frame) object.Object
+	// Error is called once for every error, in the innermost statement
+	// that produced it and before the stack unwinds.
+	Error(err object.Error, stack []*Frame)
+}
+
+// Observer is a hook that also follows the evaluation between statements:
+// when they are done, when functions are called and return, and which
+// values are allocated. It is what profilers need.
+type Observer interface {
+	Hook
+	// StatementDone is called after a statement the hook saw has been
+	// evaluated, however it ended.
+	StatementDone(statement ast.Statement, stack []*Frame)
+	// Call is called with the frame of a function call on top of the
+	// stack, before its body is evaluated.
+	Call(stack []*Frame)
+	// Return is called when the function on top of the stack returns,
+	// before its frame is removed.
+	Return(stack []*Frame)
+	// Allocate is called for every array, hash table, struct, string,
+	// function and iterator the evaluation creates.
+	Allocate(value object.Object, stack []*Frame)
+}
+
+// BranchHook is a hook that is also told which branch of every if
+// expression is taken. Coverage tools need it.
+type BranchHook interface {
+	Hook
+	// Branch is called with the index of the consequence taken, or with
+	// the number of consequences if none is and the alternative is taken,
+	// whether there is an else block or not.
+	Branch(expression ast.If, branch int, stack []*Frame)
+}
+
+// Frame is an entry of the call stack: the main program or a call to a
+// function that has not returned yet.
+type Frame struct {
+	Function string
+	// Line and Column locate the statement the frame is executing.
+	Line   int
+	Column int
+	// Env is the environment of that statement, and Call the one created
+	// for the call, which encloses it. The main frame's Call is the global
+	// environment.
+	Env  *object.Environment
+	Call *object.Environment
+}
+
+// execution is the state of one evaluation with a hook, a tracer, a
+// scheduler or a call depth limit attached, or of a task it spawned.
+type execution struct {
+	hook       Hook
+	observer   Observer
+	branchHook BranchHook
+	tracer     Tracer
+	scheduler  *Scheduler
+	// maxCallDepth replaces MaxCallDepth if it is positive.
+	maxCallDepth  int
+	stack         []*Frame
+	errorReported bool
+	errorTraced   bool
+}
+
+// Attach makes the hook observe every evaluation in env, which becomes the
+// environment of the main frame.
+func Attach(env *object.Environment, hook Hook) {
+	e := executionFor(env)
+	e.hook = hook
+	e.observer, _ = hook.(Observer)
+	e.branchHook, _ = hook.(BranchHook)
+}
+
+// executionFor returns the execution state of env, creating it with env as
+// the environment of the main frame if there is none yet.
+func executionFor(env *object.Environment) *execution {
+	if e := executionOf(env); e != nil {
+		return e
+	}
+
+	e := &execution{stack: []*Frame{{Function: "main", Env: env, Call: env}}}
+	env.SetExecution(e)
+	return e
+}
+
+// fork returns the execution state of a task spawned from this execution,
+// with the same hook, tracer, scheduler and call depth limit, and a stack of its own whose
+// first frame is that of the task, in env.
+func (e *execution) fork(env *object.Environment) *execution {
+	forked := *e
+	forked.stack = []*Frame{{Function: "task", Env: env, Call: env}}
+	forked.errorReported, forked.errorTraced = false, false
+	return &forked
+}
+
+func executionOf(env *object.Environment) *execution {
+	e, _ := env.Execution().(*execution)
+	return e
+}
+
+// beforeStatement moves the current frame to the statement and calls the hook.
+func (e *execution) beforeStatement(statement ast.Statement, env *object.Environment) object.Object {
+	tok := ast.StartToken(statement)
+	frame := e.stack[len(e.stack)-1]
+	frame.Line, frame.Column, frame.Env = tok.Line, tok.Column, env
+
+	if e.hook == nil {
+		return nil
+	}
+	return e.hook.Statement(statement, e.stack)
+}
+
+// afterStatement reports an error the first time a statement evaluates to it.
+func (e *execution) afterStatement(statement ast.Statement, result object.Object) {
+	if e.observer != nil {
+		e.observer.StatementDone(statement, e.stack)
+	}
+
+	err, ok := result.(object.Error)
+	if !ok {
+		e.errorReported = false
+		return
+	}
+
+	if !e.errorReported && e.hook != nil {
+		e.errorReported = true
+		e.hook.Error(err, e.stack)
+	}
+}
+
+func (e *execution) enter(name string, env *object.Environment) {
+	e.stack = append(e.stack, &Frame{Function: name, Env: env, Call: env})
+
+	if e.observer != nil {
+		e.observer.Call(e.stack)
+	}
+}
+
+func (e *execution) leave() {
+	if e.observer != nil {
+		e.observer.Return(e.stack)
+	}
+
+	e.stack = e.stack[:len(e.stack)-1]
+}
+
+// allocated tells the observer of an evaluation about a value it created.
+func allocated(env *object.Environment, value object.Object) object.Object {
+	if e := executionOf(env); e != nil && e.observer != nil {
+		e.observer.Allocate(value, e.stack)
+	}
+	return value
+}
+
+// branchTaken tells the branch hook of an evaluation which branch of an if
+// expression it takes.
+func branchTaken(env *object.Environment, expression ast.If, branch int) {
+	if e := executionOf(env); e != nil && e.branchHook != nil {
+		e.branchHook.Branch(expression, branch, e.stack)
+	}
+}
+
+// allocatedResult reports the result of an operation as allocated if it is
+// a value of a type that is allocated.
+func allocatedResult(env *object.Environment, value object.Object) object.Object {
+	switch value.(type) {
+	case *object.Array, *object.HashTable, *object.Struct, object.String, *object.Function, *object.Iterator:
+		return allocated(env, value)
+	default:
+		return value
+	}
+}
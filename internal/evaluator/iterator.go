@@ -0,0 +1,245 @@
+package evaluator
+
+import (
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+func init() {
+	builtins["iter"] = object.Builtin{Function: bf.iter, Arity: object.Arity{Min: 1, Max: 1}}
+	builtins["collect"] = object.Builtin{Function: bf.collect, Arity: object.Arity{Min: 1, Max: 1}}
+}
+
+func (bf BuiltinFunctions) iter(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+
+	items, err := iterate(Unwrap(args[0]))
+	if err != nil {
+		return err
+	}
+	return items
+}
+
+func (bf BuiltinFunctions) collect(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+
+	items, err := iterate(Unwrap(args[0]))
+	if err != nil {
+		return err
+	}
+
+	collected := drain(items)
+	if len(collected) == 1 && collected[0].Type() == object.ErrorType {
+		return collected[0]
+	}
+	return &object.Array{Items: collected}
+}
+
+// iterate returns an iterator over the items of an array, the characters of
+// a string, the keys of a hash table in sorted order, what is left of an
+// iterator, or the values received from a channel until it is closed. The
+// items of collections are those they hold when iterate is called.
+func iterate(value object.Object) (*object.Iterator, object.Object) {
+	switch v := value.(type) {
+	case *object.Iterator:
+		return v, nil
+	case *object.Array:
+		return sliceIterator(v.Snapshot()), nil
+	case object.String:
+		var items []object.Object
+		for _, r := range v.Value {
+			items = append(items, object.String{Value: string(r)})
+		}
+		return sliceIterator(items), nil
+	case *object.HashTable:
+		keys := sortedKeys(v.Snapshot())
+		items := make([]object.Object, len(keys))
+		for i, key := range keys {
+			items[i] = object.String{Value: key}
+		}
+		return sliceIterator(items), nil
+	case *channel:
+		return &object.Iterator{Next: func() object.Object {
+			_, item, ok, err := exchange(v.scheduler, []selectCase{{channel: v}}, true)
+			switch {
+			case err != nil:
+				return err
+			case !ok:
+				return nil
+			default:
+				return item
+			}
+		}}, nil
+	default:
+		return nil, newError("cannot iterate over %s", value.Type())
+	}
+}
+
+func sliceIterator(items []object.Object) *object.Iterator {
+	return &object.Iterator{Next: func() object.Object {
+		if len(items) == 0 {
+			return nil
+		}
+		item := items[0]
+		items = items[1:]
+		return item
+	}}
+}
+
+// drain uses up an iterator, returning its values, or only the error it
+// fails with.
+func drain(items *object.Iterator) []object.Object {
+	var result []object.Object
+	for {
+		item := items.Next()
+		if item == nil {
+			return result
+		}
+		if item.Type() == object.ErrorType {
+			return []object.Object{item}
+		}
+		result = append(result, item)
+	}
+}
+
+// count uses up an iterator and returns the number of its values, for len,
+// or the error it fails with.
+func count(items *object.Iterator) object.Object {
+	n := 0
+	for {
+		item := items.Next()
+		if item == nil {
+			return object.Integer{Value: n}
+		}
+		if item.Type() == object.ErrorType {
+			return item
+		}
+		n++
+	}
+}
+
+// appended returns an iterator over the values of another and then over
+// more, for append, taking the values of the other only as its own are
+// asked for.
+func appended(items *object.Iterator, more []object.Object) *object.Iterator {
+	return &object.Iterator{Next: func() object.Object {
+		if items != nil {
+			if item := items.Next(); item != nil {
+				return item
+			}
+			items = nil
+		}
+		if len(more) == 0 {
+			return nil
+		}
+		item := more[0]
+		more = more[1:]
+		return Unwrap(item)
+	}}
+}
+
+// drained makes a method of arrays one of iterators, which uses them up
+// into an array for it.
+func drained(method object.Builtin) object.CallerFunction {
+	return func(caller *object.Environment, args ...object.Object) object.Object {
+		items := drain(args[0].(*object.Iterator))
+		if len(items) == 1 && items[0].Type() == object.ErrorType {
+			return items[0]
+		}
+		return method.Call(caller, append([]object.Object{&object.Array{Items: items}}, args[1:]...)...)
+	}
+}
+
+// iteratorNext returns the next value of an iterator as a hash table, whose
+// "value" is null and "done" true once there are no more.
+func iteratorNext(args ...object.Object) object.Object {
+	item := args[0].(*object.Iterator).Next()
+	switch {
+	case item == nil:
+		return &object.HashTable{Items: map[string]object.Object{"value": NULL, "done": TRUE}}
+	case item.Type() == object.ErrorType:
+		return item
+	default:
+		return &object.HashTable{Items: map[string]object.Object{"value": item, "done": FALSE}}
+	}
+}
+
+// The combinators below are lazy: they return an iterator that takes values
+// from the one they are called on only as its own are asked for.
+
+func iteratorMap(caller *object.Environment, args ...object.Object) object.Object {
+	items, fn := args[0].(*object.Iterator), args[1]
+
+	return &object.Iterator{Next: func() object.Object {
+		item := items.Next()
+		if item == nil || item.Type() == object.ErrorType {
+			return item
+		}
+
+		mapped := applyFunction(fn, []object.Object{item}, caller)
+		if mapped.Type() == object.ErrorType {
+			return mapped
+		}
+		return Unwrap(mapped)
+	}}
+}
+
+func iteratorFilter(caller *object.Environment, args ...object.Object) object.Object {
+	items, fn := args[0].(*object.Iterator), args[1]
+
+	return &object.Iterator{Next: func() object.Object {
+		for {
+			item := items.Next()
+			if item == nil || item.Type() == object.ErrorType {
+				return item
+			}
+
+			keep := applyFunction(fn, []object.Object{item}, caller)
+			if keep.Type() == object.ErrorType {
+				return keep
+			}
+			if IsTruthy(Unwrap(keep)) {
+				return item
+			}
+		}
+	}}
+}
+
+func iteratorTake(args ...object.Object) object.Object {
+	items := args[0].(*object.Iterator)
+	count, ok := Unwrap(args[1]).(object.Integer)
+	if !ok {
+		return newError("argument type is not supported: got %s", Unwrap(args[1]).Type())
+	}
+
+	left := count.Value
+	return &object.Iterator{Next: func() object.Object {
+		if left <= 0 {
+			return nil
+		}
+		left--
+		return items.Next()
+	}}
+}
+
+func iteratorSkip(args ...object.Object) object.Object {
+	items := args[0].(*object.Iterator)
+	count, ok := Unwrap(args[1]).(object.Integer)
+	if !ok {
+		return newError("argument type is not supported: got %s", Unwrap(args[1]).Type())
+	}
+
+	skip := count.Value
+	return &object.Iterator{Next: func() object.Object {
+		for ; skip > 0; skip-- {
+			item := items.Next()
+			if item == nil || item.Type() == object.ErrorType {
+				return item
+			}
+		}
+		return items.Next()
+	}}
+}
@@ -0,0 +1,245 @@
+package evaluator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// methods holds the methods of the values of every type, by name. A method
+// is a builtin function called with the value it is looked up on followed
+// by the arguments of the call. Its arity counts the arguments only.
+var methods = make(map[object.Type]map[string]object.Builtin)
+
+// The methods are registered in init, because those taking callbacks call
+// back into the evaluator, which looks methods up.
+func init() {
+	methods[object.ArrayType] = map[string]object.Builtin{
+		"len":      {Function: bf.len, Arity: object.Arity{Min: 0, Max: 0}},
+		"push":     {Function: arrayPush, Arity: object.Arity{Min: 1, Max: -1}},
+		"map":      {WithCaller: arrayMap, Arity: object.Arity{Min: 1, Max: 1}},
+		"filter":   {WithCaller: arrayFilter, Arity: object.Arity{Min: 1, Max: 1}},
+		"reduce":   {WithCaller: arrayReduce, Arity: object.Arity{Min: 2, Max: 2}},
+		"join":     {Function: arrayJoin, Arity: object.Arity{Min: 1, Max: 1}},
+		"contains": {Function: arrayContains, Arity: object.Arity{Min: 1, Max: 1}},
+	}
+	methods[object.StringType] = map[string]object.Builtin{
+		"len":      {Function: bf.len, Arity: object.Arity{Min: 0, Max: 0}},
+		"split":    {Function: stringSplit, Arity: object.Arity{Min: 1, Max: 1}},
+		"contains": {Function: stringContains, Arity: object.Arity{Min: 1, Max: 1}},
+		"upper":    {Function: stringUpper, Arity: object.Arity{Min: 0, Max: 0}},
+		"lower":    {Function: stringLower, Arity: object.Arity{Min: 0, Max: 0}},
+		"trim":     {Function: stringTrim, Arity: object.Arity{Min: 0, Max: 0}},
+	}
+	methods[object.HashTableType] = map[string]object.Builtin{
+		"len":    {Function: hashTableLen, Arity: object.Arity{Min: 0, Max: 0}},
+		"keys":   {Function: hashTableKeys, Arity: object.Arity{Min: 0, Max: 0}},
+		"values": {Function: hashTableValues, Arity: object.Arity{Min: 0, Max: 0}},
+		"has":    {Function: hashTableHas, Arity: object.Arity{Min: 1, Max: 1}},
+	}
+	methods[object.IteratorType] = map[string]object.Builtin{
+		"next":     {Function: iteratorNext, Arity: object.Arity{Min: 0, Max: 0}},
+		"map":      {WithCaller: iteratorMap, Arity: object.Arity{Min: 1, Max: 1}},
+		"filter":   {WithCaller: iteratorFilter, Arity: object.Arity{Min: 1, Max: 1}},
+		"take":     {Function: iteratorTake, Arity: object.Arity{Min: 1, Max: 1}},
+		"skip":     {Function: iteratorSkip, Arity: object.Arity{Min: 1, Max: 1}},
+		"reduce":   {WithCaller: drained(methods[object.ArrayType]["reduce"]), Arity: object.Arity{Min: 2, Max: 2}},
+		"join":     {WithCaller: drained(methods[object.ArrayType]["join"]), Arity: object.Arity{Min: 1, Max: 1}},
+		"contains": {WithCaller: drained(methods[object.ArrayType]["contains"]), Arity: object.Arity{Min: 1, Max: 1}},
+		"collect":  {Function: bf.collect, Arity: object.Arity{Min: 0, Max: 0}},
+	}
+	methods[object.ChannelType] = map[string]object.Builtin{
+		"send":    {Function: channelSend, Arity: object.Arity{Min: 1, Max: 1}},
+		"receive": {Function: channelReceive, Arity: object.Arity{Min: 0, Max: 0}},
+		"close":   {Function: channelClose, Arity: object.Arity{Min: 0, Max: 0}},
+	}
+}
+
+// RegisterMethod gives the values of a type a method, or replaces the one
+// of the same name. The function is called with the value the method is
+// called on followed by the arguments, whose number the evaluator checks
+// against the arity first. Embedding hosts register their methods before
+// they evaluate any program.
+func RegisterMethod(t object.Type, name string, method object.Builtin) {
+	if methods[t] == nil {
+		methods[t] = make(map[string]object.Builtin)
+	}
+	methods[t][name] = method
+}
+
+func LookupMethod(t object.Type, name string) (object.Builtin, bool) {
+	method, ok := methods[t][name]
+	return method, ok
+}
+
+// MethodNames returns the names of the methods of a type, sorted.
+func MethodNames(t object.Type) []string {
+	names := make([]string, 0, len(methods[t]))
+	for name := range methods[t] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// callMethod calls a method with the value it was looked up on, for the
+// evaluation in env.
+func callMethod(env *object.Environment, method object.BoundMethod, args []object.Object) object.Object {
+	if !method.Method.Arity.Accepts(len(args)) {
+		return newError("wrong number of arguments to %s.%s: got=%d, want=%s",
+			method.Receiver.Type(), method.Name, len(args), method.Method.Arity)
+	}
+
+	args = append([]object.Object{method.Receiver}, args...)
+	return callBuiltin(env, method.Name, method.Method, args)
+}
+
+func arrayPush(args ...object.Object) object.Object {
+	array := args[0].(*object.Array)
+	if array.Frozen {
+		return newError("cannot push to a frozen array")
+	}
+
+	array.Push(args[1:]...)
+	return array
+}
+
+func arrayMap(caller *object.Environment, args ...object.Object) object.Object {
+	array := args[0].(*object.Array).Snapshot()
+
+	items := make([]object.Object, len(array))
+	for i, item := range array {
+		mapped := applyFunction(args[1], []object.Object{item}, caller)
+		if mapped.Type() == object.ErrorType {
+			return mapped
+		}
+		items[i] = Unwrap(mapped)
+	}
+	return &object.Array{Items: items}
+}
+
+func arrayFilter(caller *object.Environment, args ...object.Object) object.Object {
+	var items []object.Object
+	for _, item := range args[0].(*object.Array).Snapshot() {
+		keep := applyFunction(args[1], []object.Object{item}, caller)
+		if keep.Type() == object.ErrorType {
+			return keep
+		}
+		if IsTruthy(Unwrap(keep)) {
+			items = append(items, item)
+		}
+	}
+	return &object.Array{Items: items}
+}
+
+func arrayReduce(caller *object.Environment, args ...object.Object) object.Object {
+	accumulator := Unwrap(args[2])
+	for _, item := range args[0].(*object.Array).Snapshot() {
+		accumulator = applyFunction(args[1], []object.Object{accumulator, item}, caller)
+		if accumulator.Type() == object.ErrorType {
+			return accumulator
+		}
+		accumulator = Unwrap(accumulator)
+	}
+	return accumulator
+}
+
+func arrayJoin(args ...object.Object) object.Object {
+	array := args[0].(*object.Array).Snapshot()
+
+	separator, ok := Unwrap(args[1]).(object.String)
+	if !ok {
+		return newError("argument type is not supported: got %s", Unwrap(args[1]).Type())
+	}
+
+	items := make([]string, len(array))
+	for i, item := range array {
+		if str, ok := item.(object.String); ok {
+			items[i] = str.Value
+		} else {
+			items[i] = Inspect(item)
+		}
+	}
+	return object.String{Value: strings.Join(items, separator.Value)}
+}
+
+func arrayContains(args ...object.Object) object.Object {
+	for _, item := range args[0].(*object.Array).Snapshot() {
+		if Equal(item, args[1]) {
+			return TRUE
+		}
+	}
+	return FALSE
+}
+
+func stringSplit(args ...object.Object) object.Object {
+	separator, ok := Unwrap(args[1]).(object.String)
+	if !ok {
+		return newError("argument type is not supported: got %s", Unwrap(args[1]).Type())
+	}
+
+	parts := strings.Split(args[0].(object.String).Value, separator.Value)
+	items := make([]object.Object, len(parts))
+	for i, part := range parts {
+		items[i] = object.String{Value: part}
+	}
+	return &object.Array{Items: items}
+}
+
+func stringContains(args ...object.Object) object.Object {
+	substring, ok := Unwrap(args[1]).(object.String)
+	if !ok {
+		return newError("argument type is not supported: got %s", Unwrap(args[1]).Type())
+	}
+	return nativeBoolToObject(strings.Contains(args[0].(object.String).Value, substring.Value))
+}
+
+func stringUpper(args ...object.Object) object.Object {
+	return object.String{Value: strings.ToUpper(args[0].(object.String).Value)}
+}
+
+func stringLower(args ...object.Object) object.Object {
+	return object.String{Value: strings.ToLower(args[0].(object.String).Value)}
+}
+
+func stringTrim(args ...object.Object) object.Object {
+	return object.String{Value: strings.TrimSpace(args[0].(object.String).Value)}
+}
+
+func hashTableLen(args ...object.Object) object.Object {
+	return object.Integer{Value: args[0].(*object.HashTable).Len()}
+}
+
+// hashTableKeys returns the keys sorted, the order hash tables are printed
+// in.
+func hashTableKeys(args ...object.Object) object.Object {
+	keys := sortedKeys(args[0].(*object.HashTable).Snapshot())
+
+	items := make([]object.Object, len(keys))
+	for i, key := range keys {
+		items[i] = object.String{Value: key}
+	}
+	return &object.Array{Items: items}
+}
+
+// hashTableValues returns the values in the order of their sorted keys.
+func hashTableValues(args ...object.Object) object.Object {
+	hashTable := args[0].(*object.HashTable).Snapshot()
+	keys := sortedKeys(hashTable)
+
+	items := make([]object.Object, len(keys))
+	for i, key := range keys {
+		items[i] = hashTable[key]
+	}
+	return &object.Array{Items: items}
+}
+
+func hashTableHas(args ...object.Object) object.Object {
+	key, ok := Unwrap(args[1]).(object.String)
+	if !ok {
+		return newError("keys in hash tables must be strings: got %s", Unwrap(args[1]).Type())
+	}
+	_, has := args[0].(*object.HashTable).Get(key.Value)
+	return nativeBoolToObject(has)
+}
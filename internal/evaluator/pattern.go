@@ -0,0 +1,195 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+// evalMatch evaluates the first arm that matches the subject, in an
+// environment of its own holding the names its pattern binds, or, if the
+// arm is bound and its pattern binds none, in env.
+func evalMatch(node ast.Match, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if subject.Type() == object.ErrorType {
+		return subject
+	}
+	subject = Unwrap(subject)
+
+	for _, arm := range node.Arms {
+		armEnv := env
+		switch {
+		case arm.Scope == nil:
+			armEnv = object.NewEnclosedEnvironment(env)
+		case len(arm.Scope.Names) > 0:
+			armEnv = object.NewScopeEnvironment(env, arm.Scope)
+		}
+
+		if m := bindPattern(arm.Pattern, false, subject, armEnv); m != nil {
+			if m.err != nil {
+				return m.err
+			}
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if guard.Type() == object.ErrorType {
+				return guard
+			}
+			if !IsTruthy(Unwrap(guard)) {
+				continue
+			}
+		}
+
+		return Eval(arm.Result, armEnv)
+	}
+
+	return newError("no match for value: %s", Inspect(subject))
+}
+
+// mismatch is why a value does not match a pattern: the part of the pattern
+// at token expected something else.
+type mismatch struct {
+	token    token.Token
+	expected string
+	actual   object.Object
+	// err is set instead if the pattern could not be matched at all, like
+	// when a default evaluates to an error.
+	err object.Object
+}
+
+// error is the error of a mismatch where the value has to match, like in
+// a let statement or a call, located at the part of the pattern.
+func (m *mismatch) error() object.Object {
+	if m.err != nil {
+		return m.err
+	}
+	return newError("%d:%d: expected %s, got %s", m.token.Line, m.token.Column, m.expected, Inspect(m.actual))
+}
+
+// bindPattern binds the names of a pattern to the parts of a value they
+// match, in env and as constants if constant is set. It returns nil if the
+// value matches, or why it does not. The name _ binds nothing.
+func bindPattern(pattern ast.Node, constant bool, value object.Object, env *object.Environment) *mismatch {
+	switch p := pattern.(type) {
+	case ast.Integer:
+		return matchLiteral(p.Token, p.String(), object.Integer{Value: p.Value}, value)
+	case ast.String:
+		return matchLiteral(p.Token, p.String(), object.String{Value: p.Value}, value)
+	case ast.Boolean:
+		return matchLiteral(p.Token, p.String(), nativeBoolToObject(p.Value), value)
+	case ast.Identifier:
+		if p.Value != "_" && !bind(&p, constant, value, env) {
+			return &mismatch{err: newError("cannot assign to constant: %s", p.Value)}
+		}
+		return nil
+	case ast.DefaultPattern:
+		return bindPattern(p.Pattern, constant, value, env)
+	case ast.ArrayPattern:
+		return bindArrayPattern(p, constant, value, env)
+	case ast.HashTablePattern:
+		return bindHashTablePattern(p, constant, value, env)
+	default:
+		return &mismatch{err: newError("unknown pattern: %s", pattern)}
+	}
+}
+
+func matchLiteral(tok token.Token, expected string, literal, value object.Object) *mismatch {
+	if !Equal(literal, value) {
+		return &mismatch{token: tok, expected: expected, actual: value}
+	}
+	return nil
+}
+
+// bindArrayPattern matches the items of an array one by one. Items with a
+// default may be missing, and without a rest there may be no more.
+func bindArrayPattern(p ast.ArrayPattern, constant bool, value object.Object, env *object.Environment) *mismatch {
+	array, ok := value.(*object.Array)
+	if !ok {
+		return &mismatch{token: p.Token, expected: "an array", actual: value}
+	}
+	items := array.Snapshot()
+
+	required := 0
+	for i, item := range p.Items {
+		if _, ok := item.(ast.DefaultPattern); !ok {
+			required = i + 1
+		}
+	}
+
+	switch {
+	case p.Rest == nil && required == len(p.Items) && len(items) != required:
+		return &mismatch{token: p.Token, expected: describeItems("", required), actual: value}
+	case p.Rest == nil && len(items) > len(p.Items):
+		return &mismatch{token: p.Token, expected: describeItems("at most ", len(p.Items)), actual: value}
+	case len(items) < required:
+		return &mismatch{token: p.Token, expected: describeItems("at least ", required), actual: value}
+	}
+
+	for i, item := range p.Items {
+		var m *mismatch
+		if i < len(items) {
+			m = bindPattern(item, constant, items[i], env)
+		} else {
+			m = bindDefault(item.(ast.DefaultPattern), constant, env)
+		}
+		if m != nil {
+			return m
+		}
+	}
+
+	if p.Rest != nil && p.Rest.Value != "_" {
+		var rest []object.Object
+		if len(items) > len(p.Items) {
+			rest = items[len(p.Items):]
+		}
+		if !bind(p.Rest, constant, allocated(env, &object.Array{Items: rest}), env) {
+			return &mismatch{err: newError("cannot assign to constant: %s", p.Rest.Value)}
+		}
+	}
+	return nil
+}
+
+func describeItems(bound string, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("an array of %s1 item", bound)
+	}
+	return fmt.Sprintf("an array of %s%d items", bound, count)
+}
+
+// bindHashTablePattern matches the values of the keys of a hash table. Keys
+// with a default may be missing, and keys not in the pattern are ignored.
+func bindHashTablePattern(p ast.HashTablePattern, constant bool, value object.Object, env *object.Environment) *mismatch {
+	hashTable, ok := value.(*object.HashTable)
+	if !ok {
+		return &mismatch{token: p.Token, expected: "a hash table", actual: value}
+	}
+
+	for i, key := range p.Keys {
+		var m *mismatch
+		if item, ok := hashTable.Get(key.Value); ok {
+			m = bindPattern(p.Values[i], constant, item, env)
+		} else if withDefault, ok := p.Values[i].(ast.DefaultPattern); ok {
+			m = bindDefault(withDefault, constant, env)
+		} else {
+			m = &mismatch{token: key.Token, expected: "a hash table with the key " + key.String(), actual: value}
+		}
+		if m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// bindDefault matches the default of a pattern, evaluated in env, in place
+// of a value that is missing.
+func bindDefault(p ast.DefaultPattern, constant bool, env *object.Environment) *mismatch {
+	value := Eval(p.Default, env)
+	if value.Type() == object.ErrorType {
+		return &mismatch{err: value}
+	}
+	return bindPattern(p.Pattern, constant, Unwrap(value), env)
+}
@@ -0,0 +1,212 @@
+package evaluator
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// lock guards every channel and task, and the schedulers, which channel
+// operations change together.
+var lock sync.Mutex
+
+// Scheduler runs the tasks of an evaluation one at a time, switching from
+// one to the next only when the running one ends, blocks on a channel or
+// waits for a task. Which task runs next depends on nothing but the program
+// and the seed of the scheduler, so a concurrent script does the same on
+// every run, and tests of it are reproducible. Evaluations without a
+// scheduler run their tasks on goroutines of their own, in parallel.
+//
+// A task blocking while no other task can run is deadlocked, and so are
+// those blocked before it: all of them fail with an error.
+type Scheduler struct {
+	// random picks the task to run next among the ready ones, which run in
+	// the order they became ready if it is nil.
+	random *rand.Rand
+	// ready holds the waiters of the tasks that can go on, blocked those of
+	// the tasks waiting for a channel or another task.
+	ready   []*waiter
+	blocked []*waiter
+	// tasks counts the tasks that have been spawned and not ended.
+	tasks   int
+	stopped bool
+	// stopping is the waiter of Stop, woken once the last task ends.
+	stopping *waiter
+}
+
+// NewScheduler returns a scheduler running tasks in the order they become
+// ready if the seed is 0, or in an order drawn from the seed otherwise.
+func NewScheduler(seed uint64) *Scheduler {
+	s := &Scheduler{}
+	if seed != 0 {
+		s.random = rand.New(rand.NewPCG(seed, seed))
+	}
+	return s
+}
+
+// Schedule makes the scheduler run the tasks of the evaluations in env.
+func Schedule(env *object.Environment, scheduler *Scheduler) {
+	executionFor(env).scheduler = scheduler
+}
+
+func schedulerOf(env *object.Environment) *Scheduler {
+	if e := executionOf(env); e != nil {
+		return e.scheduler
+	}
+	return nil
+}
+
+// Stop ends the tasks the evaluations with the scheduler have left behind,
+// which never run on their own once the evaluations are done: those that
+// have not started never do, and the others fail with an error as soon as
+// they block. It returns when all of them have ended.
+func (s *Scheduler) Stop() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.stopped = true
+	for _, w := range slices.Clone(s.blocked) {
+		w.resume(-1, NULL, false, newError("task stopped"))
+	}
+	if s.tasks == 0 {
+		return
+	}
+
+	s.stopping = newWaiter(s)
+	s.next()
+	lock.Unlock()
+	<-s.stopping.wake
+	lock.Lock()
+}
+
+// waiter is a task blocked until a channel or another task lets it go on.
+type waiter struct {
+	scheduler *Scheduler
+	wake      chan struct{}
+	// woken is set as soon as the waiter is resumed, along with the index
+	// of the case of the select that proceeded, the value received and
+	// whether it was sent rather than the channel closed, or an error.
+	woken bool
+	index int
+	value object.Object
+	ok    bool
+	err   object.Object
+}
+
+func newWaiter(s *Scheduler) *waiter {
+	return &waiter{scheduler: s, wake: make(chan struct{}, 1)}
+}
+
+// resume wakes the waiter, or makes it ready to run if it has a scheduler.
+func (w *waiter) resume(index int, value object.Object, ok bool, err object.Object) {
+	w.woken = true
+	w.index, w.value, w.ok, w.err = index, value, ok, err
+
+	s := w.scheduler
+	if s == nil {
+		w.wake <- struct{}{}
+		return
+	}
+	s.blocked = slices.DeleteFunc(s.blocked, func(blocked *waiter) bool { return blocked == w })
+	s.ready = append(s.ready, w)
+}
+
+// spawn starts a task. Without a scheduler, it runs at once on a goroutine
+// of its own, and with one once the scheduler switches to it.
+func (s *Scheduler) spawn(t *task) {
+	if s == nil {
+		go func() {
+			result := t.run()
+			lock.Lock()
+			defer lock.Unlock()
+			t.end(result)
+		}()
+		return
+	}
+
+	w := newWaiter(s)
+	s.tasks++
+	s.ready = append(s.ready, w)
+
+	go func() {
+		<-w.wake
+
+		lock.Lock()
+		stopped := s.stopped
+		lock.Unlock()
+
+		var result object.Object = newError("task stopped")
+		if !stopped {
+			result = t.run()
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		t.end(result)
+
+		s.tasks--
+		if s.tasks == 0 && s.stopping != nil {
+			s.stopping.wake <- struct{}{}
+			return
+		}
+		s.next()
+	}()
+}
+
+// block waits until the waiter is resumed, letting the scheduler run other
+// tasks meanwhile. It is called, and returns, with lock held.
+func (s *Scheduler) block(w *waiter) {
+	if s != nil {
+		if s.stopped {
+			w.woken, w.err = true, newError("task stopped")
+			return
+		}
+		s.blocked = append(s.blocked, w)
+		s.next()
+	}
+
+	lock.Unlock()
+	<-w.wake
+	lock.Lock()
+}
+
+// next switches to the next ready task. If none is ready, the blocked
+// tasks are deadlocked, and resumed with an error.
+func (s *Scheduler) next() {
+	if len(s.ready) == 0 {
+		for _, w := range slices.Clone(s.blocked) {
+			w.resume(-1, NULL, false, newError("deadlock: all tasks are blocked"))
+		}
+	}
+	if len(s.ready) == 0 {
+		return
+	}
+
+	i := 0
+	if s.random != nil {
+		i = s.random.IntN(len(s.ready))
+	}
+	w := s.ready[i]
+	s.ready = slices.Delete(s.ready, i, i+1)
+	w.wake <- struct{}{}
+}
+
+// order returns the order in which a select tries its cases: at random
+// without a scheduler, so that none is always passed over, and otherwise
+// as the scheduler picks tasks.
+func (s *Scheduler) order(count int) []int {
+	switch {
+	case s == nil:
+		return rand.Perm(count)
+	case s.random != nil:
+		return s.random.Perm(count)
+	default:
+		order := make([]int, count)
+		for i := range order {
+			order[i] = i
+		}
+		return order
+	}
+}
@@ -0,0 +1,140 @@
+package evaluator
+
+import (
+	"slices"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+func init() {
+	builtins["wait"] = object.Builtin{Function: bf.wait, Arity: object.Arity{Min: 1, Max: 1}}
+}
+
+// task is a function call running concurrently with the code that spawned
+// it, until it ends with the value of the call.
+type task struct {
+	scheduler *Scheduler
+	run       func() object.Object
+	done      bool
+	result    object.Object
+	// waiters are the tasks waiting for this one to end.
+	waiters []*waiter
+}
+
+func (t *task) Type() object.Type {
+	return object.TaskType
+}
+
+func (t *task) String() string {
+	return "task"
+}
+
+// evalSpawn evaluates the function and the arguments of a call, or a
+// function it calls without arguments, and starts a task making the call.
+func evalSpawn(node ast.Spawn, env *object.Environment) object.Object {
+	var function object.Object
+	var args []object.Object
+
+	if call, ok := node.Value.(ast.Call); ok {
+		function = Eval(call.Function, env)
+		if function.Type() == object.ErrorType {
+			return function
+		}
+		args = evalArguments(call.Arguments, env)
+		if len(args) == 1 && args[0].Type() == object.ErrorType {
+			return args[0]
+		}
+	} else {
+		function = Eval(node.Value, env)
+		if function.Type() == object.ErrorType {
+			return function
+		}
+	}
+
+	switch Unwrap(function).(type) {
+	case *object.Function, object.Builtin, object.BoundMethod:
+	default:
+		return newError("cannot spawn %s: not a function", Unwrap(function).Type())
+	}
+
+	// A task has a stack of its own for hooks and tracers to follow.
+	caller := env
+	if e := executionOf(env); e != nil {
+		caller = object.NewEnclosedEnvironment(env)
+		caller.SetExecution(e.fork(caller))
+	}
+
+	t := &task{scheduler: schedulerOf(env)}
+	t.run = func() object.Object {
+		return Unwrap(evalFunction(function, args, caller))
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	t.scheduler.spawn(t)
+	return t
+}
+
+// end records the value of a task and resumes the tasks waiting for it.
+func (t *task) end(result object.Object) {
+	t.done, t.result = true, result
+	for _, w := range t.waiters {
+		if !w.woken {
+			w.resume(0, NULL, true, nil)
+		}
+	}
+	t.waiters = nil
+}
+
+// wait returns the value of a task once it has ended, or its error.
+func (t *task) wait() object.Object {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !t.done {
+		w := newWaiter(t.scheduler)
+		t.waiters = append(t.waiters, w)
+		t.scheduler.block(w)
+		if w.err != nil {
+			t.waiters = slices.DeleteFunc(t.waiters, func(waiting *waiter) bool { return waiting == w })
+			return w.err
+		}
+	}
+	return t.result
+}
+
+// wait waits for a task to end and returns its value, or for every task of
+// an array and returns theirs. A task that failed makes it fail with its
+// error.
+func (bf BuiltinFunctions) wait(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+
+	switch value := Unwrap(args[0]).(type) {
+	case *task:
+		return value.wait()
+	case *object.Array:
+		items := value.Snapshot()
+		tasks := make([]*task, len(items))
+		for i, item := range items {
+			t, ok := Unwrap(item).(*task)
+			if !ok {
+				return newError("cannot wait for %s: not a task", Unwrap(item).Type())
+			}
+			tasks[i] = t
+		}
+
+		results := make([]object.Object, len(tasks))
+		for i, t := range tasks {
+			results[i] = t.wait()
+			if results[i].Type() == object.ErrorType {
+				return results[i]
+			}
+		}
+		return &object.Array{Items: results}
+	default:
+		return newError("cannot wait for %s: not a task", value.Type())
+	}
+}
@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// Tracer follows everything an evaluation does, for hosts that need to
+// audit scripts. Like hooks, tracers are called on the evaluating goroutine,
+// and on those of the tasks it spawns.
+type Tracer interface {
+	// Enter is called before a node is evaluated, Exit after, with its value.
+	Enter(node ast.Node)
+	Exit(node ast.Node, result object.Object)
+	// Call is called before the body of a function is evaluated, and
+	// Return after, with the value the call evaluates to, or nil if the
+	// function ended with a call in tail position, which is made in place
+	// of its own and returns that value instead.
+	Call(function string, args []object.Object)
+	Return(function string, result object.Object)
+	// Builtin is called after a builtin function has been called.
+	Builtin(name string, args []object.Object, result object.Object)
+	// Error is called once for every error raised, with the innermost node
+	// that evaluated to it.
+	Error(err object.Error, node ast.Node)
+}
+
+// Trace makes the tracer follow every evaluation in env. A hook can be
+// attached to the same environment.
+func Trace(env *object.Environment, tracer Tracer) {
+	executionFor(env).tracer = tracer
+}
+
+func (e *execution) trace(node ast.Node, env *object.Environment) object.Object {
+	e.tracer.Enter(node)
+	result := eval(node, env)
+
+	if err, ok := result.(object.Error); !ok {
+		e.errorTraced = false
+	} else if !e.errorTraced {
+		e.errorTraced = true
+		e.tracer.Error(err, node)
+	}
+
+	e.tracer.Exit(node, result)
+	return result
+}
+
+func traceCall(env *object.Environment, function string, args []object.Object) {
+	if e := executionOf(env); e != nil && e.tracer != nil {
+		e.tracer.Call(function, args)
+	}
+}
+
+func traceReturn(env *object.Environment, function string, result object.Object) {
+	if e := executionOf(env); e != nil && e.tracer != nil {
+		if _, ok := result.(tailCall); ok {
+			result = nil
+		}
+		e.tracer.Return(function, result)
+	}
+}
+
+// callBuiltin calls a builtin function for the evaluation in env.
+func callBuiltin(env *object.Environment, name string, builtin object.Builtin, args []object.Object) object.Object {
+	result := builtin.Call(env, args...)
+
+	// Channels block with the scheduler of the evaluation creating them.
+	if ch, ok := result.(*channel); ok {
+		ch.scheduler = schedulerOf(env)
+	}
+
+	if e := executionOf(env); e != nil && e.tracer != nil {
+		e.tracer.Builtin(name, args, result)
+	}
+	return allocatedResult(env, result)
+}
@@ -2,18 +2,79 @@ package evaluator
 
 import (
 	"fmt"
+	"slices"
 
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
 	"github.com/timur-makarov/monkey-interpreter/internal/object"
 )
 
-func extendFunctionEnv(fn object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+// extendFunctionEnv encloses the function's environment for a call, carrying
+// over the execution state of the caller.
+func extendFunctionEnv(fn *object.Function, caller *object.Environment) *object.Environment {
+	scope := fn.Scope
+	if scope == nil {
+		scope = parameterScope(fn.Parameters, fn.Rest)
+	}
+	return object.NewCallEnvironment(fn.Env, caller, scope)
+}
+
+// bindParameters destructures the arguments of a call with the parameters
+// of the function, in the environment of the call, evaluating the defaults
+// of those left out there, and binds the rest parameter to an array of the
+// arguments left over. The number of arguments has been checked.
+func bindParameters(fn *object.Function, args []object.Object, env *object.Environment) object.Object {
+	for i, parameter := range fn.Parameters {
+		var m *mismatch
+		if i < len(args) {
+			m = bindPattern(parameter, false, args[i], env)
+		} else {
+			m = bindDefault(parameter.(ast.DefaultPattern), false, env)
+		}
+		if m != nil {
+			return m.error()
+		}
+	}
+
+	if fn.Rest != nil && fn.Rest.Value != "_" {
+		var rest []object.Object
+		if len(args) > len(fn.Parameters) {
+			rest = slices.Clone(args[len(fn.Parameters):])
+		}
+		bind(fn.Rest, false, allocated(env, &object.Array{Items: rest}), env)
+	}
+	return nil
+}
 
-	for i, param := range fn.Parameters {
-		env.Set(param.Value, args[i])
+// bind binds the name a let, const or struct statement declares, in its
+// slot if it has one, and reports whether it could: constants cannot be
+// bound again.
+func bind(name *ast.Identifier, constant bool, value object.Object, env *object.Environment) bool {
+	local := name.Local
+	switch {
+	case local != nil && constant:
+		return env.DefineConstantAt(local.Depth, local.Slot, value)
+	case local != nil:
+		return env.SetAt(local.Depth, local.Slot, value)
+	case constant:
+		return env.DefineConstant(name.Value, value)
+	default:
+		return env.Define(name.Value, value)
 	}
+}
 
-	return env
+// parameterScope gives every name the parameters bind a slot, for functions
+// not bound by resolver.Bind.
+func parameterScope(parameters []ast.Node, rest *ast.Identifier) *ast.Scope {
+	scope := &ast.Scope{}
+	for _, parameter := range parameters {
+		for _, name := range ast.Names(parameter) {
+			scope.Names = append(scope.Names, name.Value)
+		}
+	}
+	if rest != nil && rest.Value != "_" {
+		scope.Names = append(scope.Names, rest.Value)
+	}
+	return scope
 }
 
 func nativeBoolToObject(value bool) object.Object {
@@ -24,7 +85,8 @@ func nativeBoolToObject(value bool) object.Object {
 	}
 }
 
-func isTruthy(obj object.Object) bool {
+// IsTruthy reports whether a value counts as true in a condition.
+func IsTruthy(obj object.Object) bool {
 	switch o := obj.(type) {
 	case object.Integer:
 		return o.Value > 0
@@ -40,3 +102,28 @@ func isTruthy(obj object.Object) bool {
 func newError(format string, a ...any) object.Error {
 	return object.Error{Message: fmt.Sprintf(format, a...)}
 }
+
+// Unwrap returns the value behind the references identifiers and index
+// expressions evaluate to.
+func Unwrap(obj object.Object) object.Object {
+	switch o := obj.(type) {
+	case object.Identifier:
+		return Unwrap(o.Value)
+	case object.AccessByExpression:
+		return Unwrap(o.Value)
+	default:
+		return obj
+	}
+}
+
+// applyFunction calls a function value for a builtin that takes a callback,
+// from caller, the environment the builtin is called from, so that the call
+// is nested in it, observed and traced like any other.
+func applyFunction(fn object.Object, args []object.Object, caller *object.Environment) object.Object {
+	switch f := Unwrap(fn).(type) {
+	case *object.Function, object.Builtin, object.BoundMethod:
+		return evalFunction(fn, args, caller)
+	default:
+		return newError("not a function: %s", f.Type())
+	}
+}
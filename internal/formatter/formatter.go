@@ -0,0 +1,660 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+const (
+	Indentation  = "    "
+	MaxLineWidth = 80
+)
+
+// highest is the precedence of literals, functions, ifs and whiles,
+// which never need parentheses around them.
+const highest = math.MaxInt
+
+type SyntaxError struct {
+	Errors []parser.Error
+}
+
+func (e SyntaxError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Message
+	}
+	return "syntax errors: " + strings.Join(messages, "; ")
+}
+
+// Format parses the input and prints it back in canonical layout.
+// Comments are kept after the tokens they follow or before the statements
+// they precede, and a single blank line is kept wherever the input
+// separated statements with one or more blank lines. Formatting its own
+// output returns it unchanged.
+func Format(input string) (string, error) {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return "", SyntaxError{Errors: p.Errors()}
+	}
+
+	f := &formatter{lines: strings.Split(input, "\n"), comments: l.Comments()}
+	f.statements(program.Statements, math.MaxInt)
+
+	if f.err != nil {
+		return "", f.err
+	}
+
+	return f.out.String(), nil
+}
+
+type formatter struct {
+	out         bytes.Buffer
+	indent      int
+	atLineStart bool
+
+	lines    []string
+	comments []token.Token
+	next     int
+
+	// flat formatters never wrap lists; they are used to measure how
+	// wide an expression would be on a single line.
+	flat bool
+
+	err error
+}
+
+func (f *formatter) write(s string) {
+	if f.atLineStart && s != "" {
+		f.out.WriteString(strings.Repeat(Indentation, f.indent))
+		f.atLineStart = false
+	}
+	f.out.WriteString(s)
+}
+
+func (f *formatter) line() {
+	f.out.WriteByte('\n')
+	f.atLineStart = true
+}
+
+func (f *formatter) column() int {
+	if f.atLineStart {
+		return f.indent * len(Indentation)
+	}
+	b := f.out.Bytes()
+	return utf8.RuneCount(b[bytes.LastIndexByte(b, '\n')+1:])
+}
+
+func (f *formatter) fail(format string, a ...any) {
+	if f.err == nil {
+		f.err = fmt.Errorf(format, a...)
+	}
+}
+
+func (f *formatter) blankAbove(line int) bool {
+	return line >= 2 && line-2 < len(f.lines) && strings.TrimSpace(f.lines[line-2]) == ""
+}
+
+func (f *formatter) commentsBefore(line int, first *bool) {
+	for f.next < len(f.comments) && f.comments[f.next].Line < line {
+		comment := f.comments[f.next]
+		if !*first && f.blankAbove(comment.Line) {
+			f.line()
+		}
+		*first = false
+
+		f.write(comment.Literal)
+		f.line()
+		f.next++
+	}
+}
+
+// trailingComment takes the comment ending the first line of a statement,
+// unless it follows an opening brace or bracket or a comma, where the block
+// or literal holding it keeps it.
+func (f *formatter) trailingComment(line, nextLine int) string {
+	if nextLine == line || f.next >= len(f.comments) || f.comments[f.next].Line != line {
+		return ""
+	}
+	before := strings.TrimSpace(f.before(f.comments[f.next]))
+	if strings.HasSuffix(before, "{") || strings.HasSuffix(before, "[") || strings.HasSuffix(before, ",") {
+		return ""
+	}
+
+	comment := f.comments[f.next]
+	f.next++
+	return comment.Literal
+}
+
+// endsStatement reports whether a comment on the line is the last thing of
+// a statement that ends before the limit: whether the lines after it are
+// blank or hold nothing but comments.
+func (f *formatter) endsStatement(line, limit int) bool {
+	for l := line + 1; l < limit && l <= len(f.lines); l++ {
+		text := strings.TrimSpace(f.lines[l-1])
+		if text != "" && !strings.HasPrefix(text, "//") {
+			return false
+		}
+	}
+	return true
+}
+
+// trailingComments appends to the line written last the comments that
+// follow code on the lines of a statement after its first, such as one
+// after the closing brace of a block, so that they stay with the token
+// they follow.
+func (f *formatter) trailingComments(limit int) {
+	for f.next < len(f.comments) && f.comments[f.next].Line < limit && f.followsCode(f.comments[f.next]) {
+		f.write(" " + f.comments[f.next].Literal)
+		f.next++
+	}
+}
+
+func (f *formatter) followsCode(comment token.Token) bool {
+	return strings.TrimSpace(f.before(comment)) != ""
+}
+
+// before returns the text of the line of a comment up to it.
+func (f *formatter) before(comment token.Token) string {
+	if comment.Line < 1 || comment.Line > len(f.lines) {
+		return ""
+	}
+	text := []rune(f.lines[comment.Line-1])
+	return string(text[:min(max(comment.Column-1, 0), len(text))])
+}
+
+// insertTrailing appends the comment to the first line written since start,
+// so a comment that ended the opening line of a multi-line statement stays there.
+func (f *formatter) insertTrailing(start int, comment string) {
+	if comment == "" {
+		return
+	}
+
+	b := f.out.Bytes()
+	end := len(b)
+	if i := bytes.IndexByte(b[start:], '\n'); i >= 0 {
+		end = start + i
+	}
+
+	text := string(b[:end]) + " " + comment + string(b[end:])
+	f.out.Reset()
+	f.out.WriteString(text)
+}
+
+func (f *formatter) statements(statements []ast.Statement, end int) {
+	first := true
+
+	for i, statement := range statements {
+		line := ast.StartToken(statement).Line
+		f.commentsBefore(line, &first)
+
+		if !first && f.blankAbove(line) {
+			f.line()
+		}
+		first = false
+
+		nextLine := 0
+		if i+1 < len(statements) {
+			nextLine = ast.StartToken(statements[i+1]).Line
+		}
+		comment := f.trailingComment(line, nextLine)
+
+		start := f.out.Len()
+		f.statement(statement)
+
+		if i+1 < len(statements) && continuesExpression(statements[i+1]) {
+			f.write(";")
+		}
+
+		limit := end
+		if nextLine != 0 {
+			limit = nextLine
+		}
+		if comment != "" && f.endsStatement(line, limit) {
+			f.write(" " + comment)
+		} else {
+			f.insertTrailing(start, comment)
+		}
+		f.trailingComments(limit)
+		f.line()
+	}
+
+	f.commentsBefore(end, &first)
+}
+
+// continuesExpression reports whether a statement is printed starting with
+// a token that would be parsed as a call, an index, a struct literal or a
+// subtraction if the statement before it were not terminated by a
+// semicolon.
+func continuesExpression(statement ast.Statement) bool {
+	s, ok := statement.(ast.ExpressionStatement)
+	return ok && s.Expression != nil && opensWithOperator(s.Expression, parser.LOWEST)
+}
+
+func opensWithOperator(expression ast.Expression, required parser.Precedence) bool {
+	if precedenceOf(expression) < required {
+		return true
+	}
+
+	switch e := expression.(type) {
+	case ast.Prefix:
+		return e.Operator == token.MINUS
+	case ast.Array, ast.HashTable:
+		return true
+	case ast.Infix:
+		return opensWithOperator(e.Left, parser.OperatorPrecedence(e.Token.Type))
+	case ast.Call:
+		return opensWithOperator(e.Function, parser.CALL)
+	case ast.AccessByExpression:
+		return opensWithOperator(e.Left, parser.CALL)
+	case ast.FieldAccess:
+		return opensWithOperator(e.Left, parser.CALL)
+	default:
+		return false
+	}
+}
+
+func (f *formatter) statement(statement ast.Statement) {
+	switch s := statement.(type) {
+	case ast.LetStatement:
+		f.write(s.Token.Literal + " ")
+		if s.Name != nil {
+			f.write(s.Name.Value)
+		} else {
+			f.pattern(s.Pattern)
+		}
+		f.write(" = ")
+		f.expression(s.Value, parser.LOWEST)
+	case ast.StructStatement:
+		f.structStatement(s)
+	case ast.ReturnStatement:
+		f.write("return ")
+		f.expression(s.Value, parser.LOWEST)
+	case ast.YieldStatement:
+		f.write("yield ")
+		f.expression(s.Value, parser.LOWEST)
+	case ast.ExpressionStatement:
+		f.expression(s.Expression, parser.LOWEST)
+	default:
+		f.fail("cannot format statement: %T", statement)
+	}
+}
+
+// structStatement prints the fields of a struct declaration between spaced
+// braces on one line if they fit, and one field per line otherwise.
+func (f *formatter) structStatement(s ast.StructStatement) {
+	f.write("struct " + s.Name.Value + " ")
+
+	fields := make([]string, len(s.Fields))
+	for i, field := range s.Fields {
+		fields[i] = field.Value
+	}
+
+	switch line := "{ " + strings.Join(fields, ", ") + " }"; {
+	case len(fields) == 0:
+		f.write("{}")
+	case f.column()+utf8.RuneCountInString(line) <= MaxLineWidth:
+		f.write(line)
+	default:
+		f.list("{", "}", false, len(fields), func(i int) {
+			f.write(fields[i])
+		})
+	}
+}
+
+func (f *formatter) block(block ast.BlockStatement) {
+	f.write("{")
+
+	// A comment after the opening brace stays there, unless the first
+	// statement follows the brace on its line and the comment ends it.
+	opening := false
+	if f.next < len(f.comments) && f.comments[f.next].Line == block.Token.Line &&
+		(len(block.Statements) == 0 || ast.StartToken(block.Statements[0]).Line > block.Token.Line) {
+		f.write(" " + f.comments[f.next].Literal)
+		f.next++
+		opening = true
+	}
+
+	if len(block.Statements) == 0 && !opening && !f.hasCommentsBefore(block.Closing.Line) {
+		f.write("}")
+		return
+	}
+
+	f.indent++
+	f.line()
+	f.statements(block.Statements, block.Closing.Line)
+	f.indent--
+
+	f.write("}")
+}
+
+func (f *formatter) hasCommentsBefore(line int) bool {
+	return f.next < len(f.comments) && f.comments[f.next].Line < line
+}
+
+func precedenceOf(expression ast.Expression) parser.Precedence {
+	switch e := expression.(type) {
+	case ast.Infix:
+		return parser.OperatorPrecedence(e.Token.Type)
+	case ast.Prefix, ast.Spawn:
+		return parser.PREFIX
+	case ast.Call, ast.AccessByExpression, ast.FieldAccess, ast.StructLiteral:
+		return parser.CALL
+	default:
+		return highest
+	}
+}
+
+// expression prints an expression, wrapping it in parentheses when its own
+// precedence is lower than the one required by its position in the tree.
+func (f *formatter) expression(expression ast.Expression, required parser.Precedence) {
+	if expression == nil {
+		f.fail("cannot format incomplete expression")
+		return
+	}
+
+	if precedenceOf(expression) < required {
+		f.write("(")
+		defer f.write(")")
+	}
+
+	switch e := expression.(type) {
+	case ast.Identifier:
+		f.write(e.Value)
+	case ast.Integer:
+		f.write(strconv.Itoa(e.Value))
+	case ast.String:
+		f.write(e.String())
+	case ast.Boolean:
+		f.write(strconv.FormatBool(e.Value))
+	case ast.Prefix:
+		f.write(e.Operator)
+		f.expression(e.Right, parser.PREFIX)
+	case ast.Infix:
+		precedence := parser.OperatorPrecedence(e.Token.Type)
+		f.expression(e.Left, precedence)
+		f.write(" " + e.Operator + " ")
+		f.expression(e.Right, precedence+1)
+	case ast.Array:
+		starts := make([]int, len(e.Items))
+		for i, item := range e.Items {
+			starts[i] = ast.StartToken(item).Line
+		}
+		f.literal(e.Token, e.Closing, starts, f.fits(expression), func(i int) {
+			f.expression(e.Items[i], parser.LOWEST)
+		})
+	case ast.HashTable:
+		keys := hashTableKeys(e)
+		starts := make([]int, len(keys))
+		for i, key := range keys {
+			starts[i] = ast.StartToken(key).Line
+		}
+		f.literal(e.Token, e.Closing, starts, f.fits(expression), func(i int) {
+			f.expression(keys[i], parser.LOWEST)
+			f.write(": ")
+			f.expression(e.Items[keys[i]], parser.LOWEST)
+		})
+	case ast.Call:
+		fits := f.fits(expression)
+		f.expression(e.Function, parser.CALL)
+		f.list("(", ")", fits, len(e.Arguments), func(i int) {
+			f.expression(e.Arguments[i], parser.LOWEST)
+		})
+	case ast.AccessByExpression:
+		f.expression(e.Left, parser.CALL)
+		f.write("[")
+		f.expression(e.Index, parser.LOWEST)
+		f.write("]")
+	case ast.StructLiteral:
+		fits := f.fits(expression)
+		f.write(e.Type.Value)
+		f.list("{", "}", fits, len(e.Fields), func(i int) {
+			f.write(e.Fields[i].Value + ": ")
+			f.expression(e.Values[i], parser.LOWEST)
+		})
+	case ast.FieldAccess:
+		f.expression(e.Left, parser.CALL)
+		f.write("." + e.Field.Value)
+	case ast.Spread:
+		f.write("...")
+		f.expression(e.Value, parser.LOWEST)
+	case ast.Function:
+		count := len(e.Parameters)
+		if e.Rest != nil {
+			count++
+		}
+		f.write("fn")
+		if e.Generator {
+			f.write("*")
+		}
+		f.list("(", ")", true, count, func(i int) {
+			if i < len(e.Parameters) {
+				f.pattern(e.Parameters[i])
+			} else {
+				f.write("..." + e.Rest.Value)
+			}
+		})
+		f.write(" ")
+		f.block(e.Body)
+	case ast.If:
+		for i, condition := range e.Conditions {
+			if i > 0 {
+				f.write(" else ")
+			}
+			f.write("if (")
+			f.expression(condition, parser.LOWEST)
+			f.write(") ")
+			f.block(e.Consequences[i])
+		}
+		if e.Alternative.Token.Type != "" {
+			f.write(" else ")
+			f.block(e.Alternative)
+		}
+	case ast.While:
+		f.write("while (")
+		f.expression(e.Condition, parser.LOWEST)
+		f.write(") ")
+		f.block(e.Body)
+	case ast.For:
+		f.write("for (")
+		f.pattern(e.Pattern)
+		f.write(" in ")
+		f.expression(e.Iterable, parser.LOWEST)
+		f.write(") ")
+		f.block(e.Body)
+	case ast.Match:
+		f.write("match (")
+		f.expression(e.Subject, parser.LOWEST)
+		f.write(") ")
+		f.list("{", "}", false, len(e.Arms), func(i int) {
+			arm := e.Arms[i]
+			f.pattern(arm.Pattern)
+			if arm.Guard != nil {
+				f.write(" if ")
+				f.expression(arm.Guard, parser.LOWEST)
+			}
+			f.write(" => ")
+			f.expression(arm.Result, parser.LOWEST)
+		})
+	case ast.Spawn:
+		f.write("spawn ")
+		f.expression(e.Value, parser.PREFIX)
+	case ast.Select:
+		f.write("select ")
+		f.list("{", "}", false, len(e.Cases), func(i int) {
+			c := e.Cases[i]
+			switch {
+			case c.Operation == nil:
+				f.write("_")
+			case c.Pattern != nil:
+				f.pattern(c.Pattern)
+				f.write(" = ")
+				fallthrough
+			default:
+				f.expression(*c.Operation, parser.LOWEST)
+			}
+			f.write(" => ")
+			f.expression(c.Result, parser.LOWEST)
+		})
+	default:
+		f.fail("cannot format expression: %T", expression)
+	}
+}
+
+// pattern prints a pattern, with the names of hash table patterns that
+// bind a key to the same name alone, with their defaults.
+func (f *formatter) pattern(pattern ast.Node) {
+	switch p := pattern.(type) {
+	case ast.ArrayPattern:
+		count := len(p.Items)
+		if p.Rest != nil {
+			count++
+		}
+		f.list("[", "]", true, count, func(i int) {
+			if i < len(p.Items) {
+				f.pattern(p.Items[i])
+			} else {
+				f.write("..." + p.Rest.Value)
+			}
+		})
+	case ast.HashTablePattern:
+		f.list("{", "}", true, len(p.Keys), func(i int) {
+			value := p.Values[i]
+			if withDefault, ok := value.(ast.DefaultPattern); ok {
+				value = withDefault.Pattern
+			}
+			if name, ok := value.(ast.Identifier); !ok || name.Value != p.Keys[i].Value {
+				f.write(p.Keys[i].String() + ": ")
+			}
+			f.pattern(p.Values[i])
+		})
+	case ast.DefaultPattern:
+		f.pattern(p.Pattern)
+		f.write(" = ")
+		f.expression(p.Default, parser.LOWEST)
+	default:
+		f.expression(pattern, parser.LOWEST)
+	}
+}
+
+// list prints comma separated items between the delimiters, on one line if
+// the expression they belong to fits or one item per line otherwise.
+func (f *formatter) list(open, close string, fits bool, count int, item func(int)) {
+	f.write(open)
+
+	if count == 0 {
+		f.write(close)
+		return
+	}
+
+	if !fits {
+		f.indent++
+		for i := range count {
+			f.line()
+			item(i)
+			if i+1 < count {
+				f.write(",")
+			}
+		}
+		f.indent--
+		f.line()
+		f.write(close)
+		return
+	}
+
+	for i := range count {
+		if i > 0 {
+			f.write(", ")
+		}
+		item(i)
+	}
+	f.write(close)
+}
+
+// literal prints the items of an array or hash table literal starting on
+// the given lines like list, keeping the comments between its brackets in
+// place: a literal holding any is wrapped, with the comments on lines of
+// their own before the items they precede and the others after the items
+// they follow.
+func (f *formatter) literal(open, close token.Token, starts []int, fits bool, item func(int)) {
+	if !f.commentsWithin(open, close) {
+		f.list(open.Literal, close.Literal, fits, len(starts), item)
+		return
+	}
+
+	f.write(open.Literal)
+	if len(starts) == 0 {
+		f.trailingComments(close.Line)
+	} else {
+		f.trailingComments(starts[0])
+	}
+
+	first := true
+	f.indent++
+	for i := range starts {
+		f.line()
+		f.commentsBefore(starts[i], &first)
+		first = false
+
+		item(i)
+		limit := close.Line
+		if i+1 < len(starts) {
+			f.write(",")
+			limit = starts[i+1]
+		}
+		f.trailingComments(limit)
+	}
+	f.line()
+	f.commentsBefore(close.Line, &first)
+	f.indent--
+	f.write(close.Literal)
+}
+
+// commentsWithin reports whether the next comment is between the tokens.
+func (f *formatter) commentsWithin(open, close token.Token) bool {
+	if f.next >= len(f.comments) {
+		return false
+	}
+	comment := f.comments[f.next]
+	after := comment.Line > open.Line || comment.Line == open.Line && comment.Column > open.Column
+	return after && comment.Line < close.Line
+}
+
+// fits reports whether the first line of the expression printed without
+// wrapping ends within MaxLineWidth when it starts at the current column.
+func (f *formatter) fits(expression ast.Expression) bool {
+	if f.flat {
+		return true
+	}
+
+	measure := &formatter{indent: f.indent, lines: f.lines, flat: true}
+	measure.expression(expression, parser.LOWEST)
+
+	first, _, _ := strings.Cut(measure.out.String(), "\n")
+	return f.column()+utf8.RuneCountInString(first) <= MaxLineWidth
+}
+
+func hashTableKeys(hashTable ast.HashTable) []ast.Expression {
+	if len(hashTable.Keys) == len(hashTable.Items) {
+		return hashTable.Keys
+	}
+
+	keys := make([]ast.Expression, 0, len(hashTable.Items))
+	for key := range hashTable.Items {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys
+}
@@ -1,45 +1,73 @@
 package lexer
 
 import (
-	"iter"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/timur-makarov/monkey-interpreter/internal/token"
 )
 
-type inputIterator struct {
-	next func() (rune, bool)
-}
-
 type Lexer struct {
 	input        string
-	iterator     inputIterator
 	position     int
 	readPosition int
 	character    rune
+	line         int
+	column       int
+	comments     []token.Token
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
-	ii := createInputIterator(input)
-	l.iterator.next, _ = iter.Pull(ii)
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// Comments returns the line comments skipped so far, in source order.
+// They are not part of the token stream, so tools that need them
+// (like the formatter) read them after the program has been parsed.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 func (l *Lexer) readChar() {
-	l.character, _ = l.iterator.next()
+	if l.character == '\n' {
+		l.line++
+		l.column = 0
+	}
+
+	// Positions advance by the bytes actually decoded, so that they stay
+	// on rune boundaries even in invalid UTF-8. The end of the input reads
+	// as 0 and leaves both positions at its length.
+	character, width := l.decodeAt(l.readPosition)
+	l.character = character
 	l.position = l.readPosition
-	l.readPosition += utf8.RuneLen(l.character)
+	l.readPosition += width
+	l.column++
 }
 
 func (l *Lexer) peekChar() rune {
-	if l.readPosition > len(l.input) {
-		return 0
-	} else {
-		return []rune(l.input[l.readPosition : l.readPosition+1])[0]
+	character, _ := l.decodeAt(l.readPosition)
+	return character
+}
+
+// peekCharAt returns the character n characters after the next one.
+func (l *Lexer) peekCharAt(n int) rune {
+	position := l.readPosition
+	for range n {
+		_, width := l.decodeAt(position)
+		position += width
+	}
+	character, _ := l.decodeAt(position)
+	return character
+}
+
+func (l *Lexer) decodeAt(position int) (rune, int) {
+	if position >= len(l.input) {
+		return 0, 0
 	}
+	return utf8.DecodeRuneInString(l.input[position:])
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -76,10 +104,30 @@ func (l *Lexer) readString() string {
 	return l.input[pos:l.position]
 }
 
-func (l *Lexer) skipWhitespace() {
-	for unicode.IsSpace(l.character) {
+func (l *Lexer) readComment() token.Token {
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	pos := l.position
+
+	for l.character != '\n' && l.character != 0 {
 		l.readChar()
 	}
+
+	tok.Literal = strings.TrimRightFunc(l.input[pos:l.position], unicode.IsSpace)
+	return tok
+}
+
+func (l *Lexer) skipWhitespace() {
+	for {
+		for unicode.IsSpace(l.character) {
+			l.readChar()
+		}
+
+		if l.character != '/' || l.peekChar() != '/' {
+			return
+		}
+
+		l.comments = append(l.comments, l.readComment())
+	}
 }
 
 func (l *Lexer) determineTokenType(fType, sType token.Type, lookFor rune) token.Token {
@@ -93,13 +141,26 @@ func (l *Lexer) determineTokenType(fType, sType token.Type, lookFor rune) token.
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
 
+	line, column := l.line, l.column
+	tok := l.readToken()
+	tok.Line, tok.Column = line, column
+
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	switch l.character {
 	case '=':
-		tok = l.determineTokenType(token.ASSIGN, token.EQ, '=')
+		if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "=>"}
+		} else {
+			tok = l.determineTokenType(token.ASSIGN, token.EQ, '=')
+		}
 	case '+':
 		tok = newToken(token.PLUS, l.character)
 	case '-':
@@ -122,6 +183,14 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.character)
 	case ',':
 		tok = newToken(token.COMMA, l.character)
+	case '.':
+		if l.peekChar() == '.' && l.peekCharAt(1) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.character)
+		}
 	case ':':
 		tok = newToken(token.COLON, l.character)
 	case ';':
@@ -162,13 +231,3 @@ func (l *Lexer) NextToken() token.Token {
 func newToken(tokenType token.Type, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
-
-func createInputIterator(input string) iter.Seq[rune] {
-	return func(yield func(rune) bool) {
-		for _, ch := range input {
-			if !yield(ch) {
-				return
-			}
-		}
-	}
-}
@@ -0,0 +1,293 @@
+package linter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+const (
+	UndefinedIdentifier  = "undefined-identifier"
+	UndeclaredAssignment = "undeclared-assignment"
+	UnusedVariable       = "unused-variable"
+	UnusedParameter      = "unused-parameter"
+	ShadowedBuiltin      = "shadowed-builtin"
+	UnreachableCode      = "unreachable-code"
+	WrongArity           = "wrong-arity"
+	ConstantCondition    = "constant-condition"
+)
+
+type Diagnostic struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Check    string   `json:"check"`
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s (%s)", d.Line, d.Column, d.Severity, d.Message, d.Check)
+}
+
+// Lint reports likely mistakes in a parsed program, ordered by position.
+// Names are bound as described by resolver.Resolve. Bindings and parameters
+// whose names start with an underscore are never reported as unused.
+func Lint(program *ast.Program) []Diagnostic {
+	l := &linter{resolution: resolver.Resolve(program)}
+
+	l.checkDeclarations()
+	l.checkReferences()
+	l.statements(program.Statements)
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i], l.diagnostics[j]
+		return resolver.Before(a.Line, a.Column, b.Line, b.Column)
+	})
+
+	return l.diagnostics
+}
+
+type linter struct {
+	diagnostics []Diagnostic
+	resolution  *resolver.Resolution
+}
+
+func (l *linter) report(tok token.Token, severity Severity, check, format string, a ...any) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Line:     tok.Line,
+		Column:   tok.Column,
+		Severity: severity,
+		Check:    check,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (l *linter) checkDeclarations() {
+	for _, d := range l.resolution.Declarations {
+		name := d.Name.Literal
+
+		if _, ok := evaluator.LookupBuiltin(name); ok {
+			l.report(d.Name, Warning, ShadowedBuiltin, "%s %q shadows a builtin function", d.Kind, name)
+		}
+
+		if d.Used() || strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		check := UnusedVariable
+		if d.Kind == resolver.Parameter {
+			check = UnusedParameter
+		}
+		l.report(d.Name, Warning, check, "%s %q is never used", d.Kind, name)
+	}
+}
+
+func (l *linter) checkReferences() {
+	for _, r := range l.resolution.References {
+		if r.Declaration != nil {
+			continue
+		}
+
+		name := r.Token.Literal
+		if r.Assignment {
+			l.report(r.Token, Error, UndeclaredAssignment, "assignment to undeclared name %q", name)
+		} else if _, ok := evaluator.LookupBuiltin(name); !ok {
+			l.report(r.Token, Error, UndefinedIdentifier, "undefined identifier %q", name)
+		}
+	}
+}
+
+func (l *linter) statements(statements []ast.Statement) {
+	for i, statement := range statements {
+		if i > 0 {
+			if _, ok := statements[i-1].(ast.ReturnStatement); ok {
+				l.report(ast.StartToken(statement), Warning, UnreachableCode, "unreachable code after return")
+			}
+		}
+
+		switch st := statement.(type) {
+		case ast.LetStatement:
+			l.expression(st.Value)
+			l.defaults(st.Pattern)
+		case ast.ReturnStatement:
+			l.expression(st.Value)
+		case ast.YieldStatement:
+			l.expression(st.Value)
+		case ast.ExpressionStatement:
+			l.expression(st.Expression)
+		}
+	}
+}
+
+func (l *linter) expression(expression ast.Expression) {
+	switch e := expression.(type) {
+	case ast.Prefix:
+		l.expression(e.Right)
+	case ast.Infix:
+		l.expression(e.Left)
+		l.expression(e.Right)
+	case ast.Array:
+		for _, item := range e.Items {
+			l.expression(item)
+		}
+	case ast.HashTable:
+		for _, key := range e.Keys {
+			l.expression(key)
+			l.expression(e.Items[key])
+		}
+	case ast.AccessByExpression:
+		l.expression(e.Left)
+		l.expression(e.Index)
+	case ast.StructLiteral:
+		for _, value := range e.Values {
+			l.expression(value)
+		}
+	case ast.FieldAccess:
+		l.expression(e.Left)
+	case ast.Call:
+		l.expression(e.Function)
+		for _, argument := range e.Arguments {
+			l.expression(argument)
+		}
+		l.checkCall(e)
+	case ast.Spread:
+		l.expression(e.Value)
+	case ast.If:
+		for i, condition := range e.Conditions {
+			l.expression(condition)
+			l.statements(e.Consequences[i].Statements)
+		}
+		l.statements(e.Alternative.Statements)
+	case ast.While:
+		l.expression(e.Condition)
+		// Generators loop forever on purpose, yielding as they go.
+		if isConstant(e.Condition) && !yields(e.Body.Statements) {
+			l.report(e.Token, Warning, ConstantCondition, "while condition is constant: %s", e.Condition)
+		}
+		l.statements(e.Body.Statements)
+	case ast.For:
+		l.expression(e.Iterable)
+		l.defaults(e.Pattern)
+		l.statements(e.Body.Statements)
+	case ast.Match:
+		l.expression(e.Subject)
+		for _, arm := range e.Arms {
+			l.defaults(arm.Pattern)
+			l.expression(arm.Guard)
+			l.expression(arm.Result)
+		}
+	case ast.Spawn:
+		l.expression(e.Value)
+	case ast.Select:
+		for _, c := range e.Cases {
+			if c.Operation != nil {
+				l.expression(*c.Operation)
+			}
+			if c.Pattern != nil {
+				l.defaults(c.Pattern)
+			}
+			l.expression(c.Result)
+		}
+	case ast.Function:
+		for _, parameter := range e.Parameters {
+			l.defaults(parameter)
+		}
+		l.statements(e.Body.Statements)
+	}
+}
+
+func (l *linter) defaults(pattern ast.Node) {
+	for _, value := range ast.Defaults(pattern) {
+		l.expression(value)
+	}
+}
+
+// checkCall compares the number of arguments with the parameters of builtins
+// and of bindings that are declared with a function and never reassigned.
+// Calls spreading arguments pass as many as the arrays hold, which is not
+// known.
+func (l *linter) checkCall(node ast.Call) {
+	name, ok := node.Function.(ast.Identifier)
+	if !ok {
+		return
+	}
+
+	for _, argument := range node.Arguments {
+		if _, ok := argument.(ast.Spread); ok {
+			return
+		}
+	}
+
+	got := len(node.Arguments)
+	reference := l.resolution.ReferenceAt(name.Token)
+
+	if reference != nil && reference.Declaration != nil {
+		fn, ok := reference.Declaration.Function()
+		if arity := object.ParameterArity(fn.Parameters, fn.Rest); ok && !arity.Accepts(got) {
+			l.report(name.Token, Error, WrongArity, "%s expects %s arguments, got %d", name.Value, arity, got)
+		}
+		return
+	}
+
+	builtin, ok := evaluator.LookupBuiltin(name.Value)
+	if ok && !builtin.Arity.Accepts(got) {
+		l.report(
+			name.Token, Error, WrongArity, "builtin %s expects %s arguments, got %d",
+			name.Value, builtin.Arity, got,
+		)
+	}
+}
+
+// yields reports whether statements of a generator may yield, themselves or
+// in the blocks of the if, while and for expressions among them.
+func yields(statements []ast.Statement) bool {
+	for _, statement := range statements {
+		switch st := statement.(type) {
+		case ast.YieldStatement:
+			return true
+		case ast.ExpressionStatement:
+			var blocks []ast.BlockStatement
+			switch e := st.Expression.(type) {
+			case ast.If:
+				blocks = append([]ast.BlockStatement{e.Alternative}, e.Consequences...)
+			case ast.While:
+				blocks = []ast.BlockStatement{e.Body}
+			case ast.For:
+				blocks = []ast.BlockStatement{e.Body}
+			}
+			for _, block := range blocks {
+				if yields(block.Statements) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isConstant reports whether an expression only combines literals.
+func isConstant(expression ast.Expression) bool {
+	switch e := expression.(type) {
+	case ast.Integer, ast.String, ast.Boolean:
+		return true
+	case ast.Prefix:
+		return isConstant(e.Right)
+	case ast.Infix:
+		return e.Operator != token.ASSIGN && isConstant(e.Left) && isConstant(e.Right)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,73 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// Client is a minimal synchronous JSON-RPC client for talking to a language
+// server, used to drive the server in-process from tests and tools.
+// Notifications are kept until they are asked for.
+type Client struct {
+	writer io.Writer
+	lastID int
+	inbox  *Inbox[Message]
+}
+
+func NewClient(in io.Reader, out io.Writer) *Client {
+	return &Client{writer: out, inbox: NewInbox[Message](in)}
+}
+
+// Call sends a request and decodes its result into result, unless result is nil.
+// A JSON-RPC error response is returned as a *ResponseError.
+func (c *Client) Call(method string, params, result any) error {
+	c.lastID++
+	id := json.RawMessage(strconv.Itoa(c.lastID))
+
+	content, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteMessage(c.writer, Message{ID: id, Method: method, Params: content}); err != nil {
+		return err
+	}
+
+	response, err := c.inbox.Next(func(message Message) bool {
+		return message.Method == "" && string(message.ID) == string(id)
+	})
+	if err != nil {
+		return err
+	}
+
+	if response.Error != nil {
+		return response.Error
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// Notify sends a notification.
+func (c *Client) Notify(method string, params any) error {
+	content, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return WriteMessage(c.writer, Message{Method: method, Params: content})
+}
+
+// Notification decodes the oldest notification of the given method into
+// params, waiting for the server to send one if none has been received yet.
+func (c *Client) Notification(method string, params any) error {
+	notification, err := c.inbox.Next(func(message Message) bool {
+		return message.Method == method && len(message.ID) == 0
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(notification.Params, params)
+}
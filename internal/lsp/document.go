@@ -0,0 +1,381 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/linter"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+const unknownKind = "unknown"
+
+// builtinKinds are the kinds of values returned by builtin functions.
+var builtinKinds = map[string]string{
+	"len":    "integer",
+	"shift":  "array",
+	"append": "array",
+	"log":    "null",
+
+	"iter":    "iterator",
+	"collect": "array",
+	"channel": "channel",
+
+	"assert":       "null",
+	"assertEqual":  "null",
+	"assertThrows": "null",
+}
+
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   []string
+
+	program      *ast.Program
+	syntaxErrors []parser.Error
+	resolution   *resolver.Resolution
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version, text: text, lines: strings.Split(text, "\n")}
+	d.analyze()
+	return d
+}
+
+func (d *document) analyze() {
+	defer func() {
+		if r := recover(); r != nil {
+			d.program = &ast.Program{}
+			d.syntaxErrors = []parser.Error{{Message: fmt.Sprintf("cannot parse document: %v", r), Line: 1, Column: 1}}
+			d.resolution = resolver.Resolve(d.program)
+		}
+	}()
+
+	p := parser.New(lexer.New(d.text))
+	d.program = p.ParseProgram()
+	d.syntaxErrors = p.Errors()
+	d.resolution = resolver.Resolve(d.program)
+}
+
+// position converts a 1-based line and rune column into an LSP position.
+func (d *document) position(line, column int) Position {
+	var text string
+	if line >= 1 && line <= len(d.lines) {
+		text = d.lines[line-1]
+	}
+
+	character := 0
+	for _, r := range text {
+		if column <= 1 {
+			break
+		}
+		character += utf16.RuneLen(r)
+		column--
+	}
+
+	return Position{Line: max(line-1, 0), Character: character + max(column-1, 0)}
+}
+
+// location converts an LSP position into a 1-based line and rune column.
+func (d *document) location(position Position) (int, int) {
+	var text string
+	if position.Line >= 0 && position.Line < len(d.lines) {
+		text = d.lines[position.Line]
+	}
+
+	column, units := 1, 0
+	for _, r := range text {
+		if units >= position.Character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		column++
+	}
+
+	return position.Line + 1, column
+}
+
+func (d *document) tokenRange(tok token.Token) Range {
+	return Range{
+		Start: d.position(tok.Line, tok.Column),
+		End:   d.position(tok.Line, tok.Column+utf8.RuneCountInString(tok.Literal)),
+	}
+}
+
+// wordRange covers the identifier or number starting at the position,
+// or a single character if there is none.
+func (d *document) wordRange(line, column int) Range {
+	length := 0
+	if line >= 1 && line <= len(d.lines) {
+		runes := []rune(d.lines[line-1])
+		for i := column - 1; i >= 0 && i < len(runes); i++ {
+			if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) && runes[i] != '_' {
+				break
+			}
+			length++
+		}
+	}
+
+	return Range{Start: d.position(line, column), End: d.position(line, column+max(length, 1))}
+}
+
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	if len(d.syntaxErrors) > 0 {
+		for _, err := range d.syntaxErrors {
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    d.wordRange(err.Line, err.Column),
+				Severity: SeverityError,
+				Code:     "syntax",
+				Source:   "monkey",
+				Message:  err.Message,
+			})
+		}
+		return diagnostics
+	}
+
+	for _, lint := range linter.Lint(d.program) {
+		severity := SeverityWarning
+		if lint.Severity == linter.Error {
+			severity = SeverityError
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.wordRange(lint.Line, lint.Column),
+			Severity: severity,
+			Code:     lint.Check,
+			Source:   "monkey",
+			Message:  lint.Message,
+		})
+	}
+
+	return diagnostics
+}
+
+// occurrence is an identifier in the document together with the
+// declaration it names, which is nil for builtins and undefined names.
+type occurrence struct {
+	token       token.Token
+	declaration *resolver.Declaration
+}
+
+func (d *document) occurrenceAt(position Position) (occurrence, bool) {
+	line, column := d.location(position)
+
+	covers := func(tok token.Token) bool {
+		return tok.Line == line && column >= tok.Column &&
+			column <= tok.Column+utf8.RuneCountInString(tok.Literal)
+	}
+
+	for _, declaration := range d.resolution.Declarations {
+		if covers(declaration.Name) {
+			return occurrence{token: declaration.Name, declaration: declaration}, true
+		}
+	}
+
+	for _, reference := range d.resolution.References {
+		if covers(reference.Token) {
+			return occurrence{token: reference.Token, declaration: reference.Declaration}, true
+		}
+	}
+
+	return occurrence{}, false
+}
+
+func (d *document) describe(o occurrence) (string, bool) {
+	declaration := o.declaration
+
+	if declaration == nil {
+		if _, ok := evaluator.LookupBuiltin(o.token.Literal); ok {
+			return "(builtin) " + o.token.Literal, true
+		}
+		return "", false
+	}
+
+	name := declaration.Name.Literal
+	if declaration.Kind == resolver.Parameter {
+		return "(parameter) " + name, true
+	}
+
+	if fn, ok := declaration.Value.(ast.Function); ok {
+		return "(function) " + name + parameterList(fn), true
+	}
+
+	if st, ok := declaration.Value.(ast.StructStatement); ok {
+		return "(struct) " + name + " " + fieldList(st), true
+	}
+
+	return fmt.Sprintf("(%s) %s: %s", declaration.Kind, name, d.kind(declaration.Value, 0)), true
+}
+
+func parameterList(fn ast.Function) string {
+	names := make([]string, len(fn.Parameters))
+	for i, parameter := range fn.Parameters {
+		names[i] = parameter.String()
+	}
+	if fn.Rest != nil {
+		names = append(names, "..."+fn.Rest.Value)
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+func fieldList(st ast.StructStatement) string {
+	names := make([]string, len(st.Fields))
+	for i, field := range st.Fields {
+		names[i] = field.Value
+	}
+	return "{ " + strings.Join(names, ", ") + " }"
+}
+
+// kind infers what kind of value an expression evaluates to, as far as
+// this can be told without running it.
+func (d *document) kind(expression ast.Expression, depth int) string {
+	if depth > 16 {
+		return unknownKind
+	}
+
+	switch e := expression.(type) {
+	case ast.Integer:
+		return "integer"
+	case ast.String:
+		return "string"
+	case ast.Boolean:
+		return "boolean"
+	case ast.Array:
+		return "array"
+	case ast.HashTable:
+		return "hash table"
+	case ast.Function:
+		return "function"
+	case ast.StructStatement:
+		return "struct"
+	case ast.StructLiteral:
+		return e.Type.Value
+	case ast.Spawn:
+		return "task"
+	case ast.Prefix:
+		if e.Operator == token.BANG {
+			return "boolean"
+		}
+		return "integer"
+	case ast.Infix:
+		switch e.Operator {
+		case token.EQ, token.NEQ, token.LT, token.GT:
+			return "boolean"
+		case token.ASSIGN:
+			return d.kind(e.Right, depth+1)
+		case token.PLUS:
+			left, right := d.kind(e.Left, depth+1), d.kind(e.Right, depth+1)
+			if left == "string" || right == "string" {
+				return "string"
+			}
+			if left == "integer" && right == "integer" {
+				return "integer"
+			}
+			return unknownKind
+		default:
+			return "integer"
+		}
+	case ast.Identifier:
+		reference := d.resolution.ReferenceAt(e.Token)
+		if reference == nil || reference.Declaration == nil {
+			if _, ok := evaluator.LookupBuiltin(e.Value); ok {
+				return "builtin"
+			}
+			return unknownKind
+		}
+		if reference.Declaration.Kind == resolver.Parameter {
+			return unknownKind
+		}
+		return d.kind(reference.Declaration.Value, depth+1)
+	case ast.Call:
+		if name, ok := e.Function.(ast.Identifier); ok {
+			reference := d.resolution.ReferenceAt(name.Token)
+			if kind, ok := builtinKinds[name.Value]; ok && (reference == nil || reference.Declaration == nil) {
+				return kind
+			}
+			if reference != nil && reference.Declaration != nil {
+				if fn, ok := reference.Declaration.Function(); ok && fn.Generator {
+					return "iterator"
+				}
+			}
+		}
+		return unknownKind
+	default:
+		return unknownKind
+	}
+}
+
+func (d *document) symbols(statements []ast.Statement) []DocumentSymbol {
+	var symbols []DocumentSymbol
+
+	for _, statement := range statements {
+		if st, ok := statement.(ast.StructStatement); ok && st.Name != nil {
+			symbol := DocumentSymbol{
+				Name:           st.Name.Value,
+				Detail:         fieldList(st),
+				Kind:           SymbolStruct,
+				SelectionRange: d.tokenRange(st.Name.Token),
+			}
+			symbol.Range = Range{Start: d.position(st.Token.Line, st.Token.Column), End: d.tokenRange(st.Closing).End}
+			symbols = append(symbols, symbol)
+			continue
+		}
+
+		let, ok := statement.(ast.LetStatement)
+		if !ok {
+			continue
+		}
+
+		if let.Name == nil {
+			for _, name := range ast.Names(let.Pattern) {
+				symbol := DocumentSymbol{
+					Name:           name.Value,
+					Kind:           SymbolVariable,
+					SelectionRange: d.tokenRange(name.Token),
+				}
+				symbol.Range = symbol.SelectionRange
+				symbols = append(symbols, symbol)
+			}
+			continue
+		}
+
+		symbol := DocumentSymbol{
+			Name:           let.Name.Value,
+			Kind:           SymbolVariable,
+			SelectionRange: d.tokenRange(let.Name.Token),
+		}
+		symbol.Range = Range{Start: d.position(let.Token.Line, let.Token.Column), End: symbol.SelectionRange.End}
+
+		if fn, ok := let.Value.(ast.Function); ok {
+			symbol.Kind = SymbolFunction
+			symbol.Detail = "fn" + parameterList(fn)
+			symbol.Range.End = d.tokenRange(fn.Body.Closing).End
+			symbol.Children = d.symbols(fn.Body.Statements)
+		} else {
+			symbol.Detail = d.kind(let.Value, 0)
+		}
+
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
+
+func (d *document) fullRange() Range {
+	last := len(d.lines)
+	return Range{
+		Start: Position{},
+		End:   d.position(last, utf8.RuneCountInString(d.lines[last-1])+1),
+	}
+}
@@ -0,0 +1,73 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Inbox reads the messages a server sends to a client continuously in the
+// background, so the server is never blocked writing one nobody has asked
+// for yet, and keeps them until they are asked for. It serves the clients
+// of both the language server and the debug adapter, which frame their
+// messages the same way.
+type Inbox[M any] struct {
+	mu       sync.Mutex
+	arrived  *sync.Cond
+	received []M
+	readErr  error
+}
+
+func NewInbox[M any](in io.Reader) *Inbox[M] {
+	i := &Inbox[M]{}
+	i.arrived = sync.NewCond(&i.mu)
+	go i.readLoop(bufio.NewReader(in))
+	return i
+}
+
+func (i *Inbox[M]) readLoop(reader *bufio.Reader) {
+	for {
+		var message M
+
+		content, err := ReadMessage(reader)
+		if err == nil {
+			err = json.Unmarshal(content, &message)
+		}
+
+		i.mu.Lock()
+		if err != nil {
+			i.readErr = err
+		} else {
+			i.received = append(i.received, message)
+		}
+		i.arrived.Broadcast()
+		i.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Next removes and returns the first received message accepted by match,
+// waiting for the server to send one if necessary.
+func (i *Inbox[M]) Next(match func(M) bool) (M, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for {
+		for j, message := range i.received {
+			if match(message) {
+				i.received = append(i.received[:j], i.received[j+1:]...)
+				return message, nil
+			}
+		}
+
+		if i.readErr != nil {
+			var zero M
+			return zero, i.readErr
+		}
+		i.arrived.Wait()
+	}
+}
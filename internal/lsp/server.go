@@ -0,0 +1,395 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"unicode"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/formatter"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+// Server is a language server for Monkey. Documents are kept in memory
+// and re-analyzed as a whole on every change.
+type Server struct {
+	documents map[string]*document
+	out       io.Writer
+	shutdown  bool
+}
+
+type handler func(s *Server, params json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":                  (*Server).initialize,
+	"initialized":                 (*Server).ignore,
+	"shutdown":                    (*Server).shutdownRequest,
+	"textDocument/didOpen":        (*Server).didOpen,
+	"textDocument/didChange":      (*Server).didChange,
+	"textDocument/didClose":       (*Server).didClose,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/completion":     (*Server).completion,
+	"textDocument/rename":         (*Server).rename,
+	"textDocument/formatting":     (*Server).formatting,
+}
+
+func NewServer() *Server {
+	return &Server{documents: make(map[string]*document)}
+}
+
+// Serve handles messages read from in and writes responses and notifications
+// to out until it receives the exit notification or in is exhausted.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	reader := bufio.NewReader(in)
+
+	for {
+		content, err := ReadMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var message Message
+		if err := json.Unmarshal(content, &message); err != nil {
+			if err := s.reply(json.RawMessage("null"), nil, &ResponseError{Code: parseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if message.Method == "exit" {
+			return nil
+		}
+
+		if err := s.handle(message); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(message Message) error {
+	isRequest := len(message.ID) > 0
+
+	h, ok := handlers[message.Method]
+	switch {
+	case !ok && isRequest:
+		return s.reply(message.ID, nil, &ResponseError{Code: methodNotFound, Message: "method not found: " + message.Method})
+	case !ok:
+		return nil
+	case s.shutdown && isRequest:
+		return s.reply(message.ID, nil, &ResponseError{Code: invalidRequest, Message: "server is shutting down"})
+	}
+
+	result, err := s.call(h, message.Params)
+	if !isRequest {
+		return nil
+	}
+
+	if err != nil {
+		responseError, ok := err.(*ResponseError)
+		if !ok {
+			responseError = &ResponseError{Code: internalError, Message: err.Error()}
+		}
+		return s.reply(message.ID, nil, responseError)
+	}
+
+	return s.reply(message.ID, result, nil)
+}
+
+// call runs a handler, turning a panic into an internal error so that one
+// bad request does not take the editor's language support down.
+func (s *Server) call(h handler, params json.RawMessage) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &ResponseError{Code: internalError, Message: fmt.Sprint(r)}
+		}
+	}()
+
+	return h(s, params)
+}
+
+func (s *Server) reply(id json.RawMessage, result any, responseError *ResponseError) error {
+	message := Message{ID: id, Error: responseError}
+
+	if responseError == nil {
+		content, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		message.Result = content
+	}
+
+	return WriteMessage(s.out, message)
+}
+
+func (s *Server) notify(method string, params any) error {
+	content, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return WriteMessage(s.out, Message{Method: method, Params: content})
+}
+
+func decode(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &ResponseError{Code: invalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	d, ok := s.documents[uri]
+	if !ok {
+		return nil, &ResponseError{Code: requestFailed, Message: "unknown document: " + uri}
+	}
+	return d, nil
+}
+
+func (s *Server) positionParams(params json.RawMessage) (*document, Position, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, Position{}, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	return d, p.Position, err
+}
+
+func (s *Server) publishDiagnostics(d *document) (any, error) {
+	return nil, s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: d.diagnostics(),
+	})
+}
+
+func (s *Server) initialize(json.RawMessage) (any, error) {
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           textDocumentSyncFull,
+			DefinitionProvider:         true,
+			HoverProvider:              true,
+			DocumentSymbolProvider:     true,
+			CompletionProvider:         map[string]any{},
+			RenameProvider:             true,
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "monkey-lsp"},
+	}, nil
+}
+
+func (s *Server) ignore(json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (any, error) {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d := newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+	s.documents[d.uri] = d
+	return s.publishDiagnostics(d)
+}
+
+func (s *Server) didChange(params json.RawMessage) (any, error) {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+	d := newDocument(p.TextDocument.URI, p.TextDocument.Version, text)
+	s.documents[d.uri] = d
+	return s.publishDiagnostics(d)
+}
+
+func (s *Server) didClose(params json.RawMessage) (any, error) {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	delete(s.documents, p.TextDocument.URI)
+	return nil, s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	d, position, err := s.positionParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	o, ok := d.occurrenceAt(position)
+	if !ok || o.declaration == nil {
+		return nil, nil
+	}
+
+	return Location{URI: d.uri, Range: d.tokenRange(o.declaration.Name)}, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	d, position, err := s.positionParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	o, ok := d.occurrenceAt(position)
+	if !ok {
+		return nil, nil
+	}
+
+	description, ok := d.describe(o)
+	if !ok {
+		return nil, nil
+	}
+
+	r := d.tokenRange(o.token)
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```monkey\n" + description + "\n```"},
+		Range:    &r,
+	}, nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (any, error) {
+	var p DocumentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := d.symbols(d.program.Statements)
+	if symbols == nil {
+		symbols = []DocumentSymbol{}
+	}
+	return symbols, nil
+}
+
+// completion offers the names visible at the position and the builtins
+// they do not shadow. Variables are only offered after their declaration.
+func (s *Server) completion(params json.RawMessage) (any, error) {
+	d, position, err := s.positionParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	line, column := d.location(position)
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+
+	for scope := d.resolution.ScopeAt(line, column); scope != nil; scope = scope.Outer {
+		for name, declaration := range scope.Declarations {
+			declared := declaration.Name
+			if seen[name] || declaration.Kind != resolver.Parameter &&
+				!resolver.Before(declared.Line, declared.Column, line, column) {
+				continue
+			}
+			seen[name] = true
+
+			item := CompletionItem{Label: name, Kind: CompletionVariable}
+			if description, ok := d.describe(occurrence{token: declared, declaration: declaration}); ok {
+				item.Detail = description
+			}
+			if _, ok := declaration.Function(); ok {
+				item.Kind = CompletionFunction
+			}
+			items = append(items, item)
+		}
+	}
+
+	for _, name := range evaluator.BuiltinNames() {
+		if !seen[name] {
+			items = append(items, CompletionItem{Label: name, Kind: CompletionFunction, Detail: "(builtin) " + name})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items, nil
+}
+
+func (s *Server) rename(params json.RawMessage) (any, error) {
+	var p RenameParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isIdentifier(p.NewName) {
+		return nil, &ResponseError{Code: invalidParams, Message: fmt.Sprintf("%q is not a valid identifier", p.NewName)}
+	}
+
+	o, ok := d.occurrenceAt(p.Position)
+	if !ok || o.declaration == nil {
+		return nil, &ResponseError{Code: requestFailed, Message: "no declared name at this position"}
+	}
+
+	edits := []TextEdit{{Range: d.tokenRange(o.declaration.Name), NewText: p.NewName}}
+	for _, reference := range o.declaration.References {
+		edits = append(edits, TextEdit{Range: d.tokenRange(reference.Token), NewText: p.NewName})
+	}
+
+	return WorkspaceEdit{Changes: map[string][]TextEdit{d.uri: edits}}, nil
+}
+
+// isIdentifier reports whether the lexer reads name as a single identifier.
+func isIdentifier(name string) bool {
+	if name == "" || token.LookupIndent(name) != token.IDENT {
+		return false
+	}
+
+	for _, r := range name {
+		if !unicode.IsLetter(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) formatting(params json.RawMessage) (any, error) {
+	var p DocumentFormattingParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := formatter.Format(d.text)
+	if err != nil || formatted == d.text {
+		return []TextEdit{}, nil
+	}
+
+	return []TextEdit{{Range: d.fullRange(), NewText: formatted}}, nil
+}
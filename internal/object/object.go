@@ -2,6 +2,11 @@ package object
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/timur-makarov/monkey-interpreter/internal/ast"
 )
@@ -18,8 +23,14 @@ const (
 	IdentifierType         Type = "IDENTIFIER"
 	FunctionType           Type = "FUNCTION"
 	BuiltinType            Type = "BUILTIN"
+	MethodType             Type = "METHOD"
+	IteratorType           Type = "ITERATOR"
+	ChannelType            Type = "CHANNEL"
+	TaskType               Type = "TASK"
 	ArrayType              Type = "ARRAY"
 	HashTableType          Type = "HASHTABLE"
+	StructDefinitionType   Type = "STRUCTDEFINITION"
+	StructType             Type = "STRUCT"
 	AccessByExpressionType Type = "ACCESSBYEXPRESSION"
 )
 
@@ -112,41 +123,250 @@ func (r Error) String() string {
 }
 
 type Function struct {
-	Parameters []ast.Identifier
-	Body       ast.BlockStatement
-	Env        *Environment
+	Parameters []ast.Node
+	Rest       *ast.Identifier
+	// Generator is set for functions whose calls return an iterator over
+	// the values their body yields, rather than run it.
+	Generator bool
+	Body      ast.BlockStatement
+	Env       *Environment
+	// Scope names the slots of the environments of calls, one for every
+	// name the parameters bind.
+	Scope *ast.Scope
+}
+
+func (f *Function) Type() Type {
+	return FunctionType
 }
 
-func (f Function) Type() Type {
-	return FunctionType
+func (f *Function) String() string {
+	parameters := f.Parameters
+	if f.Rest != nil {
+		parameters = append(slices.Clip(parameters), ast.Identifier{Token: f.Rest.Token, Value: "..." + f.Rest.Value})
+	}
+	keyword := "fn"
+	if f.Generator {
+		keyword = "fn*"
+	}
+	return fmt.Sprintf("%s(%+v) {%s}", keyword, parameters, f.Body)
+}
+
+// Arity is the number of arguments the function accepts.
+func (f *Function) Arity() Arity {
+	return ParameterArity(f.Parameters, f.Rest)
 }
 
-func (f Function) String() string {
-	return fmt.Sprintf("fn(%+v) {%s}", f.Parameters, f.Body)
+// ParameterArity is the number of arguments a function with the parameters
+// accepts: at least up to the last one without a default, and any number
+// more if it has a rest parameter.
+func ParameterArity(parameters []ast.Node, rest *ast.Identifier) Arity {
+	arity := Arity{Max: len(parameters)}
+	for i, parameter := range parameters {
+		if _, ok := parameter.(ast.DefaultPattern); !ok {
+			arity.Min = i + 1
+		}
+	}
+	if rest != nil {
+		arity.Max = -1
+	}
+	return arity
 }
 
+// Iterator produces values one at a time, as a generator yields them or
+// a for loop iterates over a collection. It is used up as it goes.
+type Iterator struct {
+	// Next returns the next value, an error, or nil once there are no more
+	// values, as it does on every call after.
+	Next func() Object
+}
+
+func (it *Iterator) Type() Type {
+	return IteratorType
+}
+
+func (it *Iterator) String() string {
+	return "iterator"
+}
+
+// Array is a list of values. Tasks running concurrently can share an
+// array, so once it is evaluated its items are read and changed only with
+// the methods, which hold a lock of the array.
 type Array struct {
+	mu    sync.RWMutex
 	Items []Object
+	// Frozen arrays cannot be assigned to by index, and hold only frozen
+	// arrays and hash tables.
+	Frozen bool
 }
 
-func (a Array) Type() Type {
+func (a *Array) Type() Type {
 	return ArrayType
 }
 
-func (a Array) String() string {
-	return fmt.Sprintf("%+v", a.Items)
+func (a *Array) String() string {
+	return fmt.Sprintf("%+v", a.Snapshot())
+}
+
+// Len returns the number of items.
+func (a *Array) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.Items)
+}
+
+// Get returns the item at index, and whether there is one.
+func (a *Array) Get(index int) (Object, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if index < 0 || index >= len(a.Items) {
+		return nil, false
+	}
+	return a.Items[index], true
+}
+
+// Set replaces the item at index, and reports whether there is one.
+func (a *Array) Set(index int, value Object) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if index < 0 || index >= len(a.Items) {
+		return false
+	}
+	a.Items[index] = value
+	return true
+}
+
+// Push appends items to the array.
+func (a *Array) Push(items ...Object) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Items = append(a.Items, items...)
+}
+
+// Snapshot returns a copy of the items, which later changes of the array
+// leave as they are.
+func (a *Array) Snapshot() []Object {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return slices.Clone(a.Items)
 }
 
+// HashTable maps string keys to values. Like arrays, hash tables can be
+// shared by tasks, and their items are read and changed with the methods
+// once evaluated.
 type HashTable struct {
+	mu    sync.RWMutex
 	Items map[string]Object
+	// Frozen hash tables cannot be assigned to by key, and hold only
+	// frozen arrays and hash tables.
+	Frozen bool
 }
 
-func (ht HashTable) Type() Type {
+func (ht *HashTable) Type() Type {
 	return HashTableType
 }
 
-func (ht HashTable) String() string {
-	return fmt.Sprintf("%+v", ht.Items)
+func (ht *HashTable) String() string {
+	return fmt.Sprintf("%+v", ht.Snapshot())
+}
+
+// Len returns the number of keys.
+func (ht *HashTable) Len() int {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+	return len(ht.Items)
+}
+
+// Get returns the value of key, and whether it has one.
+func (ht *HashTable) Get(key string) (Object, bool) {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+	value, ok := ht.Items[key]
+	return value, ok
+}
+
+// Set binds key to value.
+func (ht *HashTable) Set(key string, value Object) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	ht.Items[key] = value
+}
+
+// Snapshot returns a copy of the items, which later changes of the hash
+// table leave as they are.
+func (ht *HashTable) Snapshot() map[string]Object {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+	return maps.Clone(ht.Items)
+}
+
+// StructDefinition is a struct type, as a struct statement declares it.
+type StructDefinition struct {
+	Name string
+	// Fields names the fields of the values of the type, in declared
+	// order.
+	Fields []string
+}
+
+func (sd *StructDefinition) Type() Type {
+	return StructDefinitionType
+}
+
+func (sd *StructDefinition) String() string {
+	return fmt.Sprintf("struct %s { %s }", sd.Name, strings.Join(sd.Fields, ", "))
+}
+
+// HasField reports whether values of the type have a field.
+func (sd *StructDefinition) HasField(field string) bool {
+	return slices.Contains(sd.Fields, field)
+}
+
+// Struct is a value of a struct type. Like arrays, structs can be shared
+// by tasks, and their fields are read and changed with the methods once
+// evaluated.
+type Struct struct {
+	mu         sync.RWMutex
+	Definition *StructDefinition
+	// Fields holds a value for every field of the definition.
+	Fields map[string]Object
+	// Frozen structs cannot be assigned to by field, and hold only frozen
+	// arrays, hash tables and structs.
+	Frozen bool
+}
+
+func (s *Struct) Type() Type {
+	return StructType
+}
+
+func (s *Struct) String() string {
+	values := s.Snapshot()
+	fields := make([]string, len(s.Definition.Fields))
+	for i, field := range s.Definition.Fields {
+		fields[i] = fmt.Sprintf("%s: %s", field, values[field])
+	}
+	return fmt.Sprintf("%s{%s}", s.Definition.Name, strings.Join(fields, ", "))
+}
+
+// Get returns the value of a field, and whether the struct has it.
+func (s *Struct) Get(field string) (Object, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.Fields[field]
+	return value, ok
+}
+
+// Set assigns value to a field.
+func (s *Struct) Set(field string, value Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Fields[field] = value
+}
+
+// Snapshot returns a copy of the fields, which later changes of the struct
+// leave as they are.
+func (s *Struct) Snapshot() map[string]Object {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.Fields)
 }
 
 type AccessByExpression struct {
@@ -163,20 +383,63 @@ func (a AccessByExpression) String() string {
 	return fmt.Sprintf("%s[%s]", a.Left, a.Expression)
 }
 
+// Environment binds names to values. Tasks running concurrently share the
+// environments their functions were defined in, so the bindings are
+// guarded by a lock of every environment.
 type Environment struct {
+	mu sync.RWMutex
+	// store holds the names bound without a slot. It is nil until one is.
 	store map[string]Object
-	outer *Environment
+	// slots hold the values of the names of scope, nil for those not bound
+	// yet.
+	slots []Object
+	scope *ast.Scope
+	// constants holds the names bound in this environment, by name or to
+	// a slot, that cannot be assigned to. It is nil until one is.
+	constants map[string]bool
+	outer     *Environment
+	// execution is evaluator state shared by every environment created
+	// while evaluating a program, see Execution.
+	execution any
+	// depth is the number of function calls the environment is nested in.
+	depth int
 }
 
 func NewEnvironment() *Environment {
 	return &Environment{store: make(map[string]Object)}
 }
 
+// lookup returns the value bound to a name in this environment.
+func (e *Environment) lookup(key string) (Object, bool) {
+	if i := e.slot(key); i >= 0 {
+		return e.slots[i], true
+	}
+	obj, ok := e.store[key]
+	return obj, ok
+}
+
+// slot returns the index of the bound slot of a name, or -1. A name may
+// have several slots, like a parameter repeated, and the last one wins.
+func (e *Environment) slot(key string) int {
+	if e.scope == nil {
+		return -1
+	}
+	for i := len(e.slots) - 1; i >= 0; i-- {
+		if e.slots[i] != nil && e.scope.Names[i] == key {
+			return i
+		}
+	}
+	return -1
+}
+
 func (e *Environment) Get(key string) (Object, bool) {
 	cur := e
 
 	for cur != nil {
-		if obj, ok := cur.store[key]; ok {
+		cur.mu.RLock()
+		obj, ok := cur.lookup(key)
+		cur.mu.RUnlock()
+		if ok {
 			return obj, ok
 		}
 		cur = cur.outer
@@ -185,30 +448,261 @@ func (e *Environment) Get(key string) (Object, bool) {
 	return nil, false
 }
 
-func (e *Environment) Set(key string, value Object) {
+// Set assigns to the binding of a name in the innermost environment that
+// has one, or binds it in this environment if none has. It reports whether
+// it did: a constant is left as it is.
+func (e *Environment) Set(key string, value Object) bool {
 	cur := e
 
 	for cur != nil {
-		if _, ok := cur.store[key]; ok {
-			cur.store[key] = value
-			return
+		if assigned, bound := cur.assign(key, value); bound {
+			return assigned
 		}
 		cur = cur.outer
 	}
 
+	return e.Define(key, value)
+}
+
+// assign assigns to the binding of a name in this environment, if it has
+// one, unless it is a constant.
+func (e *Environment) assign(key string, value Object) (assigned, bound bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if i := e.slot(key); i >= 0 {
+		if e.constants[key] {
+			return false, true
+		}
+		e.slots[i] = value
+		return true, true
+	}
+	if _, ok := e.store[key]; ok {
+		if e.constants[key] {
+			return false, true
+		}
+		e.store[key] = value
+		return true, true
+	}
+	return false, false
+}
+
+// Define binds a name in this environment, shadowing any binding of it in
+// the outer ones. It reports whether it did: a constant of this environment
+// cannot be bound again.
+func (e *Environment) Define(key string, value Object) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.define(key, value)
+}
+
+func (e *Environment) define(key string, value Object) bool {
+	if e.constants[key] {
+		return false
+	}
+
+	if e.scope != nil {
+		for i := len(e.slots) - 1; i >= 0; i-- {
+			if e.scope.Names[i] == key {
+				e.slots[i] = value
+				return true
+			}
+		}
+	}
+
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[key] = value
+	return true
+}
+
+// DefineConstant binds a name in this environment like Define, and makes it
+// a constant.
+func (e *Environment) DefineConstant(key string, value Object) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.define(key, value) {
+		return false
+	}
+	e.constant(key)
+	return true
+}
+
+func (e *Environment) constant(key string) {
+	if e.constants == nil {
+		e.constants = make(map[string]bool)
+	}
+	e.constants[key] = true
+}
+
+// GetAt returns the value in a slot of the environment depth levels out, nil
+// if nothing is bound to it yet.
+func (e *Environment) GetAt(depth, slot int) Object {
+	cur := e
+	for ; depth > 0; depth-- {
+		cur = cur.outer
+	}
+
+	cur.mu.RLock()
+	defer cur.mu.RUnlock()
+	return cur.slots[slot]
 }
 
+// SetAt binds a slot of the environment depth levels out. It reports whether
+// it did: a constant is left as it is.
+func (e *Environment) SetAt(depth, slot int, value Object) bool {
+	cur := e
+	for ; depth > 0; depth-- {
+		cur = cur.outer
+	}
+
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+	return cur.setSlot(slot, value)
+}
+
+func (e *Environment) setSlot(slot int, value Object) bool {
+	if e.constants[e.scope.Names[slot]] {
+		return false
+	}
+	e.slots[slot] = value
+	return true
+}
+
+// DefineConstantAt binds a slot of the environment depth levels out like
+// SetAt, and makes its name a constant.
+func (e *Environment) DefineConstantAt(depth, slot int, value Object) bool {
+	cur := e
+	for ; depth > 0; depth-- {
+		cur = cur.outer
+	}
+
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+
+	if !cur.setSlot(slot, value) {
+		return false
+	}
+	cur.constant(cur.scope.Names[slot])
+	return true
+}
+
+// Names returns the names bound directly in this environment, sorted.
+func (e *Environment) Names() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	names := make([]string, 0, len(e.store)+len(e.slots))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	for i, value := range e.slots {
+		if value != nil && !slices.Contains(names, e.scope.Names[i]) {
+			names = append(names, e.scope.Names[i])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Execution returns the state the evaluator attached to this environment.
+// Enclosed environments inherit it from their outer environment, and the
+// evaluator passes it on from a caller to the environment of the function
+// it calls, so it follows the flow of execution rather than scoping.
+func (e *Environment) Execution() any {
+	return e.execution
+}
+
+func (e *Environment) SetExecution(execution any) {
+	e.execution = execution
+}
+
+// NewEnclosedEnvironment creates the environment of a block evaluated in
+// outer. Names are bound in it by name.
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
-	env.outer = outer
+	return NewScopeEnvironment(outer, nil)
+}
+
+// NewScopeEnvironment creates the environment of a block evaluated in outer,
+// with a slot for every name of the scope.
+func NewScopeEnvironment(outer *Environment, scope *ast.Scope) *Environment {
+	env := &Environment{outer: outer, execution: outer.execution, depth: outer.depth}
+	env.bindScope(scope)
+	return env
+}
+
+// NewCallEnvironment creates the environment of a function call made from
+// caller, enclosed by outer, the environment the function was defined in.
+// It continues the execution of the caller, one call deeper, and has a slot
+// for every name of the scope of the parameters.
+func NewCallEnvironment(outer, caller *Environment, scope *ast.Scope) *Environment {
+	env := &Environment{outer: outer, execution: caller.execution, depth: caller.depth + 1}
+	env.bindScope(scope)
 	return env
 }
 
+func (e *Environment) bindScope(scope *ast.Scope) {
+	if scope != nil && len(scope.Names) > 0 {
+		e.scope = scope
+		e.slots = make([]Object, len(scope.Names))
+	}
+}
+
+// Depth is the number of function calls the environment is nested in.
+func (e *Environment) Depth() int {
+	return e.depth
+}
+
 type BuiltinFunction func(args ...Object) Object
 
+// CallerFunction is a builtin function that is also given the environment
+// it is called from, so that it can call back the functions it is given
+// from there, nested in the call like any other.
+type CallerFunction func(caller *Environment, args ...Object) Object
+
+// Arity is the number of arguments a builtin function accepts.
+// A negative Max means there is no upper bound.
+type Arity struct {
+	Min int
+	Max int
+}
+
+func (a Arity) Accepts(count int) bool {
+	return count >= a.Min && (a.Max < 0 || count <= a.Max)
+}
+
+// String describes the accepted number of arguments as "2", "1 to 3" or
+// "at least 1", the way errors of the evaluator and the linter word it.
+func (a Arity) String() string {
+	switch {
+	case a.Max < 0:
+		return fmt.Sprintf("at least %d", a.Min)
+	case a.Min == a.Max:
+		return fmt.Sprintf("%d", a.Min)
+	default:
+		return fmt.Sprintf("%d to %d", a.Min, a.Max)
+	}
+}
+
 type Builtin struct {
 	Function BuiltinFunction
+	// WithCaller is called instead of Function if it is set.
+	WithCaller CallerFunction
+	Arity      Arity
+}
+
+// Call calls the builtin function from the caller.
+func (b Builtin) Call(caller *Environment, args ...Object) Object {
+	if b.WithCaller != nil {
+		return b.WithCaller(caller, args...)
+	}
+	return b.Function(args...)
 }
 
 func (b Builtin) Type() Type {
@@ -218,3 +712,19 @@ func (b Builtin) Type() Type {
 func (b Builtin) String() string {
 	return "builtin function"
 }
+
+// BoundMethod is a method looked up on a value, value.name, which is
+// called with the value followed by the arguments.
+type BoundMethod struct {
+	Receiver Object
+	Name     string
+	Method   Builtin
+}
+
+func (bm BoundMethod) Type() Type {
+	return MethodType
+}
+
+func (bm BoundMethod) String() string {
+	return fmt.Sprintf("method %s of %s", bm.Name, bm.Receiver.Type())
+}
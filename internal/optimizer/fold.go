@@ -0,0 +1,166 @@
+package optimizer
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+// The folding rules mirror the evaluator's operators. Anything it reports
+// an error for, like a division by zero or a type mismatch, is left to fail
+// at runtime.
+
+// isConstant reports whether an expression is a literal of a value that is
+// not allocated when evaluated.
+func isConstant(expression ast.Expression) bool {
+	switch expression.(type) {
+	case ast.Integer, ast.String, ast.Boolean:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTruthy reports whether a constant counts as true in a condition.
+func isTruthy(constant ast.Expression) bool {
+	switch c := constant.(type) {
+	case ast.Integer:
+		return c.Value > 0
+	case ast.Boolean:
+		return c.Value
+	default:
+		return false
+	}
+}
+
+// withPosition returns a constant as if written at the position of tok, so
+// that errors and traces point at the expression it replaces.
+func withPosition(constant ast.Expression, tok token.Token) ast.Expression {
+	switch c := constant.(type) {
+	case ast.Integer:
+		return integer(tok, c.Value)
+	case ast.String:
+		return str(tok, c.Value)
+	case ast.Boolean:
+		return boolean(tok, c.Value)
+	default:
+		return constant
+	}
+}
+
+func integer(at token.Token, value int) ast.Integer {
+	tok := token.Token{Type: token.INT, Literal: strconv.Itoa(value), Line: at.Line, Column: at.Column}
+	return ast.Integer{Token: tok, Value: value}
+}
+
+func str(at token.Token, value string) ast.String {
+	tok := token.Token{Type: token.STRING, Literal: value, Line: at.Line, Column: at.Column}
+	return ast.String{Token: tok, Value: value}
+}
+
+func boolean(at token.Token, value bool) ast.Boolean {
+	tok := token.Token{Type: token.FALSE, Literal: "false", Line: at.Line, Column: at.Column}
+	if value {
+		tok.Type, tok.Literal = token.TRUE, "true"
+	}
+	return ast.Boolean{Token: tok, Value: value}
+}
+
+func foldPrefix(e ast.Prefix) ast.Expression {
+	switch right := e.Right.(type) {
+	case ast.Boolean:
+		if e.Operator == token.BANG {
+			return boolean(e.Token, !right.Value)
+		}
+	case ast.Integer:
+		switch e.Operator {
+		case token.BANG:
+			// The evaluator negates only booleans to true.
+			return boolean(e.Token, false)
+		case token.MINUS:
+			return integer(e.Token, -right.Value)
+		}
+	case ast.String:
+		if e.Operator == token.BANG {
+			return boolean(e.Token, false)
+		}
+	}
+	return e
+}
+
+func foldInfix(e ast.Infix) ast.Expression {
+	at := ast.StartToken(e)
+
+	switch left := e.Left.(type) {
+	case ast.Integer:
+		if right, ok := e.Right.(ast.Integer); ok {
+			return foldIntegers(e, at, left.Value, right.Value)
+		}
+	case ast.String:
+		if right, ok := e.Right.(ast.String); ok {
+			return foldStrings(e, at, left.Value, right.Value)
+		}
+	case ast.Boolean:
+		if right, ok := e.Right.(ast.Boolean); ok {
+			switch e.Operator {
+			case token.EQ:
+				return boolean(at, left.Value == right.Value)
+			case token.NEQ:
+				return boolean(at, left.Value != right.Value)
+			}
+		}
+	}
+
+	// Values of different types are never equal.
+	if isConstant(e.Left) && isConstant(e.Right) && reflect.TypeOf(e.Left) != reflect.TypeOf(e.Right) {
+		switch e.Operator {
+		case token.EQ:
+			return boolean(at, false)
+		case token.NEQ:
+			return boolean(at, true)
+		}
+	}
+	return e
+}
+
+func foldIntegers(e ast.Infix, at token.Token, left, right int) ast.Expression {
+	switch e.Operator {
+	case token.PLUS:
+		return integer(at, left+right)
+	case token.MINUS:
+		return integer(at, left-right)
+	case token.MULTIPLY:
+		return integer(at, left*right)
+	case token.DIVIDE:
+		if right != 0 {
+			return integer(at, left/right)
+		}
+	case token.LT:
+		return boolean(at, left < right)
+	case token.GT:
+		return boolean(at, left > right)
+	case token.EQ:
+		return boolean(at, left == right)
+	case token.NEQ:
+		return boolean(at, left != right)
+	}
+	return e
+}
+
+func foldStrings(e ast.Infix, at token.Token, left, right string) ast.Expression {
+	switch e.Operator {
+	case token.PLUS:
+		return str(at, left+right)
+	case token.LT:
+		return boolean(at, left < right)
+	case token.GT:
+		return boolean(at, left > right)
+	case token.EQ:
+		return boolean(at, left == right)
+	case token.NEQ:
+		return boolean(at, left != right)
+	}
+	return e
+}
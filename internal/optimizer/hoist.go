@@ -0,0 +1,377 @@
+package optimizer
+
+import (
+	"fmt"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+// hoister moves expressions out of while loops whose value is the same in
+// every iteration. Evaluating them before the loop, even one that never
+// iterates, must not be observable, so only integer arithmetic that cannot
+// fail is hoisted: +, - and * of variables that only ever hold integers.
+// Nothing is hoisted from programs that spawn tasks, which may assign any
+// variable while a loop runs.
+type hoister struct {
+	resolution *resolver.Resolution
+	spawns     bool
+	// values maps the name tokens of lets and the targets of assignments to
+	// the expressions assigned.
+	values map[token.Token]ast.Expression
+	// integers holds the variables that only ever hold integers.
+	integers map[*resolver.Declaration]bool
+	hoisted  int
+}
+
+func newHoister(res *resolver.Resolution, program *ast.Program) *hoister {
+	h := &hoister{
+		resolution: res,
+		values:     make(map[token.Token]ast.Expression),
+		integers:   make(map[*resolver.Declaration]bool),
+	}
+	h.collect(program.Statements)
+
+	// Start from every variable holding integers and drop those assigned
+	// anything else, until no more are dropped.
+	for _, d := range res.Declarations {
+		h.integers[d] = d.Kind != resolver.Parameter
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, d := range res.Declarations {
+			if h.integers[d] && !h.holdsIntegers(d) {
+				h.integers[d] = false
+				changed = true
+			}
+		}
+	}
+	return h
+}
+
+func (h *hoister) holdsIntegers(d *resolver.Declaration) bool {
+	if !h.isInteger(d.Value) {
+		return false
+	}
+	for _, reference := range d.References {
+		if reference.Assignment && !h.isInteger(h.values[reference.Token]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isInteger reports whether an expression evaluates to an integer whenever
+// it does not fail to find a name.
+func (h *hoister) isInteger(expression ast.Expression) bool {
+	switch e := expression.(type) {
+	case ast.Integer:
+		return true
+	case ast.Identifier:
+		reference := h.resolution.ReferenceAt(e.Token)
+		return reference != nil && reference.Declaration != nil && h.integers[reference.Declaration]
+	case ast.Prefix:
+		return e.Operator == token.MINUS && h.isInteger(e.Right)
+	case ast.Infix:
+		switch e.Operator {
+		case token.PLUS, token.MINUS, token.MULTIPLY:
+			return h.isInteger(e.Left) && h.isInteger(e.Right)
+		}
+	}
+	return false
+}
+
+func (h *hoister) collect(statements []ast.Statement) {
+	walkStatements(statements, func(node ast.Node) {
+		switch n := node.(type) {
+		case ast.LetStatement:
+			if n.Name != nil {
+				h.values[n.Name.Token] = n.Value
+			}
+		case ast.Infix:
+			if target, ok := n.Left.(ast.Identifier); ok && n.Operator == token.ASSIGN {
+				h.values[target.Token] = n.Right
+			}
+		case ast.Spawn:
+			h.spawns = true
+		}
+	})
+}
+
+// hoist returns the lets to evaluate before a loop and the loop using them.
+func (h *hoister) hoist(loop ast.While) ([]ast.Statement, ast.While) {
+	// A function called in the loop may assign any variable, and so may a
+	// generator a for loop resumes, or the code a yield suspends to.
+	calls := false
+	check := func(node ast.Node) {
+		switch node.(type) {
+		case ast.Call, ast.For, ast.YieldStatement:
+			calls = true
+		}
+	}
+	walk(loop.Condition, check)
+	walkStatements(loop.Body.Statements, check)
+	if calls || h.spawns {
+		return nil, loop
+	}
+
+	r := &replacer{hoister: h, loop: loop}
+	loop.Condition = r.expression(loop.Condition)
+	loop.Body = r.block(loop.Body)
+	return r.lets, loop
+}
+
+// replacer replaces the invariant expressions of a loop with the names of
+// the lets it hoists them to.
+type replacer struct {
+	*hoister
+	loop ast.While
+	lets []ast.Statement
+}
+
+func (r *replacer) inLoop(tok token.Token) bool {
+	start, end := r.loop.Token, r.loop.Body.Closing
+	return !resolver.Before(tok.Line, tok.Column, start.Line, start.Column) &&
+		!resolver.Before(end.Line, end.Column, tok.Line, tok.Column)
+}
+
+// isInvariant reports whether an expression only reads variables declared
+// before the loop and not assigned in it.
+func (r *replacer) isInvariant(expression ast.Expression) bool {
+	names, invariant := 0, true
+	walk(expression, func(node ast.Node) {
+		identifier, ok := node.(ast.Identifier)
+		if !ok {
+			return
+		}
+		names++
+
+		d := r.resolution.ReferenceAt(identifier.Token).Declaration
+		if !resolver.Before(d.Name.Line, d.Name.Column, r.loop.Token.Line, r.loop.Token.Column) {
+			invariant = false
+		}
+		for _, reference := range d.References {
+			if reference.Assignment && r.inLoop(reference.Token) {
+				invariant = false
+			}
+		}
+	})
+	return names > 0 && invariant
+}
+
+func (r *replacer) expression(expression ast.Expression) ast.Expression {
+	switch e := expression.(type) {
+	case ast.Prefix:
+		if r.isInteger(e) && r.isInvariant(e) {
+			return r.hoistExpression(e)
+		}
+		e.Right = r.expression(e.Right)
+		return e
+	case ast.Infix:
+		if r.isInteger(e) && r.isInvariant(e) {
+			return r.hoistExpression(e)
+		}
+		if _, ok := e.Left.(ast.Identifier); !ok || e.Operator != token.ASSIGN {
+			e.Left = r.expression(e.Left)
+		}
+		e.Right = r.expression(e.Right)
+		return e
+	case ast.Array:
+		items := make([]ast.Expression, len(e.Items))
+		for i, item := range e.Items {
+			items[i] = r.expression(item)
+		}
+		e.Items = items
+		return e
+	case ast.HashTable:
+		items := make(map[ast.Expression]ast.Expression, len(e.Items))
+		for _, key := range e.Keys {
+			items[key] = r.expression(e.Items[key])
+		}
+		e.Items = items
+		return e
+	case ast.AccessByExpression:
+		e.Left = r.expression(e.Left)
+		e.Index = r.expression(e.Index)
+		return e
+	case ast.StructLiteral:
+		values := make([]ast.Expression, len(e.Values))
+		for i, value := range e.Values {
+			values[i] = r.expression(value)
+		}
+		e.Values = values
+		return e
+	case ast.FieldAccess:
+		e.Left = r.expression(e.Left)
+		return e
+	case ast.If:
+		conditions := make([]ast.Expression, len(e.Conditions))
+		consequences := make([]ast.BlockStatement, len(e.Consequences))
+		for i := range e.Conditions {
+			conditions[i] = r.expression(e.Conditions[i])
+			consequences[i] = r.block(e.Consequences[i])
+		}
+		e.Conditions, e.Consequences = conditions, consequences
+		e.Alternative = r.block(e.Alternative)
+		return e
+	case ast.While:
+		e.Condition = r.expression(e.Condition)
+		e.Body = r.block(e.Body)
+		return e
+	case ast.For:
+		e.Iterable = r.expression(e.Iterable)
+		e.Body = r.block(e.Body)
+		return e
+	default:
+		// Function literals are left alone: their bodies are not evaluated
+		// by the loop.
+		return expression
+	}
+}
+
+func (r *replacer) block(block ast.BlockStatement) ast.BlockStatement {
+	statements := make([]ast.Statement, len(block.Statements))
+	for i, statement := range block.Statements {
+		switch st := statement.(type) {
+		case ast.LetStatement:
+			st.Value = r.expression(st.Value)
+			statement = st
+		case ast.ReturnStatement:
+			st.Value = r.expression(st.Value)
+			statement = st
+		case ast.YieldStatement:
+			st.Value = r.expression(st.Value)
+			statement = st
+		case ast.ExpressionStatement:
+			st.Expression = r.expression(st.Expression)
+			statement = st
+		}
+		statements[i] = statement
+	}
+	block.Statements = statements
+	return block
+}
+
+// hoistExpression adds a let of the expression before the loop and returns
+// the name it is bound to, which no script can use.
+func (r *replacer) hoistExpression(expression ast.Expression) ast.Identifier {
+	r.hoisted++
+	at := ast.StartToken(expression)
+	name := fmt.Sprintf("loop$%d", r.hoisted)
+
+	identifier := ast.Identifier{
+		Token: token.Token{Type: token.IDENT, Literal: name, Line: at.Line, Column: at.Column},
+		Value: name,
+	}
+	let := ast.LetStatement{
+		Token: token.Token{Type: token.LET, Literal: "let", Line: r.loop.Token.Line, Column: r.loop.Token.Column},
+		Name:  &identifier,
+		Value: expression,
+	}
+	r.lets = append(r.lets, let)
+	return identifier
+}
+
+// walk calls f for an expression and every expression and statement in it,
+// including those in the bodies of function literals.
+func walk(expression ast.Expression, f func(ast.Node)) {
+	if expression == nil {
+		return
+	}
+	f(expression)
+
+	switch e := expression.(type) {
+	case ast.Prefix:
+		walk(e.Right, f)
+	case ast.Infix:
+		walk(e.Left, f)
+		walk(e.Right, f)
+	case ast.Array:
+		for _, item := range e.Items {
+			walk(item, f)
+		}
+	case ast.HashTable:
+		for _, key := range e.Keys {
+			walk(e.Items[key], f)
+		}
+	case ast.AccessByExpression:
+		walk(e.Left, f)
+		walk(e.Index, f)
+	case ast.StructLiteral:
+		walk(e.Type, f)
+		for _, value := range e.Values {
+			walk(value, f)
+		}
+	case ast.FieldAccess:
+		walk(e.Left, f)
+	case ast.Call:
+		walk(e.Function, f)
+		for _, argument := range e.Arguments {
+			walk(argument, f)
+		}
+	case ast.Spread:
+		walk(e.Value, f)
+	case ast.If:
+		for i, condition := range e.Conditions {
+			walk(condition, f)
+			walkStatements(e.Consequences[i].Statements, f)
+		}
+		walkStatements(e.Alternative.Statements, f)
+	case ast.While:
+		walk(e.Condition, f)
+		walkStatements(e.Body.Statements, f)
+	case ast.For:
+		walk(e.Iterable, f)
+		walkDefaults(e.Pattern, f)
+		walkStatements(e.Body.Statements, f)
+	case ast.Match:
+		walk(e.Subject, f)
+		for _, arm := range e.Arms {
+			walkDefaults(arm.Pattern, f)
+			walk(arm.Guard, f)
+			walk(arm.Result, f)
+		}
+	case ast.Spawn:
+		walk(e.Value, f)
+	case ast.Select:
+		for _, c := range e.Cases {
+			if c.Operation != nil {
+				walk(*c.Operation, f)
+			}
+			if c.Pattern != nil {
+				walkDefaults(c.Pattern, f)
+			}
+			walk(c.Result, f)
+		}
+	case ast.Function:
+		for _, parameter := range e.Parameters {
+			walkDefaults(parameter, f)
+		}
+		walkStatements(e.Body.Statements, f)
+	}
+}
+
+func walkStatements(statements []ast.Statement, f func(ast.Node)) {
+	for _, statement := range statements {
+		f(statement)
+
+		switch st := statement.(type) {
+		case ast.LetStatement:
+			walk(st.Value, f)
+			walkDefaults(st.Pattern, f)
+		case ast.ReturnStatement:
+			walk(st.Value, f)
+		case ast.YieldStatement:
+			walk(st.Value, f)
+		case ast.ExpressionStatement:
+			walk(st.Expression, f)
+		}
+	}
+}
+
+func walkDefaults(pattern ast.Node, f func(ast.Node)) {
+	for _, value := range ast.Defaults(pattern) {
+		walk(value, f)
+	}
+}
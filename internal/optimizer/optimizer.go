@@ -0,0 +1,240 @@
+// Package optimizer rewrites programs into equivalent ones that evaluate
+// faster: the same values, the same output and the same errors.
+package optimizer
+
+import (
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+// Optimize returns an optimized copy of a program that parsed without
+// errors. It
+//   - folds prefix and infix expressions of constants,
+//   - removes the branches of if expressions that can never be taken and the
+//     statements after a return,
+//   - replaces the names bound by let to a constant and never reassigned with
+//     the constant,
+//   - evaluates integer expressions that do not change in a while loop once,
+//     before the loop, if the loop calls no functions, iterates over nothing
+//     and does not yield, and the program spawns no tasks.
+func Optimize(program *ast.Program) *ast.Program {
+	o := &optimizer{}
+	optimized := &ast.Program{Statements: o.statements(program.Statements)}
+
+	// Inlining constants makes more expressions constant, and so more names
+	// bound to constants. The rounds end once no reference is left to replace.
+	for inlined := -1; inlined != 0; {
+		o.constants = inlinableConstants(resolver.Resolve(optimized))
+		o.inlined = 0
+		optimized = &ast.Program{Statements: o.statements(optimized.Statements)}
+		inlined = o.inlined
+	}
+
+	o.hoister = newHoister(resolver.Resolve(optimized), optimized)
+	return &ast.Program{Statements: o.statements(optimized.Statements)}
+}
+
+type optimizer struct {
+	// constants maps the identifier tokens to replace to their constants.
+	constants map[token.Token]ast.Expression
+	inlined   int
+	// hoister is set for the pass hoisting loop invariants.
+	hoister *hoister
+}
+
+// inlinableConstants finds the references to variables bound to a constant
+// that are never reassigned. References before the declaration, from
+// function bodies, are left alone: they fail if evaluated too early.
+func inlinableConstants(res *resolver.Resolution) map[token.Token]ast.Expression {
+	constants := make(map[token.Token]ast.Expression)
+
+	for _, d := range res.Declarations {
+		if d.Kind == resolver.Parameter || d.Reassigned() || !isConstant(d.Value) {
+			continue
+		}
+
+		for _, reference := range d.References {
+			if resolver.Before(d.Name.Line, d.Name.Column, reference.Token.Line, reference.Token.Column) {
+				constants[reference.Token] = d.Value
+			}
+		}
+	}
+	return constants
+}
+
+func (o *optimizer) statements(statements []ast.Statement) []ast.Statement {
+	optimized := make([]ast.Statement, 0, len(statements))
+
+	for _, statement := range statements {
+		statement = o.statement(statement)
+
+		if o.hoister != nil {
+			if st, ok := statement.(ast.ExpressionStatement); ok {
+				if loop, ok := st.Expression.(ast.While); ok {
+					var lets []ast.Statement
+					lets, st.Expression = o.hoister.hoist(loop)
+					optimized = append(optimized, lets...)
+					statement = st
+				}
+			}
+		}
+
+		optimized = append(optimized, statement)
+
+		// Nothing after a return is ever evaluated.
+		if _, ok := statement.(ast.ReturnStatement); ok {
+			break
+		}
+	}
+	return optimized
+}
+
+func (o *optimizer) statement(statement ast.Statement) ast.Statement {
+	switch st := statement.(type) {
+	case ast.LetStatement:
+		st.Value = o.expression(st.Value)
+		return st
+	case ast.ReturnStatement:
+		st.Value = o.expression(st.Value)
+		return st
+	case ast.YieldStatement:
+		st.Value = o.expression(st.Value)
+		return st
+	case ast.ExpressionStatement:
+		st.Expression = o.expression(st.Expression)
+		return st
+	default:
+		return statement
+	}
+}
+
+func (o *optimizer) block(block ast.BlockStatement) ast.BlockStatement {
+	block.Statements = o.statements(block.Statements)
+	return block
+}
+
+func (o *optimizer) expressions(expressions []ast.Expression) []ast.Expression {
+	optimized := make([]ast.Expression, len(expressions))
+	for i, expression := range expressions {
+		optimized[i] = o.expression(expression)
+	}
+	return optimized
+}
+
+func (o *optimizer) expression(expression ast.Expression) ast.Expression {
+	switch e := expression.(type) {
+	case ast.Identifier:
+		if constant, ok := o.constants[e.Token]; ok {
+			o.inlined++
+			return withPosition(constant, e.Token)
+		}
+		return e
+	case ast.Prefix:
+		e.Right = o.expression(e.Right)
+		return foldPrefix(e)
+	case ast.Infix:
+		// The target of an assignment is a name, not a value.
+		if _, ok := e.Left.(ast.Identifier); !ok || e.Operator != token.ASSIGN {
+			e.Left = o.expression(e.Left)
+		}
+		e.Right = o.expression(e.Right)
+		return foldInfix(e)
+	case ast.Array:
+		e.Items = o.expressions(e.Items)
+		return e
+	case ast.HashTable:
+		// Identifier keys are looked up when the hash table is evaluated,
+		// so only the values are optimized.
+		items := make(map[ast.Expression]ast.Expression, len(e.Items))
+		for _, key := range e.Keys {
+			items[key] = o.expression(e.Items[key])
+		}
+		e.Items = items
+		return e
+	case ast.AccessByExpression:
+		e.Left = o.expression(e.Left)
+		e.Index = o.expression(e.Index)
+		return e
+	case ast.StructLiteral:
+		e.Values = o.expressions(e.Values)
+		return e
+	case ast.FieldAccess:
+		e.Left = o.expression(e.Left)
+		return e
+	case ast.Call:
+		e.Function = o.expression(e.Function)
+		e.Arguments = o.expressions(e.Arguments)
+		return e
+	case ast.Spread:
+		e.Value = o.expression(e.Value)
+		return e
+	case ast.Function:
+		e.Body = o.block(e.Body)
+		return e
+	case ast.While:
+		e.Condition = o.expression(e.Condition)
+		e.Body = o.block(e.Body)
+		return e
+	case ast.For:
+		e.Iterable = o.expression(e.Iterable)
+		e.Body = o.block(e.Body)
+		return e
+	case ast.If:
+		return o.ifExpression(e)
+	case ast.Match:
+		e.Subject = o.expression(e.Subject)
+		arms := make([]ast.MatchArm, len(e.Arms))
+		for i, arm := range e.Arms {
+			if arm.Guard != nil {
+				arm.Guard = o.expression(arm.Guard)
+			}
+			arm.Result = o.expression(arm.Result)
+			arms[i] = arm
+		}
+		e.Arms = arms
+		return e
+	case ast.Spawn:
+		e.Value = o.expression(e.Value)
+		return e
+	case ast.Select:
+		cases := make([]ast.SelectCase, len(e.Cases))
+		for i, c := range e.Cases {
+			if c.Operation != nil {
+				operation := o.expression(*c.Operation).(ast.Call)
+				c.Operation = &operation
+			}
+			c.Result = o.expression(c.Result)
+			cases[i] = c
+		}
+		e.Cases = cases
+		return e
+	default:
+		return expression
+	}
+}
+
+// ifExpression drops the branches whose condition is a constant that is
+// never true, and those after a condition that is always true, which makes
+// its branch the alternative.
+func (o *optimizer) ifExpression(e ast.If) ast.If {
+	optimized := ast.If{Token: e.Token}
+
+	for i, condition := range e.Conditions {
+		condition = o.expression(condition)
+
+		if !isConstant(condition) {
+			optimized.Conditions = append(optimized.Conditions, condition)
+			optimized.Consequences = append(optimized.Consequences, o.block(e.Consequences[i]))
+			continue
+		}
+
+		if isTruthy(condition) {
+			optimized.Alternative = o.block(e.Consequences[i])
+			return optimized
+		}
+	}
+
+	optimized.Alternative = o.block(e.Alternative)
+	return optimized
+}
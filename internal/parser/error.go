@@ -3,24 +3,42 @@ package parser
 import (
 	"fmt"
 
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
 	"github.com/timur-makarov/monkey-interpreter/internal/token"
 )
 
 type Error struct {
 	Message string
+	Line    int
+	Column  int
 }
 
-func unexpectedTypeError(expected token.Type, actual token.Type) Error {
-	message := fmt.Sprintf("expected next token to be '%s', got %s instead", expected, actual)
-	return Error{Message: message}
+func unexpectedTypeError(expected token.Type, actual token.Token) Error {
+	message := fmt.Sprintf("expected next token to be '%s', got %s instead", expected, actual.Type)
+	return Error{Message: message, Line: actual.Line, Column: actual.Column}
 }
 
-func parseFnNotImplemented(expected token.Type) Error {
-	message := fmt.Sprintf("parse function for token type '%s' is not implemented", expected)
-	return Error{Message: message}
+func parseFnNotImplemented(actual token.Token) Error {
+	message := fmt.Sprintf("parse function for token type '%s' is not implemented", actual.Type)
+	return Error{Message: message, Line: actual.Line, Column: actual.Column}
 }
 
-func invalidValue(expected string, err error) Error {
+func invalidValue(expected string, actual token.Token, err error) Error {
 	message := fmt.Sprintf("error parsing %s value: %v", expected, err)
-	return Error{Message: message}
+	return Error{Message: message, Line: actual.Line, Column: actual.Column}
+}
+
+func duplicateField(field token.Token) Error {
+	message := fmt.Sprintf("duplicate field: %s", field.Literal)
+	return Error{Message: message, Line: field.Line, Column: field.Column}
+}
+
+func invalidPattern(actual token.Token) Error {
+	message := fmt.Sprintf("expected a pattern, got %s instead", actual.Type)
+	return Error{Message: message, Line: actual.Line, Column: actual.Column}
+}
+
+func invalidSelectCase(start token.Token, operation ast.Expression) Error {
+	message := fmt.Sprintf("expected a select case to receive from or send to a channel, got %s instead", operation)
+	return Error{Message: message, Line: start.Line, Column: start.Column}
 }
@@ -14,7 +14,7 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseBoolean() ast.Expression {
 	value, err := strconv.ParseBool(p.token.Literal)
 	if err != nil {
-		p.pushError(invalidValue("boolean", err))
+		p.pushError(invalidValue("boolean", p.token, err))
 	}
 
 	return ast.Boolean{Token: p.token, Value: value}
@@ -23,7 +23,7 @@ func (p *Parser) parseBoolean() ast.Expression {
 func (p *Parser) parseInteger() ast.Expression {
 	value, err := strconv.Atoi(p.token.Literal)
 	if err != nil {
-		p.pushError(invalidValue("integer", err))
+		p.pushError(invalidValue("integer", p.token, err))
 	}
 
 	return ast.Integer{Token: p.token, Value: value}
@@ -70,7 +70,10 @@ func (p *Parser) parseIf() ast.Expression {
 
 		if p.readToken.Type == token.IF {
 			p.nextToken()
-			exp := p.parseIf().(ast.If)
+			exp, ok := p.parseIf().(ast.If)
+			if !ok {
+				return nil
+			}
 			expression.Conditions = append(expression.Conditions, exp.Conditions...)
 			expression.Consequences = append(expression.Consequences, exp.Consequences...)
 			expression.Alternative = exp.Alternative
@@ -108,9 +111,132 @@ func (p *Parser) parseWhile() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseFor() ast.Expression {
+	expression := ast.For{Token: p.token}
+
+	if !p.expectRead(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	switch p.token.Type {
+	case token.IDENT, token.LBRACKET, token.LBRACE:
+		expression.Pattern = p.parsePattern()
+		if expression.Pattern == nil {
+			return nil
+		}
+	default:
+		p.pushError(unexpectedTypeError(token.IDENT, p.token))
+		return nil
+	}
+
+	if !p.expectRead(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+	if expression.Iterable == nil {
+		return nil
+	}
+
+	if !p.expectRead(token.RPAREN) || !p.expectRead(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	return expression
+}
+
+func (p *Parser) parseSelect() ast.Expression {
+	expression := ast.Select{Token: p.token}
+
+	if !p.expectRead(token.LBRACE) {
+		return nil
+	}
+
+	for p.readToken.Type != token.RBRACE && p.readToken.Type != token.EOF {
+		p.nextToken()
+
+		c := ast.SelectCase{Token: p.token}
+		if p.token.Type != token.IDENT || p.token.Literal != "_" || p.readToken.Type != token.ARROW {
+			if !p.parseSelectOperation(&c) {
+				return nil
+			}
+		}
+
+		if !p.expectRead(token.ARROW) {
+			return nil
+		}
+
+		p.nextToken()
+		c.Result = p.parseExpression(LOWEST)
+		if c.Result == nil {
+			return nil
+		}
+		c.End = p.token
+
+		expression.Cases = append(expression.Cases, c)
+
+		if p.readToken.Type == token.COMMA {
+			p.nextToken()
+		} else if p.readToken.Type != token.RBRACE {
+			p.pushError(unexpectedTypeError(token.RBRACE, p.readToken))
+			return nil
+		}
+	}
+
+	if !p.expectRead(token.RBRACE) {
+		return nil
+	}
+
+	return expression
+}
+
+// parseSelectOperation parses what a select case does, starting at the
+// current token: `channel.send(value)`, or `channel.receive()` preceded by
+// the pattern it binds and = if the case binds the value received.
+func (p *Parser) parseSelectOperation(c *ast.SelectCase) bool {
+	if (p.token.Type == token.IDENT && p.readToken.Type == token.ASSIGN) ||
+		p.token.Type == token.LBRACKET || p.token.Type == token.LBRACE {
+		c.Pattern = p.parsePattern()
+		if c.Pattern == nil || !p.expectRead(token.ASSIGN) {
+			return false
+		}
+		p.nextToken()
+	}
+
+	start := p.token
+	operation := p.parseExpression(LOWEST)
+	if operation == nil {
+		return false
+	}
+
+	call, ok := operation.(ast.Call)
+	if ok {
+		field, isField := call.Function.(ast.FieldAccess)
+		ok = isField && (field.Field.Value == "receive" && len(call.Arguments) == 0 ||
+			field.Field.Value == "send" && len(call.Arguments) == 1 && c.Pattern == nil)
+	}
+	if !ok {
+		p.pushError(invalidSelectCase(start, operation))
+		return false
+	}
+
+	c.Operation = &call
+	return true
+}
+
 func (p *Parser) parseFunction() ast.Expression {
 	expression := ast.Function{Token: p.token}
 
+	if p.readToken.Type == token.MULTIPLY {
+		p.nextToken()
+		expression.Generator = true
+	}
+
 	if !p.expectRead(token.LPAREN) {
 		return nil
 	}
@@ -118,7 +244,9 @@ func (p *Parser) parseFunction() ast.Expression {
 	p.nextToken()
 
 	if p.token.Type != token.RPAREN {
-		expression.Parameters = p.parseFunctionParameters()
+		if !p.parseFunctionParameters(&expression) {
+			return nil
+		}
 		p.nextToken()
 	}
 
@@ -131,25 +259,45 @@ func (p *Parser) parseFunction() ast.Expression {
 	return expression
 }
 
-func (p *Parser) parseFunctionParameters() []ast.Identifier {
-	var parameters []ast.Identifier
-
+// parseFunctionParameters parses names and the array and hash table
+// patterns destructuring arguments, with their defaults, and the rest
+// parameter, which comes last.
+func (p *Parser) parseFunctionParameters(fn *ast.Function) bool {
 	for {
-		ident := p.parseIdentifier()
-		parameters = append(parameters, ident.(ast.Identifier))
+		switch p.token.Type {
+		case token.IDENT, token.LBRACKET, token.LBRACE:
+			parameter := p.parseDefault(p.parsePattern())
+			if parameter == nil {
+				return false
+			}
+			fn.Parameters = append(fn.Parameters, parameter)
+		case token.ELLIPSIS:
+			if !p.expectRead(token.IDENT) {
+				return false
+			}
+			fn.Rest = &ast.Identifier{Token: p.token, Value: p.token.Literal}
+			if p.readToken.Type != token.RPAREN {
+				p.pushError(unexpectedTypeError(token.RPAREN, p.readToken))
+				return false
+			}
+			return true
+		default:
+			p.pushError(unexpectedTypeError(token.IDENT, p.token))
+			return false
+		}
 
 		if p.readToken.Type == token.RPAREN {
 			break
 		}
 
 		if !p.expectRead(token.COMMA) {
-			break
+			return false
 		}
 
 		p.nextToken()
 	}
 
-	return parameters
+	return true
 }
 
 func (p *Parser) parseCall(function ast.Expression) ast.Expression {
@@ -165,7 +313,16 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 	var arguments []ast.Expression
 
 	for {
-		argument := p.parseExpression(LOWEST)
+		var argument ast.Expression
+		if p.token.Type == token.ELLIPSIS {
+			spread := ast.Spread{Token: p.token}
+			p.nextToken()
+			if spread.Value = p.parseExpression(LOWEST); spread.Value != nil {
+				argument = spread
+			}
+		} else {
+			argument = p.parseExpression(LOWEST)
+		}
 		if argument == nil {
 			break
 		}
@@ -199,10 +356,11 @@ func (p *Parser) parseArray() ast.Expression {
 		if p.readToken.Type == token.COMMA {
 			p.nextToken()
 		} else if p.readToken.Type != token.RBRACKET {
-			p.pushError(unexpectedTypeError(token.RBRACKET, p.readToken.Type))
+			p.pushError(unexpectedTypeError(token.RBRACKET, p.readToken))
 		}
 		p.nextToken()
 	}
+	expression.Closing = p.token
 
 	return expression
 }
@@ -212,11 +370,19 @@ func (p *Parser) parseHashTable() ast.Expression {
 
 	for p.readToken.Type != token.RBRACE && p.readToken.Type != token.EOF {
 		if p.readToken.Type != token.IDENT && p.readToken.Type != token.STRING {
+			p.pushError(unexpectedTypeError(token.STRING, p.readToken))
 			return nil
 		}
 		p.nextToken()
 
-		keyExp := p.parseExpression(LOWEST)
+		// Keys are names or strings only, which also keeps them usable as
+		// keys of the map.
+		var keyExp ast.Expression
+		if p.token.Type == token.IDENT {
+			keyExp = p.parseIdentifier()
+		} else {
+			keyExp = p.parseString()
+		}
 
 		if !p.expectRead(token.COLON) {
 			return nil
@@ -226,16 +392,22 @@ func (p *Parser) parseHashTable() ast.Expression {
 
 		valExp := p.parseExpression(LOWEST)
 
+		if _, ok := expression.Items[keyExp]; !ok {
+			expression.Keys = append(expression.Keys, keyExp)
+		}
 		expression.Items[keyExp] = valExp
 
 		if p.readToken.Type == token.COMMA {
 			p.nextToken()
 		} else if p.readToken.Type != token.RBRACE {
-			p.pushError(unexpectedTypeError(token.RBRACE, p.readToken.Type))
+			p.pushError(unexpectedTypeError(token.RBRACE, p.readToken))
 		}
 	}
 
-	p.nextToken()
+	if !p.expectRead(token.RBRACE) {
+		return nil
+	}
+	expression.Closing = p.token
 
 	return expression
 }
@@ -253,6 +425,57 @@ func (p *Parser) parseAccessByIndexOrKey(leftExp ast.Expression) ast.Expression
 	return expression
 }
 
+func (p *Parser) parseFieldAccess(leftExp ast.Expression) ast.Expression {
+	expression := ast.FieldAccess{Token: p.token, Left: leftExp}
+
+	if !p.expectRead(token.IDENT) {
+		return nil
+	}
+
+	expression.Field = ast.Identifier{Token: p.token, Value: p.token.Literal}
+
+	return expression
+}
+
+func (p *Parser) parseStructLiteral(leftExp ast.Expression) ast.Expression {
+	expression := ast.StructLiteral{Token: p.token, Type: leftExp.(ast.Identifier)}
+	given := make(map[string]bool)
+
+	for p.readToken.Type != token.RBRACE && p.readToken.Type != token.EOF {
+		if !p.expectRead(token.IDENT) {
+			return nil
+		}
+
+		if given[p.token.Literal] {
+			p.pushError(duplicateField(p.token))
+		}
+		given[p.token.Literal] = true
+		field := ast.Identifier{Token: p.token, Value: p.token.Literal}
+
+		if !p.expectRead(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+
+		expression.Fields = append(expression.Fields, field)
+		expression.Values = append(expression.Values, p.parseExpression(LOWEST))
+
+		if p.readToken.Type == token.COMMA {
+			p.nextToken()
+		} else if p.readToken.Type != token.RBRACE {
+			p.pushError(unexpectedTypeError(token.RBRACE, p.readToken))
+			return nil
+		}
+	}
+
+	if !p.expectRead(token.RBRACE) {
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) parsePrefix() ast.Expression {
 	expression := ast.Prefix{Token: p.token, Operator: p.token.Literal}
 
@@ -262,6 +485,18 @@ func (p *Parser) parsePrefix() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseSpawn() ast.Expression {
+	expression := ast.Spawn{Token: p.token}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(PREFIX)
+	if expression.Value == nil {
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) parseInfix(leftExp ast.Expression) ast.Expression {
 	expression := ast.Infix{Token: p.token, Operator: p.token.Literal, Left: leftExp}
 
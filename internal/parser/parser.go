@@ -3,6 +3,7 @@ package parser
 import (
 	"github.com/timur-makarov/monkey-interpreter/internal/ast"
 	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
 	"github.com/timur-makarov/monkey-interpreter/internal/token"
 )
 
@@ -46,9 +47,17 @@ var precedences = map[token.Type]Precedence{
 	token.DIVIDE:   PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX_OR_KEY,
+	token.DOT:      CALL,
+	token.LBRACE:   CALL,
 	token.ASSIGN:   ASSIGN,
 }
 
+// OperatorPrecedence reports the binding power of an infix operator token,
+// or 0 if the token is not an infix operator.
+func OperatorPrecedence(tokenType token.Type) Precedence {
+	return precedences[tokenType]
+}
+
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{l: l}
 	p.nextToken()
@@ -71,7 +80,11 @@ func New(l *lexer.Lexer) *Parser {
 
 	p.registerPrefixFn(token.IF, p.parseIf)
 	p.registerPrefixFn(token.WHILE, p.parseWhile)
+	p.registerPrefixFn(token.FOR, p.parseFor)
 	p.registerPrefixFn(token.FUNCTION, p.parseFunction)
+	p.registerPrefixFn(token.MATCH, p.parseMatch)
+	p.registerPrefixFn(token.SPAWN, p.parseSpawn)
+	p.registerPrefixFn(token.SELECT, p.parseSelect)
 
 	p.infixParseFns = make(map[token.Type]infixParseFn)
 	p.registerInfixFn(token.PLUS, p.parseInfix)
@@ -85,6 +98,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfixFn(token.ASSIGN, p.parseInfix)
 	p.registerInfixFn(token.LPAREN, p.parseCall)
 	p.registerInfixFn(token.LBRACKET, p.parseAccessByIndexOrKey)
+	p.registerInfixFn(token.DOT, p.parseFieldAccess)
+	p.registerInfixFn(token.LBRACE, p.parseStructLiteral)
 
 	return p
 }
@@ -104,15 +119,27 @@ func (p *Parser) ParseProgram() *ast.Program {
 		p.nextToken()
 	}
 
+	// Assignments to constants are rejected along with syntax errors, once
+	// the program is complete enough to tell which names are constants.
+	if len(p.errors) == 0 {
+		for _, err := range resolver.Resolve(&program).Errors {
+			p.pushError(Error{Message: err.Message, Line: err.Token.Line, Column: err.Token.Column})
+		}
+	}
+
 	return &program
 }
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.token.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -121,16 +148,22 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseExpression(precedence Precedence) ast.Expression {
 	prefix, ok := p.prefixParseFns[p.token.Type]
 	if !ok {
-		p.pushError(parseFnNotImplemented(p.token.Type))
+		p.pushError(parseFnNotImplemented(p.token))
 		return nil
 	}
 
 	leftExp := prefix()
 
 	for p.readToken.Type != token.SEMICOLON && precedence < precedences[p.readToken.Type] {
+		// Only the name of a struct type is followed by the fields of a
+		// struct literal, anything else ends before a brace.
+		if _, ok := leftExp.(ast.Identifier); p.readToken.Type == token.LBRACE && !ok {
+			break
+		}
+
 		infix, ok := p.infixParseFns[p.readToken.Type]
 		if !ok {
-			p.pushError(parseFnNotImplemented(p.token.Type))
+			p.pushError(parseFnNotImplemented(p.token))
 			return nil
 		}
 
@@ -155,7 +188,7 @@ func (p *Parser) expectRead(expectedType token.Type) bool {
 		p.nextToken()
 		return true
 	} else {
-		p.pushError(unexpectedTypeError(expectedType, p.readToken.Type))
+		p.pushError(unexpectedTypeError(expectedType, p.readToken))
 		return false
 	}
 }
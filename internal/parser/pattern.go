@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+func (p *Parser) parseMatch() ast.Expression {
+	expression := ast.Match{Token: p.token}
+
+	if !p.expectRead(token.LPAREN) {
+		return nil
+	}
+
+	expression.Subject = p.parseGroup()
+	if expression.Subject == nil {
+		return nil
+	}
+
+	if !p.expectRead(token.LBRACE) {
+		return nil
+	}
+
+	for p.readToken.Type != token.RBRACE && p.readToken.Type != token.EOF {
+		p.nextToken()
+
+		arm := ast.MatchArm{Token: p.token, Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+
+		if p.readToken.Type == token.IF {
+			p.nextToken()
+			p.nextToken()
+			arm.Guard = p.parseExpression(LOWEST)
+		}
+
+		if !p.expectRead(token.ARROW) {
+			return nil
+		}
+
+		p.nextToken()
+		arm.Result = p.parseExpression(LOWEST)
+		arm.End = p.token
+
+		expression.Arms = append(expression.Arms, arm)
+
+		if p.readToken.Type == token.COMMA {
+			p.nextToken()
+		} else if p.readToken.Type != token.RBRACE {
+			p.pushError(unexpectedTypeError(token.RBRACE, p.readToken))
+			return nil
+		}
+	}
+
+	if !p.expectRead(token.RBRACE) {
+		return nil
+	}
+
+	return expression
+}
+
+// parsePattern parses the pattern starting at the current token: a
+// literal, a name to bind, _ to match anything without binding it, or an
+// array or hash table pattern holding patterns, whose items and values may
+// have defaults.
+func (p *Parser) parsePattern() ast.Node {
+	switch p.token.Type {
+	case token.INT:
+		return p.parseInteger()
+	case token.MINUS:
+		minus := p.token
+		if !p.expectRead(token.INT) {
+			return nil
+		}
+		integer := p.parseInteger().(ast.Integer)
+		return ast.Integer{Token: minus, Value: -integer.Value}
+	case token.STRING:
+		return p.parseString()
+	case token.TRUE, token.FALSE:
+		return p.parseBoolean()
+	case token.IDENT:
+		return p.parseIdentifier()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashTablePattern()
+	default:
+		p.pushError(invalidPattern(p.token))
+		return nil
+	}
+}
+
+// parseDefault parses the default of the pattern ending at the current
+// token, if it is followed by one.
+func (p *Parser) parseDefault(pattern ast.Node) ast.Node {
+	if pattern == nil || p.readToken.Type != token.ASSIGN {
+		return pattern
+	}
+
+	p.nextToken()
+	withDefault := ast.DefaultPattern{Token: p.token, Pattern: pattern}
+
+	p.nextToken()
+	withDefault.Default = p.parseExpression(LOWEST)
+	if withDefault.Default == nil {
+		return nil
+	}
+	return withDefault
+}
+
+func (p *Parser) parseArrayPattern() ast.Node {
+	pattern := ast.ArrayPattern{Token: p.token}
+
+	for p.readToken.Type != token.RBRACKET && p.readToken.Type != token.EOF {
+		p.nextToken()
+
+		// The rest binds what the items before it leave over, so it
+		// comes last.
+		if p.token.Type == token.ELLIPSIS {
+			if !p.expectRead(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.token, Value: p.token.Literal}
+			break
+		}
+
+		item := p.parseDefault(p.parsePattern())
+		if item == nil {
+			return nil
+		}
+		pattern.Items = append(pattern.Items, item)
+
+		if p.readToken.Type == token.COMMA {
+			p.nextToken()
+		} else if p.readToken.Type != token.RBRACKET {
+			p.pushError(unexpectedTypeError(token.RBRACKET, p.readToken))
+			return nil
+		}
+	}
+
+	if !p.expectRead(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseHashTablePattern() ast.Node {
+	pattern := ast.HashTablePattern{Token: p.token}
+
+	for p.readToken.Type != token.RBRACE && p.readToken.Type != token.EOF {
+		p.nextToken()
+
+		switch p.token.Type {
+		case token.IDENT:
+			name := p.parseIdentifier().(ast.Identifier)
+			value := p.parseDefault(name)
+			if value == nil {
+				return nil
+			}
+			pattern.Keys = append(pattern.Keys, ast.String{Token: name.Token, Value: name.Value})
+			pattern.Values = append(pattern.Values, value)
+		case token.STRING:
+			key := p.parseString().(ast.String)
+			if !p.expectRead(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+
+			value := p.parseDefault(p.parsePattern())
+			if value == nil {
+				return nil
+			}
+			pattern.Keys = append(pattern.Keys, key)
+			pattern.Values = append(pattern.Values, value)
+		default:
+			p.pushError(unexpectedTypeError(token.STRING, p.token))
+			return nil
+		}
+
+		if p.readToken.Type == token.COMMA {
+			p.nextToken()
+		} else if p.readToken.Type != token.RBRACE {
+			p.pushError(unexpectedTypeError(token.RBRACE, p.readToken))
+			return nil
+		}
+	}
+
+	if !p.expectRead(token.RBRACE) {
+		return nil
+	}
+
+	return pattern
+}
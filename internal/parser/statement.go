@@ -8,18 +8,73 @@ import (
 func (p *Parser) parseLetStatement() ast.Statement {
 	statement := ast.LetStatement{Token: p.token}
 
+	switch p.readToken.Type {
+	case token.LBRACKET, token.LBRACE:
+		p.nextToken()
+		statement.Pattern = p.parsePattern()
+		if statement.Pattern == nil {
+			return nil
+		}
+	default:
+		if !p.expectRead(token.IDENT) {
+			return nil
+		}
+		statement.Name = &ast.Identifier{Token: p.token, Value: p.token.Literal}
+	}
+
+	if !p.expectRead(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.readToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseStructStatement() ast.Statement {
+	statement := ast.StructStatement{Token: p.token}
+
 	if !p.expectRead(token.IDENT) {
 		return nil
 	}
 
 	statement.Name = &ast.Identifier{Token: p.token, Value: p.token.Literal}
 
-	if !p.expectRead(token.ASSIGN) {
+	if !p.expectRead(token.LBRACE) {
 		return nil
 	}
 
-	p.nextToken()
-	statement.Value = p.parseExpression(LOWEST)
+	declared := make(map[string]bool)
+
+	for p.readToken.Type != token.RBRACE && p.readToken.Type != token.EOF {
+		if !p.expectRead(token.IDENT) {
+			return nil
+		}
+
+		if declared[p.token.Literal] {
+			p.pushError(duplicateField(p.token))
+		}
+		declared[p.token.Literal] = true
+		statement.Fields = append(statement.Fields, ast.Identifier{Token: p.token, Value: p.token.Literal})
+
+		if p.readToken.Type == token.COMMA {
+			p.nextToken()
+		} else if p.readToken.Type != token.RBRACE {
+			p.pushError(unexpectedTypeError(token.RBRACE, p.readToken))
+			return nil
+		}
+	}
+
+	if !p.expectRead(token.RBRACE) {
+		return nil
+	}
+
+	statement.Closing = p.token
 
 	if p.readToken.Type == token.SEMICOLON {
 		p.nextToken()
@@ -41,6 +96,19 @@ func (p *Parser) parseReturnStatement() ast.Statement {
 	return statement
 }
 
+func (p *Parser) parseYieldStatement() ast.Statement {
+	statement := ast.YieldStatement{Token: p.token}
+
+	p.nextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.readToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+
+	return statement
+}
+
 func (p *Parser) parseExpressionStatement() ast.Statement {
 	statement := ast.ExpressionStatement{Token: p.token}
 
@@ -66,5 +134,7 @@ func (p *Parser) parseBlockStatement() ast.BlockStatement {
 		p.nextToken()
 	}
 
+	statement.Closing = p.token
+
 	return statement
 }
@@ -0,0 +1,197 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// The pprof format is a gzipped protocol buffer, the Profile message of
+// https://github.com/google/pprof/blob/main/proto/profile.proto. Only the
+// parts needed for call graphs are written, encoded by hand.
+
+// Field numbers of the messages written.
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileTimeNanos         = 9
+	profileDurationNanos     = 10
+	profilePeriodType        = 11
+	profilePeriod            = 12
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+)
+
+// buffer encodes protocol buffer messages.
+type buffer struct {
+	data []byte
+}
+
+func (b *buffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *buffer) key(field, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// int64 writes a varint field, leaving out zero values like proto3 does.
+func (b *buffer) int64(field int, x int64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.varint(uint64(x))
+}
+
+func (b *buffer) bytes(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *buffer) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *buffer) message(field int, encode func(m *buffer)) {
+	var m buffer
+	encode(&m)
+	b.bytes(field, m.data)
+}
+
+// packed writes a repeated varint field in packed form.
+func (b *buffer) packed(field int, xs []uint64) {
+	var m buffer
+	for _, x := range xs {
+		m.varint(x)
+	}
+	b.bytes(field, m.data)
+}
+
+// stringTable numbers the strings of a profile, whose first entry must be
+// the empty string.
+type stringTable struct {
+	table []string
+	index map[string]int64
+}
+
+func (s *stringTable) id(str string) int64 {
+	if s.index == nil {
+		s.index = map[string]int64{"": 0}
+		s.table = []string{""}
+	}
+
+	if id, ok := s.index[str]; ok {
+		return id
+	}
+	id := int64(len(s.table))
+	s.table = append(s.table, str)
+	s.index[str] = id
+	return id
+}
+
+// WritePprof writes the profile in the pprof format, with a sample of
+// time and allocations for every call stack, so that `go tool pprof` shows
+// the Monkey functions and lines instead of the interpreter's.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	var b buffer
+	var names stringTable
+
+	valueType := func(typ, unit string) func(m *buffer) {
+		return func(m *buffer) {
+			m.int64(valueTypeType, names.id(typ))
+			m.int64(valueTypeUnit, names.id(unit))
+		}
+	}
+	b.message(profileSampleType, valueType("time", "nanoseconds"))
+	b.message(profileSampleType, valueType("allocations", "count"))
+
+	functions := make(map[string]uint64)
+	locations := make(map[location]uint64)
+	var functionOrder []string
+	var locationOrder []location
+
+	p.mu.Lock()
+	p.walk(func(n *node, stack []location) {
+		if n.self == 0 && n.allocations == 0 {
+			return
+		}
+
+		ids := make([]uint64, len(stack))
+		for i, loc := range stack {
+			if _, ok := functions[loc.function]; !ok {
+				functions[loc.function] = uint64(len(functions) + 1)
+				functionOrder = append(functionOrder, loc.function)
+			}
+			if _, ok := locations[loc]; !ok {
+				locations[loc] = uint64(len(locations) + 1)
+				locationOrder = append(locationOrder, loc)
+			}
+			ids[i] = locations[loc]
+		}
+
+		b.message(profileSample, func(m *buffer) {
+			m.packed(sampleLocationID, ids)
+			m.packed(sampleValue, []uint64{uint64(n.self.Nanoseconds()), uint64(n.allocations)})
+		})
+	})
+	p.mu.Unlock()
+
+	for _, loc := range locationOrder {
+		b.message(profileLocation, func(m *buffer) {
+			m.int64(locationID, int64(locations[loc]))
+			m.message(locationLine, func(l *buffer) {
+				l.int64(lineFunctionID, int64(functions[loc.function]))
+				l.int64(lineLine, int64(loc.line))
+			})
+		})
+	}
+
+	for _, name := range functionOrder {
+		b.message(profileFunction, func(m *buffer) {
+			m.int64(functionID, int64(functions[name]))
+			m.int64(functionName, names.id(name))
+			m.int64(functionSystemName, names.id(name))
+			m.int64(functionFilename, names.id(p.filename))
+		})
+	}
+
+	b.int64(profileTimeNanos, p.start.UnixNano())
+	b.int64(profileDurationNanos, p.Duration().Nanoseconds())
+	b.message(profilePeriodType, valueType("time", "nanoseconds"))
+	b.int64(profilePeriod, 1)
+	b.int64(profileDefaultSampleType, names.id("time"))
+
+	for _, str := range names.table {
+		b.string(profileStringTable, str)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
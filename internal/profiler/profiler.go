@@ -0,0 +1,368 @@
+// Package profiler measures where the evaluation of a script spends its
+// time and allocates values, by function and by line.
+package profiler
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// Profiler is an evaluator.Observer that accounts for all of the time of
+// an evaluation: every interval between two events is charged to the line
+// the innermost frame was executing, under the call stack that led there.
+// It is safe for concurrent use, so tasks running in parallel can report
+// to it; the events of tasks left running after Stop are dropped.
+type Profiler struct {
+	mu       sync.Mutex
+	filename string
+	now      func() time.Time
+
+	start, last, end time.Time
+
+	root    *node
+	current *node
+	// frames holds the node each frame started in, and the time it did.
+	frames []frameStart
+	// statements holds the statements being evaluated, innermost last.
+	statements []statementStart
+
+	functions map[string]*FunctionStats
+	lines     map[int]*LineStats
+	// active counts the frames of a function, and the statements of a
+	// line, that are being evaluated: only the outermost add their time
+	// to the cumulative time, so recursion is not counted twice.
+	activeFunctions map[string]int
+	activeLines     map[int]int
+}
+
+// FunctionStats are the measurements of a function, named the way the
+// call stack names it.
+type FunctionStats struct {
+	Name  string
+	Calls int
+	// Self is the time spent in the function itself, Cumulative adds the
+	// time spent in the functions it called.
+	Self        time.Duration
+	Cumulative  time.Duration
+	Allocations int
+}
+
+// LineStats are the measurements of a line of the script. Hits counts the
+// statements starting on it that were evaluated.
+type LineStats struct {
+	Line        int
+	Hits        int
+	Self        time.Duration
+	Cumulative  time.Duration
+	Allocations int
+}
+
+// location is where time is spent: a line of a function.
+type location struct {
+	function string
+	line     int
+}
+
+// node is a call stack, the path from the root to it, in a tree of all
+// the call stacks seen so far.
+type node struct {
+	location    location
+	parent      *node
+	children    map[location]*node
+	self        time.Duration
+	allocations int
+}
+
+type frameStart struct {
+	node  *node
+	start time.Time
+}
+
+type statementStart struct {
+	node  *node
+	line  int
+	depth int
+	start time.Time
+}
+
+// New creates a profiler for the script with the filename.
+func New(filename string) *Profiler {
+	return &Profiler{
+		filename:        filename,
+		now:             time.Now,
+		root:            &node{},
+		functions:       make(map[string]*FunctionStats),
+		lines:           make(map[int]*LineStats),
+		activeFunctions: make(map[string]int),
+		activeLines:     make(map[int]int),
+	}
+}
+
+// Attach makes the profiler observe the evaluations in env and starts the
+// clock.
+func (p *Profiler) Attach(env *object.Environment) {
+	evaluator.Attach(env, p)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.start = p.now()
+	p.last = p.start
+	p.current = p.root.child(location{function: "main"})
+	p.frames = []frameStart{{node: p.current, start: p.start}}
+	p.activeFunctions["main"] = 1
+	p.function("main").Calls++
+}
+
+// Stop stops the clock, charging the time since the last event to the
+// main frame.
+func (p *Profiler) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.end = p.tick()
+
+	if len(p.frames) > 0 {
+		main := p.function("main")
+		main.Cumulative += p.end.Sub(p.frames[0].start)
+		p.frames = nil
+	}
+}
+
+// Duration is the time between attaching and stopping the profiler.
+func (p *Profiler) Duration() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.end.Sub(p.start)
+}
+
+func (n *node) child(loc location) *node {
+	if c, ok := n.children[loc]; ok {
+		return c
+	}
+
+	if n.children == nil {
+		n.children = make(map[location]*node)
+	}
+	c := &node{location: loc, parent: n}
+	n.children[loc] = c
+	return c
+}
+
+// tick charges the time since the last event to the current call stack.
+func (p *Profiler) tick() time.Time {
+	now := p.now()
+	p.current.self += now.Sub(p.last)
+	p.last = now
+	return now
+}
+
+func (p *Profiler) function(name string) *FunctionStats {
+	stats, ok := p.functions[name]
+	if !ok {
+		stats = &FunctionStats{Name: name}
+		p.functions[name] = stats
+	}
+	return stats
+}
+
+func (p *Profiler) line(line int) *LineStats {
+	stats, ok := p.lines[line]
+	if !ok {
+		stats = &LineStats{Line: line}
+		p.lines[line] = stats
+	}
+	return stats
+}
+
+func (p *Profiler) Statement(statement ast.Statement, stack []*evaluator.Frame) object.Object {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped() {
+		return nil
+	}
+
+	now := p.tick()
+	frame := stack[len(stack)-1]
+
+	p.current = p.current.parent.child(location{function: frame.Function, line: frame.Line})
+	p.statements = append(p.statements, statementStart{
+		node: p.current, line: frame.Line, depth: len(stack), start: now,
+	})
+
+	p.line(frame.Line).Hits++
+	p.activeLines[frame.Line]++
+	return nil
+}
+
+func (p *Profiler) StatementDone(statement ast.Statement, stack []*evaluator.Frame) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped() || len(p.statements) == 0 {
+		return
+	}
+
+	now := p.tick()
+
+	done := p.statements[len(p.statements)-1]
+	p.statements = p.statements[:len(p.statements)-1]
+
+	p.activeLines[done.line]--
+	if p.activeLines[done.line] == 0 {
+		p.line(done.line).Cumulative += now.Sub(done.start)
+	}
+
+	// Back in the statement around it, if that is in the same frame, or
+	// else where the frame started.
+	if n := len(p.statements); n > 0 && p.statements[n-1].depth == len(stack) {
+		p.current = p.statements[n-1].node
+	} else {
+		p.current = p.frames[len(p.frames)-1].node
+	}
+}
+
+func (p *Profiler) Error(object.Error, []*evaluator.Frame) {}
+
+func (p *Profiler) Call(stack []*evaluator.Frame) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped() {
+		return
+	}
+
+	now := p.tick()
+	frame := stack[len(stack)-1]
+
+	p.current = p.current.child(location{function: frame.Function, line: frame.Line})
+	p.frames = append(p.frames, frameStart{node: p.current, start: now})
+
+	p.function(frame.Function).Calls++
+	p.activeFunctions[frame.Function]++
+}
+
+func (p *Profiler) Return(stack []*evaluator.Frame) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// The frame of main is never returned from.
+	if p.stopped() || len(p.frames) < 2 {
+		return
+	}
+
+	now := p.tick()
+	frame := stack[len(stack)-1]
+
+	started := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+
+	p.activeFunctions[frame.Function]--
+	if p.activeFunctions[frame.Function] == 0 {
+		p.function(frame.Function).Cumulative += now.Sub(started.start)
+	}
+
+	// The caller continues in the statement that made the call.
+	p.current = started.node.parent
+}
+
+func (p *Profiler) Allocate(value object.Object, stack []*evaluator.Frame) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.stopped() {
+		p.current.allocations++
+	}
+}
+
+// stopped reports whether Stop has been called.
+func (p *Profiler) stopped() bool {
+	return p.frames == nil
+}
+
+// walk calls visit for every call stack in the tree, innermost location first.
+func (p *Profiler) walk(visit func(n *node, stack []location)) {
+	var walk func(n *node, stack []location)
+	walk = func(n *node, stack []location) {
+		stack = append([]location{n.location}, stack...)
+		visit(n, stack)
+		for _, c := range n.sortedChildren() {
+			walk(c, stack)
+		}
+	}
+
+	for _, c := range p.root.sortedChildren() {
+		walk(c, nil)
+	}
+}
+
+// Functions returns the measurements of every function called, by
+// descending cumulative time.
+func (p *Profiler) Functions() []FunctionStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	self := make(map[string]time.Duration)
+	allocations := make(map[string]int)
+	p.walk(func(n *node, _ []location) {
+		self[n.location.function] += n.self
+		allocations[n.location.function] += n.allocations
+	})
+
+	functions := make([]FunctionStats, 0, len(p.functions))
+	for name, stats := range p.functions {
+		f := *stats
+		f.Self, f.Allocations = self[name], allocations[name]
+		functions = append(functions, f)
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].Cumulative != functions[j].Cumulative {
+			return functions[i].Cumulative > functions[j].Cumulative
+		}
+		return functions[i].Name < functions[j].Name
+	})
+	return functions
+}
+
+// Lines returns the measurements of every line a statement on which was
+// evaluated, in line order.
+func (p *Profiler) Lines() []LineStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	self := make(map[int]time.Duration)
+	allocations := make(map[int]int)
+	p.walk(func(n *node, _ []location) {
+		self[n.location.line] += n.self
+		allocations[n.location.line] += n.allocations
+	})
+
+	lines := make([]LineStats, 0, len(p.lines))
+	for line, stats := range p.lines {
+		l := *stats
+		l.Self, l.Allocations = self[line], allocations[line]
+		lines = append(lines, l)
+	}
+
+	sort.Slice(lines, func(i, j int) bool { return lines[i].Line < lines[j].Line })
+	return lines
+}
+
+// sortedChildren returns the children of a node in a stable order.
+func (n *node) sortedChildren() []*node {
+	children := make([]*node, 0, len(n.children))
+	for _, c := range n.children {
+		children = append(children, c)
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		a, b := children[i].location, children[j].location
+		if a.function != b.function {
+			return a.function < b.function
+		}
+		return a.line < b.line
+	})
+	return children
+}
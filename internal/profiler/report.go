@@ -0,0 +1,47 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteText writes a report of the functions, by descending cumulative
+// time, and of the lines of the source, in order.
+func (p *Profiler) WriteText(w io.Writer, source string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(tw, "Total time: %s\n\n", duration(p.Duration()))
+
+	fmt.Fprintln(tw, "calls\tself\tcumulative\tallocations\t \tfunction")
+	for _, f := range p.Functions() {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t \t%s\n", f.Calls, duration(f.Self), duration(f.Cumulative), f.Allocations, f.Name)
+	}
+
+	sourceLines := strings.Split(source, "\n")
+
+	fmt.Fprintln(tw, "\nhits\tself\tcumulative\tallocations\tline\t")
+	for _, l := range p.Lines() {
+		text := ""
+		if l.Line >= 1 && l.Line <= len(sourceLines) {
+			text = strings.TrimSpace(sourceLines[l.Line-1])
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d:\t %s\n", l.Hits, duration(l.Self), duration(l.Cumulative), l.Allocations, l.Line, text)
+	}
+
+	return tw.Flush()
+}
+
+// duration rounds a duration to three significant digits or microseconds.
+func duration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
 	"github.com/timur-makarov/monkey-interpreter/internal/object"
 	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
 )
 
 const PROMPT = "Monkey code -> "
@@ -36,7 +37,7 @@ func ReadUserInput(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
+		evaluated := evaluator.Eval(resolver.Bind(program), env)
 		if evaluated != nil {
 			_, _ = io.WriteString(out, evaluated.String()+"\n")
 		}
@@ -0,0 +1,303 @@
+package resolver
+
+import (
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+// Bind returns a copy of a program that parsed without errors in which the
+// variables of functions and blocks are located by index rather than by
+// name: every block and parameter list gets the scope of slots the
+// evaluator creates its environment with, and every identifier naming one of
+// their variables the depth and slot of the variable. Global variables are
+// still looked up by name, so that programs evaluated one after another in
+// an environment, like the lines of the REPL, see each other's.
+//
+// Blocks that bind no names get an empty scope, which tells the evaluator to
+// create no environment for them, and that is accounted for in the depths.
+func Bind(program *ast.Program) *ast.Program {
+	res := Resolve(program)
+	b := &binder{
+		resolution:   res,
+		scopes:       make(map[token.Token]*Scope),
+		slots:        make(map[*Declaration]int),
+		names:        make(map[*Scope]*ast.Scope),
+		declarations: make(map[token.Token]*Declaration),
+		bindsByName:  make(map[*Scope]bool),
+	}
+
+	for _, s := range res.Scopes[1:] {
+		b.scopes[s.Start] = s
+		b.names[s] = &ast.Scope{}
+	}
+
+	// Slots are numbered in the order the names are declared in, which for
+	// a function is the order of its parameters.
+	for _, d := range res.Declarations {
+		b.declarations[d.Name] = d
+		if d.Scope.Outer == nil {
+			continue
+		}
+
+		names := b.names[d.Scope]
+		b.slots[d] = len(names.Names)
+		names.Names = append(names.Names, d.Name.Literal)
+	}
+
+	// Assigning to a name that is not declared, a builtin's, binds it in
+	// the environment of the block, which must then have one.
+	for _, reference := range res.References {
+		if reference.Assignment && reference.Declaration == nil {
+			b.bindsByName[reference.Scope] = true
+		}
+	}
+
+	return &ast.Program{Statements: b.statements(program.Statements)}
+}
+
+type binder struct {
+	resolution *Resolution
+	// scopes maps the tokens scopes start at to them.
+	scopes       map[token.Token]*Scope
+	slots        map[*Declaration]int
+	names        map[*Scope]*ast.Scope
+	declarations map[token.Token]*Declaration
+	bindsByName  map[*Scope]bool
+}
+
+// hasEnvironment reports whether the evaluator creates an environment for
+// a scope: the program and every call have one, blocks only if they bind
+// names.
+func (b *binder) hasEnvironment(s *Scope) bool {
+	return s.Outer == nil || s.Start.Type == token.FUNCTION ||
+		len(b.names[s].Names) > 0 || b.bindsByName[s]
+}
+
+// local locates the variable a reference is to, nil for globals, builtins
+// and undefined names.
+func (b *binder) local(reference *Reference) *ast.Local {
+	if reference == nil || reference.Declaration == nil || reference.Declaration.Scope.Outer == nil {
+		return nil
+	}
+
+	depth := 0
+	for s := reference.Scope; s != reference.Declaration.Scope; s = s.Outer {
+		if b.hasEnvironment(s) {
+			depth++
+		}
+	}
+	return &ast.Local{Depth: depth, Slot: b.slots[reference.Declaration]}
+}
+
+func (b *binder) scope(start token.Token) *ast.Scope {
+	s, ok := b.scopes[start]
+	switch {
+	case !ok:
+		return nil
+	case len(b.names[s].Names) == 0 && b.bindsByName[s]:
+		// An environment binding names only by name.
+		return nil
+	default:
+		return b.names[s]
+	}
+}
+
+func (b *binder) statements(statements []ast.Statement) []ast.Statement {
+	bound := make([]ast.Statement, len(statements))
+
+	for i, statement := range statements {
+		switch st := statement.(type) {
+		case ast.LetStatement:
+			st.Value = b.expression(st.Value)
+			if st.Name != nil {
+				st.Name = b.declaration(*st.Name)
+			} else {
+				st.Pattern = b.pattern(st.Pattern)
+			}
+			statement = st
+		case ast.StructStatement:
+			if st.Name != nil {
+				st.Name = b.declaration(*st.Name)
+			}
+			statement = st
+		case ast.ReturnStatement:
+			st.Value = b.expression(st.Value)
+			statement = st
+		case ast.YieldStatement:
+			st.Value = b.expression(st.Value)
+			statement = st
+		case ast.ExpressionStatement:
+			st.Expression = b.expression(st.Expression)
+			statement = st
+		}
+		bound[i] = statement
+	}
+	return bound
+}
+
+// declaration locates the name a statement declares.
+func (b *binder) declaration(name ast.Identifier) *ast.Identifier {
+	if d, ok := b.declarations[name.Token]; ok && d.Scope.Outer != nil {
+		name.Local = &ast.Local{Slot: b.slots[d]}
+	} else {
+		// A repeated declaration assigns to the name declared first.
+		name.Local = b.local(b.resolution.ReferenceAt(name.Token))
+	}
+	return &name
+}
+
+func (b *binder) block(block ast.BlockStatement) ast.BlockStatement {
+	block.Scope = b.scope(block.Token)
+	block.Statements = b.statements(block.Statements)
+	return block
+}
+
+func (b *binder) expressions(expressions []ast.Expression) []ast.Expression {
+	bound := make([]ast.Expression, len(expressions))
+	for i, expression := range expressions {
+		bound[i] = b.expression(expression)
+	}
+	return bound
+}
+
+func (b *binder) expression(expression ast.Expression) ast.Expression {
+	switch e := expression.(type) {
+	case ast.Identifier:
+		e.Local = b.local(b.resolution.ReferenceAt(e.Token))
+		return e
+	case ast.Prefix:
+		e.Right = b.expression(e.Right)
+		return e
+	case ast.Infix:
+		e.Left = b.expression(e.Left)
+		e.Right = b.expression(e.Right)
+		return e
+	case ast.Array:
+		e.Items = b.expressions(e.Items)
+		return e
+	case ast.HashTable:
+		keys := make([]ast.Expression, len(e.Keys))
+		items := make(map[ast.Expression]ast.Expression, len(e.Items))
+		for i, key := range e.Keys {
+			keys[i] = b.expression(key)
+			items[keys[i]] = b.expression(e.Items[key])
+		}
+		e.Keys, e.Items = keys, items
+		return e
+	case ast.AccessByExpression:
+		e.Left = b.expression(e.Left)
+		e.Index = b.expression(e.Index)
+		return e
+	case ast.StructLiteral:
+		e.Type = b.expression(e.Type).(ast.Identifier)
+		e.Values = b.expressions(e.Values)
+		return e
+	case ast.FieldAccess:
+		e.Left = b.expression(e.Left)
+		return e
+	case ast.Call:
+		e.Function = b.expression(e.Function)
+		e.Arguments = b.expressions(e.Arguments)
+		return e
+	case ast.Spread:
+		e.Value = b.expression(e.Value)
+		return e
+	case ast.If:
+		conditions := make([]ast.Expression, len(e.Conditions))
+		consequences := make([]ast.BlockStatement, len(e.Consequences))
+		for i := range e.Conditions {
+			conditions[i] = b.expression(e.Conditions[i])
+			consequences[i] = b.block(e.Consequences[i])
+		}
+		e.Conditions, e.Consequences = conditions, consequences
+		e.Alternative = b.block(e.Alternative)
+		return e
+	case ast.While:
+		e.Condition = b.expression(e.Condition)
+		e.Body = b.block(e.Body)
+		return e
+	case ast.For:
+		e.Iterable = b.expression(e.Iterable)
+		e.Pattern = b.pattern(e.Pattern)
+		e.Body = b.block(e.Body)
+		return e
+	case ast.Match:
+		e.Subject = b.expression(e.Subject)
+		arms := make([]ast.MatchArm, len(e.Arms))
+		for i, arm := range e.Arms {
+			arm.Scope = b.scope(arm.Token)
+			arm.Pattern = b.pattern(arm.Pattern)
+			if arm.Guard != nil {
+				arm.Guard = b.expression(arm.Guard)
+			}
+			arm.Result = b.expression(arm.Result)
+			arms[i] = arm
+		}
+		e.Arms = arms
+		return e
+	case ast.Spawn:
+		e.Value = b.expression(e.Value)
+		return e
+	case ast.Select:
+		cases := make([]ast.SelectCase, len(e.Cases))
+		for i, c := range e.Cases {
+			if c.Operation != nil {
+				operation := b.expression(*c.Operation).(ast.Call)
+				c.Operation = &operation
+			}
+			c.Scope = b.scope(c.Token)
+			if c.Pattern != nil {
+				c.Pattern = b.pattern(c.Pattern)
+			}
+			c.Result = b.expression(c.Result)
+			cases[i] = c
+		}
+		e.Cases = cases
+		return e
+	case ast.Function:
+		e.Scope = b.scope(e.Token)
+		parameters := make([]ast.Node, len(e.Parameters))
+		for i, parameter := range e.Parameters {
+			parameters[i] = b.pattern(parameter)
+		}
+		e.Parameters = parameters
+		if e.Rest != nil {
+			e.Rest = b.declaration(*e.Rest)
+		}
+		e.Body = b.block(e.Body)
+		return e
+	default:
+		return expression
+	}
+}
+
+// pattern locates the names a pattern binds.
+func (b *binder) pattern(pattern ast.Node) ast.Node {
+	switch p := pattern.(type) {
+	case ast.Identifier:
+		return *b.declaration(p)
+	case ast.DefaultPattern:
+		p.Default = b.expression(p.Default)
+		p.Pattern = b.pattern(p.Pattern)
+		return p
+	case ast.ArrayPattern:
+		items := make([]ast.Node, len(p.Items))
+		for i, item := range p.Items {
+			items[i] = b.pattern(item)
+		}
+		p.Items = items
+		if p.Rest != nil {
+			p.Rest = b.declaration(*p.Rest)
+		}
+		return p
+	case ast.HashTablePattern:
+		values := make([]ast.Node, len(p.Values))
+		for i, value := range p.Values {
+			values[i] = b.pattern(value)
+		}
+		p.Values = values
+		return p
+	default:
+		return pattern
+	}
+}
@@ -0,0 +1,399 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+type Kind string
+
+const (
+	Variable  Kind = "variable"
+	Constant  Kind = "constant"
+	Parameter Kind = "parameter"
+	Struct    Kind = "struct"
+)
+
+type Declaration struct {
+	Name token.Token
+	Kind Kind
+	// Value is the expression a variable is declared with, the statement
+	// declaring a struct type, or nil for parameters and names bound by
+	// patterns.
+	Value      ast.Expression
+	Scope      *Scope
+	References []*Reference
+}
+
+// Used reports whether the declaration is read anywhere.
+func (d *Declaration) Used() bool {
+	for _, reference := range d.References {
+		if !reference.Assignment {
+			return true
+		}
+	}
+	return false
+}
+
+// Reassigned reports whether the declaration is assigned after being declared.
+func (d *Declaration) Reassigned() bool {
+	for _, reference := range d.References {
+		if reference.Assignment {
+			return true
+		}
+	}
+	return false
+}
+
+// Function returns the function literal a variable is bound to, as long
+// as it is never reassigned.
+func (d *Declaration) Function() (ast.Function, bool) {
+	fn, ok := d.Value.(ast.Function)
+	return fn, ok && !d.Reassigned()
+}
+
+type Reference struct {
+	Token token.Token
+	// Scope is the innermost scope the reference is made in.
+	Scope *Scope
+	// Declaration is nil for builtins and undefined names.
+	Declaration *Declaration
+	// Assignment is set for the target of `=` and for a repeated let or
+	// const, which the evaluator treats as an assignment.
+	Assignment bool
+}
+
+// Scope is a program, a function's parameter list, a block or an arm of a
+// match expression. Start and End are the tokens delimiting it; they are
+// zero for the program scope.
+type Scope struct {
+	Outer        *Scope
+	Start        token.Token
+	End          token.Token
+	Declarations map[string]*Declaration
+}
+
+func (s *Scope) Lookup(name string) *Declaration {
+	for cur := s; cur != nil; cur = cur.Outer {
+		if d, ok := cur.Declarations[name]; ok {
+			return d
+		}
+	}
+	return nil
+}
+
+// Contains reports whether the 1-based line and column lie within the scope.
+func (s *Scope) Contains(line, column int) bool {
+	if s.Outer == nil {
+		return true
+	}
+	return !Before(line, column, s.Start.Line, s.Start.Column) &&
+		!Before(s.End.Line, s.End.Column, line, column)
+}
+
+// Before reports whether position a comes before position b.
+func Before(aLine, aColumn, bLine, bColumn int) bool {
+	return aLine < bLine || aLine == bLine && aColumn < bColumn
+}
+
+// Error is a binding the program may not make: an assignment to a constant,
+// or a constant declared over a name already declared in its scope.
+type Error struct {
+	Token   token.Token
+	Message string
+}
+
+type Resolution struct {
+	Declarations []*Declaration
+	References   []*Reference
+	// Errors lists the invalid bindings, ordered by position.
+	Errors []Error
+	// Scopes lists every scope, starting with the program scope.
+	Scopes []*Scope
+
+	byToken map[token.Token]*Reference
+}
+
+// ReferenceAt returns the reference made by an identifier token.
+func (r *Resolution) ReferenceAt(tok token.Token) *Reference {
+	return r.byToken[tok]
+}
+
+// ScopeAt returns the innermost scope containing the position.
+func (r *Resolution) ScopeAt(line, column int) *Scope {
+	innermost := r.Scopes[0]
+	for _, s := range r.Scopes[1:] {
+		// Scopes containing the same position are nested, so the one
+		// starting last is the innermost.
+		if s.Contains(line, column) &&
+			Before(innermost.Start.Line, innermost.Start.Column, s.Start.Line, s.Start.Column) {
+			innermost = s
+		}
+	}
+	return innermost
+}
+
+// Resolve binds every identifier in the program to its declaration.
+//
+// Scoping follows the source structure: a let, a const, a struct or a
+// parameter declares a name in the enclosing block or function, and a let of
+// a name that already exists in the same scope is an assignment to it, an
+// error if the name is a constant. Function bodies are resolved after the
+// rest of the program, so they may refer to names declared later
+// in an enclosing scope, as recursive and mutually recursive functions do.
+// The program may be incomplete, as produced from input with syntax errors.
+//
+// A yield is an error outside of generators, and inside expressions other
+// than the if, while and for expressions that generators are made of.
+func Resolve(program *ast.Program) *Resolution {
+	r := &resolver{resolution: &Resolution{byToken: make(map[token.Token]*Reference)}}
+
+	r.statements(program.Statements, r.newScope(nil, token.Token{}, token.Token{}))
+
+	for len(r.functions) > 0 {
+		fn := r.functions[0]
+		r.functions = r.functions[1:]
+		r.function(fn.node, fn.scope)
+	}
+
+	res := r.resolution
+	sort.SliceStable(res.Declarations, func(i, j int) bool {
+		a, b := res.Declarations[i].Name, res.Declarations[j].Name
+		return Before(a.Line, a.Column, b.Line, b.Column)
+	})
+	sort.SliceStable(res.References, func(i, j int) bool {
+		a, b := res.References[i].Token, res.References[j].Token
+		return Before(a.Line, a.Column, b.Line, b.Column)
+	})
+	sort.SliceStable(res.Errors, func(i, j int) bool {
+		a, b := res.Errors[i].Token, res.Errors[j].Token
+		return Before(a.Line, a.Column, b.Line, b.Column)
+	})
+
+	return res
+}
+
+type resolver struct {
+	resolution *Resolution
+	functions  []pendingFunction
+	// generator is set while the body of a generator is resolved, and
+	// yields while its statements can yield, outside of expressions.
+	generator bool
+	yields    bool
+}
+
+type pendingFunction struct {
+	node  ast.Function
+	scope *Scope
+}
+
+func (r *resolver) newScope(outer *Scope, start, end token.Token) *Scope {
+	s := &Scope{Outer: outer, Start: start, End: end, Declarations: make(map[string]*Declaration)}
+	r.resolution.Scopes = append(r.resolution.Scopes, s)
+	return s
+}
+
+func (r *resolver) declare(s *Scope, name token.Token, kind Kind, value ast.Expression) {
+	if existing, ok := s.Declarations[name.Literal]; ok && kind != Parameter {
+		if kind == Constant && existing.Kind != Constant {
+			r.error(name, "cannot declare constant %s: already declared", name.Literal)
+		}
+		r.reference(s, existing, name, true)
+		return
+	}
+
+	d := &Declaration{Name: name, Kind: kind, Value: value, Scope: s}
+	s.Declarations[name.Literal] = d
+	r.resolution.Declarations = append(r.resolution.Declarations, d)
+}
+
+func (r *resolver) reference(s *Scope, d *Declaration, tok token.Token, assignment bool) {
+	reference := &Reference{Token: tok, Scope: s, Declaration: d, Assignment: assignment}
+	if d != nil {
+		d.References = append(d.References, reference)
+		if assignment && d.Kind == Constant {
+			r.error(tok, "cannot assign to constant: %s", tok.Literal)
+		}
+	}
+	r.resolution.References = append(r.resolution.References, reference)
+	r.resolution.byToken[tok] = reference
+}
+
+func (r *resolver) error(tok token.Token, format string, a ...any) {
+	r.resolution.Errors = append(r.resolution.Errors, Error{Token: tok, Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *resolver) statements(statements []ast.Statement, s *Scope) {
+	for _, statement := range statements {
+		switch st := statement.(type) {
+		case ast.LetStatement:
+			r.value(st.Value, s)
+			kind := Variable
+			if st.Constant() {
+				kind = Constant
+			}
+			if st.Name != nil {
+				r.declare(s, st.Name.Token, kind, st.Value)
+			} else {
+				r.pattern(st.Pattern, kind, s)
+			}
+		case ast.StructStatement:
+			if st.Name != nil {
+				r.declare(s, st.Name.Token, Struct, st)
+			}
+		case ast.ReturnStatement:
+			r.value(st.Value, s)
+		case ast.YieldStatement:
+			switch {
+			case !r.generator:
+				r.error(st.Token, "yield outside of a generator")
+			case !r.yields:
+				r.error(st.Token, "cannot yield inside an expression")
+			}
+			r.value(st.Value, s)
+		case ast.ExpressionStatement:
+			switch st.Expression.(type) {
+			case ast.If, ast.While, ast.For:
+				r.expression(st.Expression, s)
+			default:
+				r.value(st.Expression, s)
+			}
+		}
+	}
+}
+
+func (r *resolver) block(block ast.BlockStatement, s *Scope) {
+	r.statements(block.Statements, r.newScope(s, block.Token, block.Closing))
+}
+
+func (r *resolver) function(fn ast.Function, s *Scope) {
+	r.generator, r.yields = fn.Generator, fn.Generator
+
+	params := r.newScope(s, fn.Token, fn.Body.Closing)
+	for _, param := range fn.Parameters {
+		r.pattern(param, Parameter, params)
+	}
+	if fn.Rest != nil {
+		r.pattern(*fn.Rest, Parameter, params)
+	}
+
+	r.block(fn.Body, params)
+}
+
+func (r *resolver) expression(expression ast.Expression, s *Scope) {
+	switch e := expression.(type) {
+	case ast.Identifier:
+		r.reference(s, s.Lookup(e.Value), e.Token, false)
+	case ast.Prefix:
+		r.expression(e.Right, s)
+	case ast.Infix:
+		if target, ok := e.Left.(ast.Identifier); ok && e.Operator == token.ASSIGN {
+			r.reference(s, s.Lookup(target.Value), target.Token, true)
+		} else {
+			r.expression(e.Left, s)
+		}
+		r.expression(e.Right, s)
+	case ast.Array:
+		for _, item := range e.Items {
+			r.expression(item, s)
+		}
+	case ast.HashTable:
+		for _, key := range e.Keys {
+			r.expression(key, s)
+			r.expression(e.Items[key], s)
+		}
+	case ast.AccessByExpression:
+		r.expression(e.Left, s)
+		r.expression(e.Index, s)
+	case ast.StructLiteral:
+		r.expression(e.Type, s)
+		for _, value := range e.Values {
+			r.expression(value, s)
+		}
+	case ast.FieldAccess:
+		r.expression(e.Left, s)
+	case ast.Call:
+		r.expression(e.Function, s)
+		for _, argument := range e.Arguments {
+			r.expression(argument, s)
+		}
+	case ast.Spread:
+		r.expression(e.Value, s)
+	case ast.If:
+		for i, condition := range e.Conditions {
+			r.value(condition, s)
+			if i < len(e.Consequences) {
+				r.block(e.Consequences[i], s)
+			}
+		}
+		r.block(e.Alternative, s)
+	case ast.While:
+		r.value(e.Condition, s)
+		r.block(e.Body, s)
+	case ast.For:
+		r.value(e.Iterable, s)
+		body := r.newScope(s, e.Body.Token, e.Body.Closing)
+		r.pattern(e.Pattern, Variable, body)
+		r.statements(e.Body.Statements, body)
+	case ast.Match:
+		r.expression(e.Subject, s)
+		for _, arm := range e.Arms {
+			scope := r.newScope(s, arm.Token, arm.End)
+			r.pattern(arm.Pattern, Variable, scope)
+			r.expression(arm.Guard, scope)
+			r.expression(arm.Result, scope)
+		}
+	case ast.Spawn:
+		r.expression(e.Value, s)
+	case ast.Select:
+		for _, c := range e.Cases {
+			if c.Operation != nil {
+				r.expression(*c.Operation, s)
+			}
+			scope := r.newScope(s, c.Token, c.End)
+			if c.Pattern != nil {
+				r.pattern(c.Pattern, Variable, scope)
+			}
+			r.expression(c.Result, scope)
+		}
+	case ast.Function:
+		r.functions = append(r.functions, pendingFunction{node: e, scope: s})
+	}
+}
+
+// value resolves an expression evaluated for its value, which the blocks
+// in it cannot yield from.
+func (r *resolver) value(expression ast.Expression, s *Scope) {
+	yields := r.yields
+	r.yields = false
+	r.expression(expression, s)
+	r.yields = yields
+}
+
+// pattern declares the names a pattern binds, as the kind given, after
+// resolving the defaults before them. The name _ binds nothing.
+func (r *resolver) pattern(pattern ast.Node, kind Kind, s *Scope) {
+	switch p := pattern.(type) {
+	case ast.Identifier:
+		if p.Value != "_" {
+			r.declare(s, p.Token, kind, nil)
+		}
+	case ast.DefaultPattern:
+		r.value(p.Default, s)
+		r.pattern(p.Pattern, kind, s)
+	case ast.ArrayPattern:
+		for _, item := range p.Items {
+			r.pattern(item, kind, s)
+		}
+		if p.Rest != nil {
+			r.pattern(*p.Rest, kind, s)
+		}
+	case ast.HashTablePattern:
+		for _, value := range p.Values {
+			r.pattern(value, kind, s)
+		}
+	}
+}
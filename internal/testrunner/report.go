@@ -0,0 +1,149 @@
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Failed counts the results of failed tests.
+func Failed(results []Result) int {
+	failed := 0
+	for _, result := range results {
+		if !result.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+func indent(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+// WriteText writes a failure report for every failed test and a summary,
+// and with verbose a line for every passed test as well.
+func WriteText(w io.Writer, results []Result, verbose bool) error {
+	var b strings.Builder
+
+	for _, result := range results {
+		name := fmt.Sprintf("%s (%s:%d)", result.Test.Name, result.Test.File, result.Test.Line)
+		if result.Test.Line == 0 {
+			name = result.Test.Name
+		}
+
+		switch {
+		case !result.Passed:
+			fmt.Fprintf(&b, "--- FAIL: %s (%s)\n%s\n", name, seconds(result.Duration), indent(result.Message, "    "))
+		case verbose:
+			fmt.Fprintf(&b, "--- PASS: %s (%s)\n", name, seconds(result.Duration))
+		}
+	}
+
+	if failed := Failed(results); failed > 0 {
+		fmt.Fprintf(&b, "FAIL: %d of %d tests failed\n", failed, len(results))
+	} else {
+		fmt.Fprintf(&b, "PASS: %d tests\n", len(results))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+// WriteTAP writes the results in the Test Anything Protocol, version 13.
+func WriteTAP(w io.Writer, results []Result) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(results))
+	for i, result := range results {
+		status := "ok"
+		if !result.Passed {
+			status = "not ok"
+		}
+		fmt.Fprintf(&b, "%s %d - %s: %s\n", status, i+1, result.Test.File, result.Test.Name)
+
+		if !result.Passed {
+			fmt.Fprintf(&b, "  ---\n  message: |\n%s\n  ...\n", indent(result.Message, "    "))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as JUnit XML, with a test suite per file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitTestSuites{Tests: len(results), Failures: Failed(results)}
+	suites := make(map[string]int)
+	durations := make(map[string]time.Duration)
+
+	for _, result := range results {
+		file := result.Test.File
+		i, ok := suites[file]
+		if !ok {
+			i = len(report.Suites)
+			suites[file] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: file})
+		}
+
+		testCase := junitTestCase{
+			Name:      result.Test.Name,
+			ClassName: file,
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+		}
+		if !result.Passed {
+			message, _, _ := strings.Cut(result.Message, "\n")
+			testCase.Failure = &junitFailure{Message: message, Text: result.Message}
+			report.Suites[i].Failures++
+		}
+
+		report.Suites[i].Tests++
+		report.Suites[i].Cases = append(report.Suites[i].Cases, testCase)
+		durations[file] += result.Duration
+	}
+
+	for i := range report.Suites {
+		report.Suites[i].Time = fmt.Sprintf("%.3f", durations[report.Suites[i].Name].Seconds())
+	}
+
+	content, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, content)
+	return err
+}
@@ -0,0 +1,181 @@
+package testrunner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+)
+
+// FileSuffix ends the names of files holding tests.
+const FileSuffix = "_test.monkey"
+
+// TestPrefix starts the names of test functions: functions without
+// parameters bound by a top-level let in a test file.
+const TestPrefix = "test"
+
+// File is the source of a test file.
+type File struct {
+	Path   string
+	Source string
+}
+
+// Test is a test function found in a file.
+type Test struct {
+	File    string
+	Name    string
+	Line    int
+	program *ast.Program
+}
+
+// Result is the outcome of running a test. Tests whose file fails to
+// parse or to evaluate are reported as a single failed test named after
+// the file.
+type Result struct {
+	Test     Test
+	Passed   bool
+	Message  string
+	Duration time.Duration
+}
+
+type Options struct {
+	// Filter selects the tests to run by name. All tests run if it is nil.
+	Filter *regexp.Regexp
+	// Parallel is the number of tests run at the same time, at least one.
+	Parallel int
+	// Prepare is called with the environment of each test before its file
+	// is evaluated, for instance to attach a hook.
+	Prepare func(test Test, env *object.Environment)
+	// Deterministic runs the tasks every test spawns with a scheduler of
+	// its own, seeded with Seed, so that they run the same way every time.
+	// The tasks a test leaves behind are stopped once it is done.
+	Deterministic bool
+	Seed          uint64
+}
+
+// Discover parses a test file and returns its tests in source order. A
+// file that does not parse yields a failed result instead.
+func Discover(file File) ([]Test, *Result) {
+	p := parser.New(lexer.New(file.Source))
+	program := p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) > 0 {
+		messages := make([]string, len(errors))
+		for i, err := range errors {
+			messages[i] = fmt.Sprintf("%d:%d: %s", err.Line, err.Column, err.Message)
+		}
+		return nil, &Result{
+			Test:    Test{File: file.Path, Name: file.Path},
+			Message: "syntax errors:\n" + strings.Join(messages, "\n"),
+		}
+	}
+
+	bound := resolver.Bind(program)
+
+	var tests []Test
+	for _, statement := range program.Statements {
+		let, ok := statement.(ast.LetStatement)
+		if !ok || let.Name == nil || !strings.HasPrefix(let.Name.Value, TestPrefix) {
+			continue
+		}
+
+		if fn, ok := let.Value.(ast.Function); ok && len(fn.Parameters) == 0 {
+			tests = append(tests, Test{File: file.Path, Name: let.Name.Value, Line: let.Token.Line, program: bound})
+		}
+	}
+
+	return tests, nil
+}
+
+// Run runs the tests of the files and returns the results in the order of
+// the files and of the tests in them, however many run in parallel.
+//
+// Every test runs in an environment of its own: the whole file is
+// evaluated in it before the test function is called, so tests cannot see
+// what other tests did.
+func Run(files []File, options Options) []Result {
+	var results []Result
+	var tests []Test
+
+	for _, file := range files {
+		discovered, failure := Discover(file)
+		if failure != nil {
+			results = append(results, *failure)
+			continue
+		}
+
+		for _, test := range discovered {
+			if options.Filter == nil || options.Filter.MatchString(test.Name) {
+				tests = append(tests, test)
+			}
+		}
+	}
+
+	testResults := make([]Result, len(tests))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+
+	for range max(options.Parallel, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				testResults[i] = runTest(tests[i], options)
+			}
+		}()
+	}
+
+	for i := range tests {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	return append(results, testResults...)
+}
+
+func runTest(test Test, options Options) (result Result) {
+	result.Test = test
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			result.Passed, result.Message = false, fmt.Sprintf("panic: %v", r)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	env := object.NewEnvironment()
+	if options.Deterministic {
+		scheduler := evaluator.NewScheduler(options.Seed)
+		evaluator.Schedule(env, scheduler)
+		defer scheduler.Stop()
+	}
+	if options.Prepare != nil {
+		options.Prepare(test, env)
+	}
+
+	if err, ok := evaluator.Eval(test.program, env).(object.Error); ok {
+		result.Message = "evaluating the file: " + err.Message
+		return result
+	}
+
+	call := &ast.Program{Statements: []ast.Statement{
+		ast.ExpressionStatement{Expression: ast.Call{Function: ast.Identifier{Value: test.Name}}},
+	}}
+	if err, ok := evaluator.Eval(call, env).(object.Error); ok {
+		result.Message = err.Message
+		return result
+	}
+
+	result.Passed = true
+	return result
+}
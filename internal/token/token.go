@@ -5,6 +5,8 @@ type Type string
 type Token struct {
 	Type    Type
 	Literal string
+	Line    int
+	Column  int
 }
 
 const (
@@ -15,6 +17,8 @@ const (
 	INT    = "INT"
 	STRING = "STRING"
 
+	COMMENT = "COMMENT"
+
 	ASSIGN   = "="
 	PLUS     = "+"
 	MINUS    = "-"
@@ -22,6 +26,9 @@ const (
 	MULTIPLY = "*"
 
 	COMMA     = ","
+	DOT       = "."
+	ELLIPSIS  = "..."
+	ARROW     = "=>"
 	COLON     = ":"
 	SEMICOLON = ";"
 	BANG      = "!"
@@ -39,10 +46,18 @@ const (
 
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
+	STRUCT   = "STRUCT"
+	MATCH    = "MATCH"
 	IF       = "IF"
 	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	YIELD    = "YIELD"
+	SPAWN    = "SPAWN"
+	SELECT   = "SELECT"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 )
@@ -50,12 +65,20 @@ const (
 var keywords = map[string]Type{
 	"fn":     FUNCTION,
 	"let":    LET,
+	"const":  CONST,
+	"struct": STRUCT,
+	"match":  MATCH,
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
 	"true":   TRUE,
 	"false":  FALSE,
 	"while":  WHILE,
+	"for":    FOR,
+	"in":     IN,
+	"yield":  YIELD,
+	"spawn":  SPAWN,
+	"select": SELECT,
 }
 
 func LookupIndent(ident string) Type {
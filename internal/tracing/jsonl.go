@@ -0,0 +1,138 @@
+// Package tracing has evaluator.Tracer implementations that record what
+// scripts do: a writer of JSON lines and an exporter of OpenTelemetry
+// style spans.
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// Event is a line written by a JSONWriter.
+type Event struct {
+	Time string `json:"time"`
+	// Event is enter, exit, call, return, builtin or error.
+	Event string `json:"event"`
+	// Depth is the number of function calls the event happens in.
+	Depth     int      `json:"depth"`
+	Node      string   `json:"node,omitempty"`
+	Line      int      `json:"line,omitempty"`
+	Column    int      `json:"column,omitempty"`
+	Function  string   `json:"function,omitempty"`
+	Arguments []string `json:"arguments,omitempty"`
+	// Value is missing from the return of a function that ended with a
+	// call in tail position.
+	Value string `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// JSONWriter is a tracer writing every event as a line of JSON. It is safe
+// for concurrent use, so tasks running in parallel write whole lines.
+type JSONWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	now     func() time.Time
+	depth   int
+	err     error
+}
+
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{encoder: json.NewEncoder(w), now: time.Now}
+}
+
+// Err returns the first error writing an event failed with. Writing stops
+// after it.
+func (j *JSONWriter) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// write writes an event. It is called with mu held.
+func (j *JSONWriter) write(e Event) {
+	if j.err != nil {
+		return
+	}
+
+	e.Time = j.now().UTC().Format(time.RFC3339Nano)
+	e.Depth = j.depth
+	j.err = j.encoder.Encode(e)
+}
+
+// nodeEvent describes a node by its type and position.
+func nodeEvent(event string, node ast.Node) Event {
+	tok := ast.StartToken(node)
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*")
+	return Event{Event: event, Node: strings.TrimPrefix(name, "ast."), Line: tok.Line, Column: tok.Column}
+}
+
+func inspectAll(values []object.Object) []string {
+	inspected := make([]string, len(values))
+	for i, value := range values {
+		inspected[i] = evaluator.Inspect(value)
+	}
+	return inspected
+}
+
+func (j *JSONWriter) Enter(node ast.Node) {
+	e := nodeEvent("enter", node)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.write(e)
+}
+
+func (j *JSONWriter) Exit(node ast.Node, result object.Object) {
+	e := nodeEvent("exit", node)
+	e.Value = evaluator.Inspect(result)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.write(e)
+}
+
+func (j *JSONWriter) Call(function string, args []object.Object) {
+	e := Event{Event: "call", Function: function, Arguments: inspectAll(args)}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.depth++
+	j.write(e)
+}
+
+func (j *JSONWriter) Return(function string, result object.Object) {
+	e := Event{Event: "return", Function: function}
+	if result != nil {
+		e.Value = evaluator.Inspect(result)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.write(e)
+	j.depth--
+}
+
+func (j *JSONWriter) Builtin(name string, args []object.Object, result object.Object) {
+	e := Event{Event: "builtin", Function: name, Arguments: inspectAll(args), Value: evaluator.Inspect(result)}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.write(e)
+}
+
+func (j *JSONWriter) Error(err object.Error, node ast.Node) {
+	e := nodeEvent("error", node)
+	e.Error = err.Message
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.write(e)
+}
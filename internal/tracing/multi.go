@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// Multi is a tracer passing every event on to the tracers in it, in order.
+type Multi []evaluator.Tracer
+
+func (m Multi) Enter(node ast.Node) {
+	for _, t := range m {
+		t.Enter(node)
+	}
+}
+
+func (m Multi) Exit(node ast.Node, result object.Object) {
+	for _, t := range m {
+		t.Exit(node, result)
+	}
+}
+
+func (m Multi) Call(function string, args []object.Object) {
+	for _, t := range m {
+		t.Call(function, args)
+	}
+}
+
+func (m Multi) Return(function string, result object.Object) {
+	for _, t := range m {
+		t.Return(function, result)
+	}
+}
+
+func (m Multi) Builtin(name string, args []object.Object, result object.Object) {
+	for _, t := range m {
+		t.Builtin(name, args, result)
+	}
+}
+
+func (m Multi) Error(err object.Error, node ast.Node) {
+	for _, t := range m {
+		t.Error(err, node)
+	}
+}
@@ -0,0 +1,269 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+// The spans are written in the JSON encoding of the OpenTelemetry protocol
+// (OTLP), so collectors and trace viewers can import the files.
+
+// Status codes of spans.
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+// spanKindInternal is the kind of spans that stay within a process.
+const spanKindInternal = 1
+
+type Span struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId,omitempty"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	// Times are nanoseconds since the Unix epoch, as strings, like OTLP
+	// encodes 64-bit integers in JSON.
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []Attribute `json:"attributes,omitempty"`
+	Events            []SpanEvent `json:"events,omitempty"`
+	Status            Status      `json:"status"`
+}
+
+type SpanEvent struct {
+	TimeUnixNano string      `json:"timeUnixNano"`
+	Name         string      `json:"name"`
+	Attributes   []Attribute `json:"attributes,omitempty"`
+}
+
+type Attribute struct {
+	Key   string `json:"key"`
+	Value Value  `json:"value"`
+}
+
+type Value struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func stringAttribute(key, value string) Attribute {
+	return Attribute{Key: key, Value: Value{StringValue: &value}}
+}
+
+func intAttribute(key string, value int) Attribute {
+	s := strconv.Itoa(value)
+	return Attribute{Key: key, Value: Value{IntValue: &s}}
+}
+
+// SpanBatchSize is the number of ended spans a SpanExporter writes at once.
+const SpanBatchSize = 256
+
+// SpanExporter is a tracer recording a span for the program and for every
+// function call in it. Builtin calls and errors become events of the span
+// they happen in. Spans are written in batches as they end, each batch a
+// line of JSON like the files of the OpenTelemetry collector, so that only
+// the spans still open and those of the batch are kept. It is safe for
+// concurrent use; the events of tasks left running after Close are dropped.
+type SpanExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	now     func() time.Time
+	traceID string
+	err     error
+
+	// batch holds the ended spans not written yet.
+	batch []Span
+	// open holds the spans that have not ended yet, innermost last.
+	open []*Span
+	// callLines holds the lines of the call expressions being evaluated.
+	callLines []int
+	closed    bool
+}
+
+// NewSpanExporter starts a trace with a span for the program, which is
+// named after the script.
+func NewSpanExporter(w io.Writer, script string) *SpanExporter {
+	s := &SpanExporter{encoder: json.NewEncoder(w), now: time.Now, traceID: randomID(16)}
+
+	root := s.start(script)
+	root.Attributes = append(root.Attributes, stringAttribute("code.filepath", script))
+	return s
+}
+
+func randomID(bytes int) string {
+	id := make([]byte, bytes)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func (s *SpanExporter) timestamp() string {
+	return strconv.FormatInt(s.now().UnixNano(), 10)
+}
+
+func (s *SpanExporter) start(name string) *Span {
+	span := &Span{
+		TraceID:           s.traceID,
+		SpanID:            randomID(8),
+		Name:              name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: s.timestamp(),
+	}
+	if len(s.open) > 0 {
+		span.ParentSpanID = s.open[len(s.open)-1].SpanID
+	}
+
+	s.open = append(s.open, span)
+	return span
+}
+
+func (s *SpanExporter) end(result object.Object) {
+	if len(s.open) == 0 {
+		return
+	}
+	span := s.open[len(s.open)-1]
+	s.open = s.open[:len(s.open)-1]
+
+	span.EndTimeUnixNano = s.timestamp()
+	if err, ok := result.(object.Error); ok {
+		span.Status = Status{Code: StatusError, Message: err.Message}
+	}
+
+	s.batch = append(s.batch, *span)
+	if len(s.batch) >= SpanBatchSize {
+		s.flush()
+	}
+}
+
+func (s *SpanExporter) event(name string, attributes ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.open) == 0 {
+		return
+	}
+	span := s.open[len(s.open)-1]
+	span.Events = append(span.Events, SpanEvent{TimeUnixNano: s.timestamp(), Name: name, Attributes: attributes})
+}
+
+func (s *SpanExporter) Enter(node ast.Node) {
+	if call, ok := node.(ast.Call); ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.callLines = append(s.callLines, ast.StartToken(call).Line)
+	}
+}
+
+func (s *SpanExporter) Exit(node ast.Node, result object.Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch node.(type) {
+	case ast.Call:
+		if len(s.callLines) > 0 {
+			s.callLines = s.callLines[:len(s.callLines)-1]
+		}
+	case *ast.Program:
+		if err, ok := result.(object.Error); ok && len(s.open) > 0 {
+			s.open[0].Status = Status{Code: StatusError, Message: err.Message}
+		}
+	}
+}
+
+func (s *SpanExporter) Call(function string, args []object.Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	span := s.start(function)
+	span.Attributes = append(span.Attributes,
+		stringAttribute("code.function", function),
+		intAttribute("monkey.arguments", len(args)),
+	)
+	if len(s.callLines) > 0 {
+		span.Attributes = append(span.Attributes, intAttribute("code.lineno", s.callLines[len(s.callLines)-1]))
+	}
+}
+
+func (s *SpanExporter) Return(function string, result object.Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.end(result)
+}
+
+func (s *SpanExporter) Builtin(name string, args []object.Object, result object.Object) {
+	s.event("builtin "+name,
+		intAttribute("monkey.arguments", len(args)),
+		stringAttribute("monkey.result", evaluator.Inspect(result)),
+	)
+}
+
+func (s *SpanExporter) Error(err object.Error, node ast.Node) {
+	s.event("exception",
+		stringAttribute("exception.message", err.Message),
+		intAttribute("code.lineno", ast.StartToken(node).Line),
+	)
+}
+
+// Err returns the first error writing spans failed with. Writing stops
+// after it.
+func (s *SpanExporter) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// flush writes the ended spans as a line of JSON. It and the other
+// unexported methods are called with mu held.
+func (s *SpanExporter) flush() {
+	if s.err != nil || len(s.batch) == 0 {
+		return
+	}
+
+	service := "monkey"
+	document := map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{
+				"attributes": []Attribute{{Key: "service.name", Value: Value{StringValue: &service}}},
+			},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]string{"name": "monkey"},
+				"spans": s.batch,
+			}},
+		}},
+	}
+
+	s.err = s.encoder.Encode(document)
+	s.batch = s.batch[:0]
+}
+
+// Close ends the spans still open, the program's at least, and writes the
+// spans not written yet.
+func (s *SpanExporter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for len(s.open) > 0 {
+		s.end(evaluator.NULL)
+	}
+	s.flush()
+	return s.err
+}
@@ -0,0 +1,120 @@
+package test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/benchmark"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+const benchmarksPath = "testdata/bench/benchmarks.monkey"
+
+func readBenchmarks(b *testing.B) string {
+	data, err := os.ReadFile(benchmarksPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBenchmarkLoad(t *testing.T) {
+	benchmarks, err := benchmark.Load(`let fib = fn(n) { if (n < 2) { return n } return fib(n - 1) + fib(n - 2) }
+let benchFib = fn() { return fib(5) }
+let benchWithParameter = fn(n) { n }
+let benchNotAFunction = 1
+let helper = fn() { 1 }
+let benchFails = fn() { 1 + true }
+`)
+	assert.NoError(t, err)
+
+	var names []string
+	for _, b := range benchmarks {
+		names = append(names, b.Name)
+	}
+	assert.Equal(t, []string{"benchFib", "benchFails"}, names)
+	assert.Equal(t, 2, benchmarks[0].Line)
+	assert.Equal(t, object.Integer{Value: 5}, benchmarks[0].Call())
+
+	_, err = benchmark.Load("let x = ")
+	assert.ErrorContains(t, err, "syntax errors")
+
+	_, err = benchmark.Load("let x = 1 + true")
+	assert.ErrorContains(t, err, "evaluating the file")
+}
+
+func TestBenchmarkRun(t *testing.T) {
+	benchmarks, err := benchmark.Load(`let benchSum = fn() { let i = 0; while (i < 10) { i = i + 1 } i }
+let benchFails = fn() { 1 + true }
+`)
+	assert.NoError(t, err)
+
+	passed := benchmark.Run(benchmarks[0], 10*time.Millisecond)
+	assert.NoError(t, passed.Err)
+	assert.Greater(t, passed.N, 1)
+	assert.GreaterOrEqual(t, passed.Duration, 10*time.Millisecond)
+	assert.Greater(t, passed.NsPerOp(), int64(0))
+
+	failed := benchmark.Run(benchmarks[1], 10*time.Millisecond)
+	assert.ErrorContains(t, failed.Err, "type mismatch")
+	assert.Zero(t, failed.N)
+
+	var report strings.Builder
+	assert.NoError(t, benchmark.WriteText(&report, []benchmark.Result{passed, failed}))
+
+	lines := strings.Split(report.String(), "\n")
+	assert.Regexp(t, `^benchSum +\d+ +\d+ ns/op +\d+ B/op +\d+ allocs/op$`, lines[0])
+	assert.Equal(t, "--- FAIL: benchFails (line 2)", lines[1])
+	assert.Contains(t, lines[2], "type mismatch")
+}
+
+func BenchmarkLexer(b *testing.B) {
+	input := readBenchmarks(b)
+	b.SetBytes(int64(len(input)))
+
+	for b.Loop() {
+		l := lexer.New(input)
+		for l.NextToken().Type != token.EOF {
+		}
+	}
+}
+
+func BenchmarkParser(b *testing.B) {
+	input := readBenchmarks(b)
+	b.SetBytes(int64(len(input)))
+
+	for b.Loop() {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			b.Fatal(p.Errors())
+		}
+	}
+}
+
+// BenchmarkEval runs the benchmark functions of the benchmark script, the
+// same workloads as `monkey bench testdata/bench`.
+func BenchmarkEval(b *testing.B) {
+	benchmarks, err := benchmark.Load(readBenchmarks(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bench := range benchmarks {
+		b.Run(strings.TrimPrefix(bench.Name, benchmark.Prefix), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if evaluated := bench.Call(); evaluated.Type() == object.ErrorType {
+					b.Fatal(evaluated)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+)
+
+func TestBindLocals(t *testing.T) {
+	program := resolver.Bind(getProgram(t, `
+let g = 1;
+let f = fn(a, b) {
+    let c = a + b;
+    if (c > g) {
+        return c;
+    }
+    let h = fn() { if (true) { let d = c; return d + a; } };
+    return h();
+};
+`))
+
+	fn := program.Statements[1].(ast.LetStatement).Value.(ast.Function)
+	assert.Equal(t, []string{"a", "b"}, fn.Scope.Names)
+	assert.Equal(t, []string{"c", "h"}, fn.Body.Scope.Names)
+
+	// let c = a + b
+	let := fn.Body.Statements[0].(ast.LetStatement)
+	assert.Equal(t, &ast.Local{Depth: 0, Slot: 0}, let.Name.Local)
+	sum := let.Value.(ast.Infix)
+	assert.Equal(t, &ast.Local{Depth: 1, Slot: 0}, sum.Left.(ast.Identifier).Local)
+	assert.Equal(t, &ast.Local{Depth: 1, Slot: 1}, sum.Right.(ast.Identifier).Local)
+
+	// The block of the if binds nothing, so it has no environment, and g is
+	// a global.
+	branches := fn.Body.Statements[1].(ast.ExpressionStatement).Expression.(ast.If)
+	condition := branches.Conditions[0].(ast.Infix)
+	assert.Nil(t, condition.Right.(ast.Identifier).Local)
+	assert.Empty(t, branches.Consequences[0].Scope.Names)
+	result := branches.Consequences[0].Statements[0].(ast.ReturnStatement).Value
+	assert.Equal(t, &ast.Local{Depth: 0, Slot: 0}, result.(ast.Identifier).Local)
+
+	// In h: the block of the if, h's body and parameters, then f's body.
+	h := fn.Body.Statements[2].(ast.LetStatement).Value.(ast.Function)
+	inner := h.Body.Statements[0].(ast.ExpressionStatement).Expression.(ast.If).Consequences[0]
+	assert.Equal(t, []string{"d"}, inner.Scope.Names)
+	d := inner.Statements[0].(ast.LetStatement).Value.(ast.Identifier)
+	assert.Equal(t, &ast.Local{Depth: 2, Slot: 0}, d.Local)
+	a := inner.Statements[1].(ast.ReturnStatement).Value.(ast.Infix).Right.(ast.Identifier)
+	assert.Equal(t, &ast.Local{Depth: 3, Slot: 0}, a.Local)
+}
+
+func TestBindPreservesSemantics(t *testing.T) {
+	inputs := []string{
+		"let f = fn(x) { let y = x * 2; return y + 1 }; log(f(3))",
+		"let x = 1; if (true) { let x = 2; log(x) }; log(x)",
+		"let f = fn(x) { x = 3; return x }; let x = 1; log(f(2), x)",
+		"let f = fn(x, x) { return x }; log(f(1, 2))",
+		"let counter = fn() { let n = 0; return fn() { n = n + 1; return n } }; let c = counter(); c(); log(c())",
+		"let f = fn() { return g() }; let g = fn() { return 42 }; log(f())",
+		"let f = fn() { let h = fn() { return y }; let r = h(); let y = 2; return [r, h()] }; f()",
+		"let f = fn() { let y = 1; let y = y + 1; return y }; log(f())",
+		"let f = fn(k) { let key = k; return {key: 1}[k] }; log(f(\"a\"))",
+		"let f = fn() { let i = 0; let s = 0; while (i < 5) { let sq = i * i; s = s + sq; i = i + 1 }; return s }; log(f())",
+		"let f = fn(n) { if (n < 1) { return 0 } return n + f(n - 1) }; log(f(50))",
+		"let f = fn(a) { if (a > 0) { let b = a; if (b > 1) { let c = b; return fn() { return a + b + c } } } }; log(f(2)())",
+		"let f = fn() { len = 5; return len }; f()",
+		"let f = fn() { if (true) { len = 5 }; return len([1]) }; f()",
+		"let f = fn() { let l = len; return l([1, 2]) }; f()",
+		"let f = fn() { return z }; f()",
+	}
+
+	for _, input := range inputs {
+		program := getProgram(t, input)
+
+		expected, expectedOutput := evalLogging(program)
+		actual, actualOutput := evalLogging(resolver.Bind(program))
+
+		assert.Equal(t, evaluator.Inspect(expected), evaluator.Inspect(actual), input)
+		assert.Equal(t, expectedOutput, actualOutput, input)
+	}
+}
+
+const fibProgram = `
+let fib = fn(n) {
+    if (n < 2) {
+        return n;
+    }
+    return fib(n - 1) + fib(n - 2);
+};
+fib(25);
+`
+
+const loopProgram = `
+let count = fn(n) {
+    let i = 0;
+    let total = 0;
+    while (i < n) {
+        total = total + i;
+        i = i + 1;
+    }
+    return total;
+};
+count(100000);
+`
+
+func parseBenchmark(b *testing.B, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		b.Fatal(p.Errors())
+	}
+	return program
+}
+
+func benchmarkEval(b *testing.B, program *ast.Program) {
+	for b.Loop() {
+		evaluated := evaluator.Eval(program, object.NewEnvironment())
+		if evaluated.Type() == object.ErrorType {
+			b.Fatal(evaluated)
+		}
+	}
+}
+
+func BenchmarkFibByName(b *testing.B) {
+	benchmarkEval(b, parseBenchmark(b, fibProgram))
+}
+
+func BenchmarkFibBound(b *testing.B) {
+	benchmarkEval(b, resolver.Bind(parseBenchmark(b, fibProgram)))
+}
+
+func BenchmarkWhileByName(b *testing.B) {
+	benchmarkEval(b, parseBenchmark(b, loopProgram))
+}
+
+func BenchmarkWhileBound(b *testing.B) {
+	benchmarkEval(b, resolver.Bind(parseBenchmark(b, loopProgram)))
+}
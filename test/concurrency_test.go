@@ -0,0 +1,273 @@
+package test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+)
+
+func TestParsedConcurrency(t *testing.T) {
+	program := getProgram(t, "let t = spawn worker(c, 1); select { [a, b] = c.receive() => a + b, c.send(1) => 0, _ => wait(t) }")
+	assert.Len(t, program.Statements, 2)
+
+	spawn, ok := program.Statements[0].(ast.LetStatement).Value.(ast.Spawn)
+	assert.True(t, ok)
+	assert.Equal(t, "spawn call fn worker with args ([c 1])", spawn.String())
+
+	sel, ok := program.Statements[1].(ast.ExpressionStatement).Expression.(ast.Select)
+	assert.True(t, ok)
+	if assert.Len(t, sel.Cases, 3) {
+		assert.Equal(t, "[a, b]", sel.Cases[0].Pattern.String())
+		assert.Equal(t, "c", sel.Cases[0].Channel().String())
+		assert.False(t, sel.Cases[0].Sends())
+		assert.Nil(t, sel.Cases[1].Pattern)
+		assert.True(t, sel.Cases[1].Sends())
+		assert.Nil(t, sel.Cases[2].Operation)
+	}
+	assert.Equal(t, "select {[a, b] = call fn (c).receive with args ([]) => a + b, call fn (c).send with args ([1]) => 0, _ => call fn wait with args ([t])}", sel.String())
+}
+
+func TestConcurrencySyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"select { c.close() => 1 }", "expected a select case to receive from or send to a channel, got call fn (c).close with args ([]) instead"},
+		{"select { x = c.send(1) => 1 }", "expected a select case to receive from or send to a channel, got call fn (c).send with args ([1]) instead"},
+		{"select { c.receive(1) => 1 }", "expected a select case to receive from or send to a channel, got call fn (c).receive with args ([1]) instead"},
+		{"select { c => 1 }", "expected a select case to receive from or send to a channel, got c instead"},
+		{"select { c.receive() }", "expected next token to be '=>', got } instead"},
+		{"select { c.receive() => 1 c.receive() => 2 }", "expected next token to be '}', got IDENT instead"},
+		{"spawn", "parse function for token type 'EOF' is not implemented"},
+		{"let g = fn*() { spawn fn() { yield 1 } }", "yield outside of a generator"},
+	}
+
+	for _, test := range tests {
+		testSyntaxError(t, test.input, test.expected)
+	}
+}
+
+// testScheduled evaluates a program, as written and bound, with tasks run
+// by a scheduler seeded with seed, and returns what both evaluate to.
+func testScheduled(t *testing.T, input string, seed uint64) (object.Object, object.Object) {
+	t.Helper()
+
+	evaluate := func(program *ast.Program) object.Object {
+		env := object.NewEnvironment()
+		scheduler := evaluator.NewScheduler(seed)
+		evaluator.Schedule(env, scheduler)
+		defer scheduler.Stop()
+		return evaluator.Eval(program, env)
+	}
+	return evaluate(getProgram(t, input)), evaluate(resolver.Bind(getProgram(t, input)))
+}
+
+func TestEvaluatedConcurrency(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// A spawned task runs once the one spawning it blocks.
+		{`let order = []; let c = channel();
+let t = spawn fn() { order.push("task"); c.send(1); order.push("sent"); return 2 };
+order.push("main");
+let v = c.receive();
+order.push("received");
+[v, wait(t), order]`, `[1, 2, ["main", "task", "sent", "received"]]`},
+		{"let c = channel(2); c.send(1); c.send(2); [c.receive(), c.receive()]", "[1, 2]"},
+		{`let c = channel(1); let log = [];
+spawn fn() { c.send(1); log.push("one"); c.send(2); log.push("two") };
+[c.receive(), c.receive(), log]`, `[1, 2, ["one", "two"]]`},
+		{"let c = channel(); spawn fn() { for (i in [1, 2, 3]) { c.send(i) }; c.close() }; collect(c)", "[1, 2, 3]"},
+		{"let c = channel(); let s = 0; spawn fn() { c.send(1); c.send(2); c.close() }; for (x in c) { s = s + x }; s", "3"},
+		{"let c = channel(); c.close(); [c.receive(), collect(c)]", "[null, []]"},
+		{"let c = channel(2); c.send(1); c.close(); [c.receive(), c.receive()]", "[1, null]"},
+		{"let c = channel(); let t = spawn fn() { return c.receive() }; spawn fn() { c.close() }; wait(t)", "null"},
+		{"wait([spawn fn() { return 1 }, spawn fn(x) { return x }(2)])", "[1, 2]"},
+		{"wait([])", "[]"},
+		{`wait(spawn len("abc"))`, "3"},
+		{"let a = [1]; wait(spawn a.push(2)); a", "[1, 2]"},
+		{"let t = spawn fn() { return 1 }; [wait(t), wait(t)]", "[1, 1]"},
+		{"let x = 1; wait(spawn fn() { x = 2 }); x", "2"},
+		{`let c = channel(); select { v = c.receive() => v, _ => "empty" }`, `"empty"`},
+		{"let a = channel(1); let b = channel(1); a.send(1); b.send(2); select { x = a.receive() => x, y = b.receive() => y }", "1"},
+		{`let c = channel(1); select { c.send(5) => "sent" }; c.receive()`, "5"},
+		{"let c = channel(); spawn fn() { c.send(3) }; select { x = c.receive() => x * 2 }", "6"},
+		{"let c = channel(1); c.send([1, 2]); select { [a, b] = c.receive() => a + b }", "3"},
+		{"let c = channel(); c.close(); select { x = c.receive() => x }", "null"},
+		// Names bound by a case are scoped to its result.
+		{"let x = 0; let c = channel(1); c.send(1); select { x = c.receive() => x }; x", "0"},
+		{"let c = channel(); [c, spawn fn() { }]", "[channel, task]"},
+		{"let c = channel(); [c == c, channel() == channel(), copy(c) == c]", "[true, false, true]"},
+	}
+
+	for _, test := range tests {
+		evaluated, bound := testScheduled(t, test.input, 0)
+		assert.Equal(t, test.expected, evaluator.Inspect(evaluated), test.input)
+		assert.Equal(t, test.expected, evaluator.Inspect(bound), test.input)
+	}
+}
+
+func TestConcurrencyErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let c = channel(); c.receive()", "deadlock: all tasks are blocked"},
+		{"let c = channel(); c.send(1)", "deadlock: all tasks are blocked"},
+		{"let c = channel(); wait(spawn fn() { return c.receive() })", "deadlock: all tasks are blocked"},
+		{"select { }", "deadlock: all tasks are blocked"},
+		{"let c = channel(); c.close(); c.send(1)", "send on a closed channel"},
+		{"let c = channel(); c.close(); c.close()", "close of a closed channel"},
+		{"let c = channel(); let t = spawn fn() { c.send(1) }; spawn fn() { c.close() }; wait(t)", "send on a closed channel"},
+		{"let c = channel(); c.close(); select { c.send(1) => 1 }", "send on a closed channel"},
+		{"channel(-1)", "channel capacity is negative: -1"},
+		{`channel("a")`, "argument type is not supported: got STRING"},
+		{"wait(1)", "cannot wait for INTEGER: not a task"},
+		{"wait([spawn fn() { }, 1])", "cannot wait for INTEGER: not a task"},
+		{"spawn 1", "cannot spawn INTEGER: not a function"},
+		{"spawn missing()", "identifier not found: missing"},
+		{"select { x = (1).receive() => x }", "cannot select on INTEGER: not a channel"},
+		{"wait(spawn fn() { return missing })", "identifier not found: missing"},
+		{"wait([spawn fn() { return 1 }, spawn fn(x) { return x }()])", "wrong number of arguments: got=0, want=1"},
+		{"let c = channel(1); c.send(1); select { [a] = c.receive() => a }", "1:41: expected an array, got 1"},
+	}
+
+	for _, test := range tests {
+		evaluated, bound := testScheduled(t, test.input, 0)
+		testErrorObject(t, evaluated, test.expected)
+		testErrorObject(t, bound, test.expected)
+	}
+}
+
+// Tasks ready at the same time run in an order drawn from the seed of the
+// scheduler: the same on every run, and different for some seeds.
+func TestSchedulerIsReproducible(t *testing.T) {
+	input := `
+let order = [];
+let c = channel();
+let tasks = [];
+for (id in [1, 2, 3, 4]) {
+    tasks.push(spawn fn() { order.push(id); c.send(id); order.push(id * 10) });
+}
+let received = [c.receive(), c.receive(), c.receive(), c.receive()];
+wait(tasks);
+[order, received]`
+
+	evaluated, _ := testScheduled(t, input, 0)
+	assert.Equal(t, "[[1, 10, 2, 3, 4, 20, 30, 40], [1, 2, 3, 4]]", evaluator.Inspect(evaluated))
+
+	orders := make(map[string]bool)
+	for seed := uint64(1); seed <= 10; seed++ {
+		first, bound := testScheduled(t, input, seed)
+		second, _ := testScheduled(t, input, seed)
+		assert.Equal(t, evaluator.Inspect(first), evaluator.Inspect(second), "seed %d", seed)
+		assert.Equal(t, evaluator.Inspect(first), evaluator.Inspect(bound), "seed %d", seed)
+		orders[evaluator.Inspect(first)] = true
+	}
+	assert.Greater(t, len(orders), 1)
+}
+
+// Without a scheduler, tasks run in parallel and share the environments
+// their functions were defined in.
+func TestTasksRunInParallel(t *testing.T) {
+	input := `
+let last = 0;
+let results = channel(4);
+let worker = fn(n) {
+    let sum = 0;
+    let i = 0;
+    while (i < n) {
+        i = i + 1;
+        sum = sum + i;
+        last = i;
+    }
+    results.send(n);
+    return sum;
+};
+let tasks = [];
+for (n in [10, 20, 30, 40, 50, 60, 70, 80]) {
+    tasks.push(spawn worker(n));
+}
+let received = 0;
+for (_ in tasks) {
+    received = received + results.receive();
+}
+let total = wait(tasks).reduce(fn(a, b) { return a + b }, 0);
+[total, received, last > 0]`
+
+	for range 5 {
+		assert.Equal(t, "[10380, 360, true]", evaluator.Inspect(testEvalWithError(t, input)))
+	}
+}
+
+// Tasks running in parallel can push to, assign to and read the same
+// arrays, hash tables and structs. Run with -race, nothing of it races.
+func TestTasksShareCollections(t *testing.T) {
+	input := `
+struct Counter { value }
+let items = [];
+let seen = {};
+let counter = Counter{value: 0};
+let worker = fn(name) {
+    let i = 0;
+    while (i < 200) {
+        i = i + 1;
+        items.push(i);
+        seen[name] = i;
+        seen["last"] = i;
+        counter.value = i;
+        let read = [len(items), items[0], seen.keys(), counter, copy(items) == items];
+    }
+    return i;
+};
+let tasks = [spawn worker("a"), spawn worker("b"), spawn worker("c")];
+let read = [items.map(fn(x) { return x }), seen.values(), counter.value];
+wait(tasks);
+[len(items), seen["a"], seen["b"], seen["c"], seen["last"], counter.value]`
+
+	for range 5 {
+		assert.Equal(t, "[600, 200, 200, 200, 200, 200]", evaluator.Inspect(testEvalWithError(t, input)))
+	}
+}
+
+// Stopping a scheduler ends the tasks the program left behind, blocked or
+// not started, so that no goroutine outlives it.
+func TestStoppedTasksEnd(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	env := object.NewEnvironment()
+	scheduler := evaluator.NewScheduler(0)
+	evaluator.Schedule(env, scheduler)
+	evaluator.Eval(getProgram(t, `
+let c = channel();
+let started = channel(1);
+let blocked = spawn fn() { started.send(true); return c.receive() };
+started.receive();
+let idle = spawn fn() { return 1 };
+`), env)
+
+	scheduler.Stop()
+	testErrorObject(t, testWait(t, env, "blocked"), "task stopped")
+	testErrorObject(t, testWait(t, env, "idle"), "task stopped")
+
+	for range 100 {
+		if runtime.NumGoroutine() <= goroutines {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
+
+func testWait(t *testing.T, env *object.Environment, name string) object.Object {
+	t.Helper()
+	return evaluator.Eval(getProgram(t, "wait("+name+")"), env)
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the conformance suite")
+
+const conformanceDir = "testdata/conformance"
+
+// buildMonkey builds the monkey command into a temporary directory.
+func buildMonkey(t *testing.T) string {
+	binary := filepath.Join(t.TempDir(), "monkey")
+
+	output, err := exec.Command("go", "build", "-o", binary, "../cmd").CombinedOutput()
+	if err != nil {
+		t.Fatalf("building monkey: %v\n%s", err, output)
+	}
+	return binary
+}
+
+// runScript runs monkey with the arguments, a script in the conformance
+// directory last, and describes what it printed to stdout and stderr and
+// its exit status.
+func runScript(t *testing.T, binary string, args ...string) string {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = conformanceDir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	status := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatal(err)
+		}
+		status = exitErr.ExitCode()
+	}
+
+	return fmt.Sprintf("-- stdout --\n%s-- stderr --\n%s-- exit status --\n%d\n", stdout.String(), stderr.String(), status)
+}
+
+// TestConformance runs every script in testdata/conformance and compares
+// its output with the golden file next to it. Run with -update to rewrite
+// the golden files after an intended change of behavior.
+func TestConformance(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join(conformanceDir, "*.monkey"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, scripts)
+
+	binary := buildMonkey(t)
+
+	for _, script := range scripts {
+		name := filepath.Base(script)
+
+		t.Run(strings.TrimSuffix(name, ".monkey"), func(t *testing.T) {
+			actual := runScript(t, binary, name)
+			golden := strings.TrimSuffix(script, ".monkey") + ".golden"
+
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, []byte(actual), 0o644))
+				return
+			}
+
+			assert.Equal(t, readFile(t, golden), actual)
+		})
+	}
+}
+
+// monkey run --deterministic fails a script whose tasks are all blocked
+// instead of hanging, and runs the others as they run without it.
+func TestRunDeterministic(t *testing.T) {
+	binary := buildMonkey(t)
+
+	deadlock := filepath.Join(t.TempDir(), "deadlock.monkey")
+	assert.NoError(t, os.WriteFile(deadlock, []byte("let c = channel();\nc.receive()\n"), 0o644))
+	assert.Equal(t, "-- stdout --\n-- stderr --\nERROR: deadlock: all tasks are blocked\n-- exit status --\n1\n",
+		runScript(t, binary, "run", "--deterministic", deadlock))
+
+	golden := readFile(t, filepath.Join(conformanceDir, "concurrency.golden"))
+	for _, seed := range []string{"0", "7"} {
+		assert.Equal(t, golden, runScript(t, binary, "run", "--deterministic", "--seed", seed, "concurrency.monkey"), seed)
+	}
+}
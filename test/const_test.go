@@ -0,0 +1,154 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+)
+
+func TestConstantAssignmentsAreParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []parser.Error
+	}{
+		{"const x = 1; x = 2", []parser.Error{{Message: "cannot assign to constant: x", Line: 1, Column: 14}}},
+		{"const x = 1\nconst x = 2", []parser.Error{{Message: "cannot assign to constant: x", Line: 2, Column: 7}}},
+		{"const x = 1\nlet x = 2", []parser.Error{{Message: "cannot assign to constant: x", Line: 2, Column: 5}}},
+		{"let x = 1\nconst x = 2", []parser.Error{{Message: "cannot declare constant x: already declared", Line: 2, Column: 7}}},
+		// Functions may assign to constants declared after them.
+		{"let f = fn() { x = 2 }\nconst x = 1", []parser.Error{{Message: "cannot assign to constant: x", Line: 1, Column: 16}}},
+		{"let f = fn() { const x = 1; if (true) { x = 2 } }", []parser.Error{{Message: "cannot assign to constant: x", Line: 1, Column: 41}}},
+		{"const [x, ...xs] = [1]; xs = 2", []parser.Error{{Message: "cannot assign to constant: xs", Line: 1, Column: 25}}},
+		{"const x = 1; let [x] = [2]", []parser.Error{{Message: "cannot assign to constant: x", Line: 1, Column: 19}}},
+		{"const x = 1; let f = fn(x) { x = 2 }", nil},
+		{"const x = 1; let f = fn([x]) { x = 2 }", nil},
+		{"const x = 1; let f = fn() { let x = 2; x = 3 }", nil},
+		{"let f = fn() { const x = 1; return x }; f(); f()", nil},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		p.ParseProgram()
+		assert.Equal(t, test.expected, p.Errors(), test.input)
+	}
+}
+
+func TestConstantAssignmentsFailAtRuntime(t *testing.T) {
+	// Programs evaluated one after another, like the lines of the REPL,
+	// are parsed separately.
+	tests := []struct {
+		inputs   []string
+		expected string
+	}{
+		{[]string{"const x = 1", "x = 2"}, "cannot assign to constant: x"},
+		{[]string{"const x = 1", "let x = 2"}, "cannot assign to constant: x"},
+		{[]string{"const x = 1", "const x = 2"}, "cannot assign to constant: x"},
+		{[]string{"const x = 1", "let f = fn() { x = 2 }", "f()"}, "cannot assign to constant: x"},
+		{[]string{"const x = 1", "if (true) { x = 2 }"}, "cannot assign to constant: x"},
+	}
+
+	for _, test := range tests {
+		for _, bind := range []bool{false, true} {
+			env := object.NewEnvironment()
+
+			var evaluated object.Object
+			for _, input := range test.inputs {
+				program := getProgram(t, input)
+				if bind {
+					program = resolver.Bind(program)
+				}
+				evaluated = evaluator.Eval(program, env)
+			}
+
+			testErrorObject(t, evaluated, test.expected)
+			x, _ := env.Get("x")
+			assert.Equal(t, object.Integer{Value: 1}, x, test.inputs)
+		}
+	}
+}
+
+func TestConstantsInFunctionsAndBlocks(t *testing.T) {
+	input := `
+let f = fn(n) {
+    const double = n * 2
+    let total = 0
+    let i = 0
+    while (i < 3) {
+        const step = double + i
+        total = total + step
+        i = i + 1
+    }
+    return total
+}
+f(1) + f(2)
+`
+	testIntegerObject(t, testEvalWithError(t, input), 24)
+	testIntegerObject(t, evaluator.Eval(resolver.Bind(getProgram(t, input)), object.NewEnvironment()), 24)
+}
+
+func TestConstantsDefinedByHosts(t *testing.T) {
+	config := evaluator.Freeze(&object.HashTable{Items: map[string]object.Object{
+		"limit": object.Integer{Value: 10},
+	}})
+	env := object.NewEnvironment()
+	assert.True(t, env.DefineConstant("config", config))
+
+	tests := []string{
+		`config = {}`,
+		`if (true) { config = {} }`,
+		`let steal = fn() { config = {} }; steal()`,
+		`config["limit"] = 100`,
+	}
+
+	// Snippets may shadow the constant with their own binding, but cannot
+	// change it.
+	for _, input := range tests {
+		snippet := object.NewEnclosedEnvironment(env)
+		evaluated := evaluator.Eval(resolver.Bind(getProgram(t, input)), snippet)
+		assert.Equal(t, object.ErrorType, evaluated.Type(), input)
+	}
+
+	assert.False(t, env.Define("config", object.Integer{Value: 1}))
+	assert.False(t, env.Set("config", object.Integer{Value: 1}))
+
+	value, _ := env.Get("config")
+	assert.Equal(t, config, value)
+	assert.Equal(t, `{"limit": 10}`, evaluator.Inspect(value))
+}
+
+func TestFreeze(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`let a = freeze([1, 2]); a[0] = 5`, "cannot assign to an index of a frozen array"},
+		{`let h = freeze({"a": 1}); h["a"] = 5`, "cannot assign to a key of a frozen hash table"},
+		{`let h = freeze({"a": 1}); h["b"] = 5`, "cannot assign to a key of a frozen hash table"},
+		{`let h = freeze({"a": [1, {"b": 2}]}); h["a"][1]["b"] = 5`, "cannot assign to a key of a frozen hash table"},
+		{`let h = freeze({"a": [1, {"b": 2}]}); h["a"][0] = 5`, "cannot assign to an index of a frozen array"},
+		// Freezing copies, the original can still be changed.
+		{`let a = [1, 2]; let b = freeze(a); a[0] = 5; a[0] + b[0]`, 6},
+		// Values derived from frozen ones are not frozen.
+		{`let a = freeze([1, 2]); let b = append(a, 3); b[0] = 5; b[0] + a[0]`, 6},
+		{`let a = freeze([[1]]); let b = append(a, [2]); b[0][0] = 5`, "cannot assign to an index of a frozen array"},
+		{`freeze(5)`, 5},
+		{`let a = [1, 2]; a[1] = a; let b = freeze(a); len(b[1][1][1])`, 2},
+		{`let a = [1, 2]; a[1] = a; let b = freeze(a); b[1][1][0] = 2`, "cannot assign to an index of a frozen array"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(t, test.input)
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			testErrorObject(t, evaluated, expected)
+		}
+	}
+}
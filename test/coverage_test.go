@@ -0,0 +1,85 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/coverage"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/testrunner"
+)
+
+const coveredTests = `let classify = fn(n) {
+    if (n < 0) {
+        return "negative";
+    } else if (n == 0) {
+        return "zero";
+    }
+    return "positive";
+};
+let unused = fn() {
+    return 1;
+};
+let testPositive = fn() {
+    assertEqual("positive", classify(5));
+};
+let testNegative = fn() {
+    assertEqual("negative", classify(-1));
+};
+`
+
+func coverTests(t *testing.T) *coverage.Profile {
+	profile := coverage.New()
+	profile.AddFile("classify_test.monkey", coveredTests)
+
+	results := testrunner.Run(
+		[]testrunner.File{{Path: "classify_test.monkey", Source: coveredTests}},
+		testrunner.Options{
+			Parallel: 2,
+			Prepare: func(test testrunner.Test, env *object.Environment) {
+				profile.Attach(test.File, env)
+			},
+		},
+	)
+	assert.Equal(t, 0, testrunner.Failed(results))
+
+	return profile
+}
+
+func TestCoverageCounts(t *testing.T) {
+	files := coverTests(t).Files()
+	assert.Len(t, files, 1)
+
+	lines := files[0].Lines()
+	assert.Equal(t, 2, lines[2])
+	assert.Equal(t, 1, lines[3])
+	assert.Equal(t, 0, lines[5])
+	assert.Equal(t, 0, lines[10])
+
+	assert.Equal(t, []int{1, 0, 1}, files[0].Branches[coverage.Position{Line: 2, Column: 5}])
+
+	statements, statementsHit, branches, branchesHit := files[0].Summary()
+	assert.Equal(t, []int{11, 9, 3, 2}, []int{statements, statementsHit, branches, branchesHit})
+}
+
+func TestCoverageReports(t *testing.T) {
+	profile := coverTests(t)
+
+	var lcov strings.Builder
+	assert.NoError(t, profile.WriteLcov(&lcov))
+	assert.True(t, strings.HasPrefix(lcov.String(), "TN:\nSF:classify_test.monkey\n"))
+	for _, record := range []string{"BRDA:2,0,1,0\n", "BRF:3\nBRH:2\n", "DA:5,0\n", "LF:11\nLH:9\nend_of_record\n"} {
+		assert.Contains(t, lcov.String(), record)
+	}
+
+	var summary strings.Builder
+	assert.NoError(t, profile.WriteSummary(&summary))
+	assert.Equal(t, "classify_test.monkey: 81.8% of statements, 66.7% of branches\n", summary.String())
+
+	var html strings.Builder
+	assert.NoError(t, profile.WriteHTML(&html))
+	assert.Contains(t, html.String(), `<tr class="partial"><td class="number">2</td><td class="hits">2</td>`)
+	assert.Contains(t, html.String(), `<tr class="uncovered"><td class="number">5</td><td class="hits">0</td>`)
+}
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/dap"
+)
+
+const dapSource = `let items = [1, [2, 3]];
+let add = fn(a, b) {
+    let sum = a + b;
+    return sum;
+};
+let total = add(1, 2);
+total + "x";
+`
+
+func startDapServer(t *testing.T) (*dap.Client, string) {
+	path := filepath.Join(t.TempDir(), "main.monkey")
+	assert.NoError(t, os.WriteFile(path, []byte(dapSource), 0o644))
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- dap.NewServer().Serve(serverIn, serverOut)
+		_ = serverOut.Close()
+	}()
+
+	client := dap.NewClient(clientIn, clientOut)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Call("disconnect", map[string]any{}, nil))
+		assert.NoError(t, <-done)
+	})
+
+	var capabilities dap.Capabilities
+	assert.NoError(t, client.Call("initialize", dap.InitializeArguments{ClientID: "test"}, &capabilities))
+	assert.True(t, capabilities.SupportsConfigurationDoneRequest)
+	assert.NoError(t, client.Event("initialized", nil))
+
+	return client, path
+}
+
+func TestDapBreakpointsAndStepping(t *testing.T) {
+	client, path := startDapServer(t)
+
+	assert.NoError(t, client.Call("launch", dap.LaunchArguments{Program: path}, nil))
+
+	var breakpoints dap.SetBreakpointsResponseBody
+	assert.NoError(t, client.Call("setBreakpoints", dap.SetBreakpointsArguments{
+		Source:      dap.Source{Path: path},
+		Breakpoints: []dap.SourceBreakpoint{{Line: 3, Condition: "b > 1"}},
+	}, &breakpoints))
+	assert.Len(t, breakpoints.Breakpoints, 1)
+	assert.True(t, breakpoints.Breakpoints[0].Verified)
+	assert.NoError(t, client.Call("configurationDone", nil, nil))
+
+	var stopped dap.StoppedEventBody
+	assert.NoError(t, client.Event("stopped", &stopped))
+	assert.Equal(t, "breakpoint", stopped.Reason)
+	assert.Equal(t, []int{breakpoints.Breakpoints[0].ID}, stopped.HitBreakpointIDs)
+
+	var trace dap.StackTraceResponseBody
+	assert.NoError(t, client.Call("stackTrace", dap.StackTraceArguments{ThreadID: 1}, &trace))
+	assert.Equal(t, 2, trace.TotalFrames)
+	assert.Equal(t, "add", trace.StackFrames[0].Name)
+	assert.Equal(t, 3, trace.StackFrames[0].Line)
+	assert.Equal(t, 5, trace.StackFrames[0].Column)
+	assert.Equal(t, path, trace.StackFrames[0].Source.Path)
+	assert.Equal(t, "main", trace.StackFrames[1].Name)
+	assert.Equal(t, 6, trace.StackFrames[1].Line)
+
+	var scopes dap.ScopesResponseBody
+	assert.NoError(t, client.Call("scopes", dap.ScopesArguments{FrameID: trace.StackFrames[0].ID}, &scopes))
+	assert.Equal(t, "Local", scopes.Scopes[0].Name)
+	assert.Equal(t, "Global", scopes.Scopes[1].Name)
+
+	var locals dap.VariablesResponseBody
+	assert.NoError(t, client.Call("variables", dap.VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}, &locals))
+	assert.Equal(t, []dap.Variable{
+		{Name: "a", Value: "1", Type: "INTEGER"},
+		{Name: "b", Value: "2", Type: "INTEGER"},
+	}, locals.Variables)
+
+	var globals dap.VariablesResponseBody
+	assert.NoError(t, client.Call("variables", dap.VariablesArguments{VariablesReference: scopes.Scopes[1].VariablesReference}, &globals))
+	assert.Equal(t, "add", globals.Variables[0].Name)
+	assert.Equal(t, "fn(a, b)", globals.Variables[0].Value)
+	assert.Equal(t, "items", globals.Variables[1].Name)
+
+	var items dap.VariablesResponseBody
+	assert.NoError(t, client.Call("variables", dap.VariablesArguments{VariablesReference: globals.Variables[1].VariablesReference}, &items))
+	assert.Equal(t, "[0]", items.Variables[0].Name)
+	assert.Equal(t, "1", items.Variables[0].Value)
+	assert.Equal(t, "ARRAY", items.Variables[1].Type)
+	assert.NotZero(t, items.Variables[1].VariablesReference)
+
+	var evaluated dap.EvaluateResponseBody
+	assert.NoError(t, client.Call("evaluate", dap.EvaluateArguments{Expression: "a * 10 + b", FrameID: 1}, &evaluated))
+	assert.Equal(t, "12", evaluated.Result)
+
+	assert.NoError(t, client.Call("next", dap.ThreadArguments{ThreadID: 1}, nil))
+	assert.NoError(t, client.Event("stopped", &stopped))
+	assert.Equal(t, "step", stopped.Reason)
+	assert.NoError(t, client.Call("stackTrace", dap.StackTraceArguments{ThreadID: 1}, &trace))
+	assert.Equal(t, 4, trace.StackFrames[0].Line)
+
+	assert.NoError(t, client.Call("stepOut", dap.ThreadArguments{ThreadID: 1}, nil))
+	assert.NoError(t, client.Event("stopped", &stopped))
+	assert.NoError(t, client.Call("stackTrace", dap.StackTraceArguments{ThreadID: 1}, &trace))
+	assert.Equal(t, 1, trace.TotalFrames)
+	assert.Equal(t, 7, trace.StackFrames[0].Line)
+
+	assert.Error(t, client.Call("variables", dap.VariablesArguments{VariablesReference: 99}, nil))
+
+	assert.NoError(t, client.Call("continue", dap.ThreadArguments{ThreadID: 1}, nil))
+
+	var output dap.OutputEventBody
+	assert.NoError(t, client.Event("output", &output))
+	assert.Equal(t, "stderr", output.Category)
+	assert.Equal(t, "ERROR: type mismatch: INTEGER + STRING\n", output.Output)
+
+	var exited dap.ExitedEventBody
+	assert.NoError(t, client.Event("exited", &exited))
+	assert.Equal(t, 1, exited.ExitCode)
+	assert.NoError(t, client.Event("terminated", nil))
+}
+
+func TestDapStopOnEntryAndErrors(t *testing.T) {
+	client, path := startDapServer(t)
+
+	assert.NoError(t, client.Call("launch", dap.LaunchArguments{Program: path, StopOnEntry: true}, nil))
+	assert.NoError(t, client.Call("setExceptionBreakpoints", dap.SetExceptionBreakpointsArguments{Filters: []string{"error"}}, nil))
+	assert.NoError(t, client.Call("configurationDone", nil, nil))
+
+	var stopped dap.StoppedEventBody
+	assert.NoError(t, client.Event("stopped", &stopped))
+	assert.Equal(t, "entry", stopped.Reason)
+
+	assert.NoError(t, client.Call("continue", dap.ThreadArguments{ThreadID: 1}, nil))
+
+	assert.NoError(t, client.Event("stopped", &stopped))
+	assert.Equal(t, "exception", stopped.Reason)
+	assert.Equal(t, "type mismatch: INTEGER + STRING", stopped.Text)
+
+	assert.NoError(t, client.Call("terminate", map[string]any{}, nil))
+
+	var exited dap.ExitedEventBody
+	assert.NoError(t, client.Event("exited", &exited))
+	assert.Equal(t, 1, exited.ExitCode)
+}
+
+func TestDapListensOnLoopback(t *testing.T) {
+	for _, address := range []string{":0", "127.0.0.1:0", "localhost:0", "[::1]:0"} {
+		listener, err := dap.Listen(address)
+		if err != nil {
+			// Not every machine has an IPv6 loopback interface.
+			assert.Equal(t, "[::1]:0", address, err)
+			continue
+		}
+		host, _, err := net.SplitHostPort(listener.Addr().String())
+		assert.NoError(t, err)
+		assert.True(t, net.ParseIP(host).IsLoopback(), address)
+		assert.NoError(t, listener.Close())
+	}
+
+	for _, address := range []string{"0.0.0.0:4711", "[::]:4711", "192.0.2.1:4711", "example.com:4711"} {
+		_, err := dap.Listen(address)
+		assert.EqualError(t, err, "cannot listen on "+address+": not a loopback address")
+	}
+	_, err := dap.Listen("4711")
+	assert.Error(t, err)
+}
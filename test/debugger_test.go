@@ -0,0 +1,205 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/debugger"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+const debuggedProgram = `let total = 0;
+let add = fn(a, b) {
+    let sum = a + b;
+    return sum;
+};
+let i = 0;
+while (i < 3) {
+    total = 0 + add(total, i);
+    i = i + 1;
+}
+total + "x";
+`
+
+// stopLocation is where a stop happened, as function:line of each frame.
+func stopLocation(stop debugger.Stop) string {
+	frames := make([]string, len(stop.Stack))
+	for i, frame := range stop.Stack {
+		frames[i] = fmt.Sprintf("%s:%d", frame.Function, frame.Line)
+	}
+	return strings.Join(frames, " ")
+}
+
+// debug runs the program, answering the stops with the actions in turn and
+// recording them, and continues once the actions run out.
+func debug(t *testing.T, d *debugger.Debugger, actions ...debugger.Action) ([]debugger.Stop, object.Object) {
+	var stops []debugger.Stop
+	d.OnStop = func(stop debugger.Stop) debugger.Action {
+		stops = append(stops, stop)
+		if len(actions) == 0 {
+			return debugger.Continue
+		}
+		action := actions[0]
+		actions = actions[1:]
+		return action
+	}
+
+	result := d.Run(getProgram(t, debuggedProgram), object.NewEnvironment())
+	return stops, result
+}
+
+func TestDebuggerStepping(t *testing.T) {
+	d := debugger.New()
+	_, err := d.SetBreakpoint(8, "")
+	assert.NoError(t, err)
+
+	stops, _ := debug(t, d, debugger.StepIn, debugger.StepOver, debugger.StepOut, debugger.StepOver, debugger.Terminate)
+
+	locations := make([]string, len(stops))
+	for i, stop := range stops {
+		locations[i] = stopLocation(stop)
+	}
+
+	assert.Equal(t, []string{
+		"main:8",
+		"add:3 main:8",
+		"add:4 main:8",
+		"main:9",
+		"main:8",
+	}, locations)
+	assert.Equal(t, debugger.BreakpointReason, stops[0].Reason)
+	assert.Equal(t, debugger.StepReason, stops[1].Reason)
+}
+
+func TestDebuggerConditionalBreakpoint(t *testing.T) {
+	d := debugger.New()
+	_, err := d.SetBreakpoint(3, "b > 1")
+	assert.NoError(t, err)
+
+	var scopes []debugger.Scope
+	var value object.Object
+	d.OnStop = func(stop debugger.Stop) debugger.Action {
+		scopes = debugger.Scopes(stop.Stack[0])
+		value, err = d.Evaluate("a + b", stop.Stack[0])
+		return debugger.Terminate
+	}
+	d.Run(getProgram(t, debuggedProgram), object.NewEnvironment())
+
+	assert.NoError(t, err)
+	testIntegerObject(t, value, 3)
+
+	assert.Equal(t, "Local", scopes[0].Name)
+	assert.Equal(t, []debugger.Variable{
+		{Name: "a", Value: object.Integer{Value: 1}},
+		{Name: "b", Value: object.Integer{Value: 2}},
+	}, scopes[0].Variables)
+	assert.Equal(t, "Global", scopes[1].Name)
+
+	_, err = d.SetBreakpoint(1, "a +")
+	assert.Error(t, err)
+}
+
+func TestDebuggerStopOnError(t *testing.T) {
+	d := debugger.New()
+	d.StopOnError = true
+
+	stops, result := debug(t, d)
+	assert.Len(t, stops, 1)
+	assert.Equal(t, debugger.ErrorReason, stops[0].Reason)
+	assert.Equal(t, "type mismatch: INTEGER + STRING", stops[0].Error.Message)
+	assert.Equal(t, "main:11", stopLocation(stops[0]))
+	testErrorObject(t, result, "type mismatch: INTEGER + STRING")
+}
+
+func TestDebuggerTerminate(t *testing.T) {
+	d := debugger.New()
+	d.StopOnEntry = true
+
+	stops, result := debug(t, d, debugger.Terminate)
+	assert.Len(t, stops, 1)
+	assert.Equal(t, debugger.EntryReason, stops[0].Reason)
+	testErrorObject(t, result, debugger.TerminatedMessage)
+}
+
+const tasksProgram = `let work = fn(n) {
+    let doubled = n * 2;
+    return doubled;
+};
+let tasks = [spawn work(1), spawn work(2), spawn work(3)];
+wait(tasks)
+`
+
+// A debugged program runs its tasks one at a time, each stopping at the
+// breakpoints in it. Run with -race, nothing of it races.
+func TestDebuggerOfTasks(t *testing.T) {
+	d := debugger.New()
+	_, err := d.SetBreakpoint(2, "")
+	assert.NoError(t, err)
+
+	var stops []string
+	d.OnStop = func(stop debugger.Stop) debugger.Action {
+		stops = append(stops, stopLocation(stop))
+		return debugger.Continue
+	}
+
+	result := d.Run(getProgram(t, tasksProgram), object.NewEnvironment())
+	assert.Equal(t, "[2, 4, 6]", evaluator.Inspect(result))
+	assert.Len(t, stops, 3)
+	for _, stop := range stops {
+		assert.True(t, strings.HasPrefix(stop, "work:2 task"), stop)
+	}
+
+	// Attached as the hook of an evaluation running tasks in parallel, the
+	// debugger steps through all of them.
+	d = debugger.New()
+	d.StopOnEntry = true
+	var count atomic.Int32
+	d.OnStop = func(debugger.Stop) debugger.Action {
+		count.Add(1)
+		return debugger.StepIn
+	}
+
+	env := object.NewEnvironment()
+	evaluator.Attach(env, d)
+	result = evaluator.Eval(getProgram(t, tasksProgram), env)
+	assert.Equal(t, "[2, 4, 6]", evaluator.Inspect(result))
+	assert.Equal(t, int32(3+3*2), count.Load())
+}
+
+func TestDebuggerConsole(t *testing.T) {
+	commands := strings.Join([]string{
+		"break 3 if a > 0",
+		"continue",
+		"stack",
+		"print a * 10",
+		"vars",
+		"quit",
+	}, "\n")
+
+	var out strings.Builder
+	console := debugger.NewConsole(debuggedProgram, strings.NewReader(commands), &out)
+	console.Debugger().Run(getProgram(t, debuggedProgram), object.NewEnvironment())
+
+	expected := `stopped in main at line 1
+>    1  let total = 0;
+(debug) breakpoint 1 at line 3
+(debug) breakpoint 1 hit in add at line 3
+>    3      let sum = a + b;
+(debug) * #0 add at line 3
+  #1 main at line 8
+(debug) 10
+(debug) Local:
+  a = 1
+  b = 2
+Global:
+  add = fn(a, b)
+  i = 2
+  total = 1
+(debug) `
+	assert.Equal(t, expected, out.String())
+}
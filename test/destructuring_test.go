@@ -0,0 +1,142 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+)
+
+func TestParsedDestructuring(t *testing.T) {
+	program := getProgram(t, `let [a, [b, c = 2], ...rest] = x; const {name, "v": version = 1} = y; fn([first], {port = 80}, z) { return z }`)
+	assert.Len(t, program.Statements, 3)
+
+	let := program.Statements[0].(ast.LetStatement)
+	assert.Nil(t, let.Name)
+	array, ok := let.Pattern.(ast.ArrayPattern)
+	assert.True(t, ok)
+	assert.Len(t, array.Items, 2)
+	assert.Equal(t, "rest", array.Rest.Value)
+	assert.Equal(t, "let [a, [b, c = 2], ...rest] = x", let.String())
+
+	constant := program.Statements[1].(ast.LetStatement)
+	assert.True(t, constant.Constant())
+	hashTable, ok := constant.Pattern.(ast.HashTablePattern)
+	assert.True(t, ok)
+	withDefault, ok := hashTable.Values[1].(ast.DefaultPattern)
+	assert.True(t, ok)
+	assert.Equal(t, "version", withDefault.Pattern.String())
+	assert.Equal(t, "1", withDefault.Default.String())
+
+	fn := program.Statements[2].(ast.ExpressionStatement).Expression.(ast.Function)
+	assert.Len(t, fn.Parameters, 3)
+	assert.IsType(t, ast.ArrayPattern{}, fn.Parameters[0])
+	assert.IsType(t, ast.HashTablePattern{}, fn.Parameters[1])
+	assert.IsType(t, ast.Identifier{}, fn.Parameters[2])
+
+	names := []string{}
+	for _, name := range ast.Names(let.Pattern) {
+		names = append(names, name.Value)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "rest"}, names)
+}
+
+func TestDestructuringSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b = ] = x", "parse function for token type ']' is not implemented"},
+		{"let [a b] = x", "expected next token to be ']', got IDENT instead"},
+		{"let {a} x", "expected next token to be '=', got IDENT instead"},
+		{"let 1 = x", "expected next token to be 'IDENT', got INT instead"},
+		{"fn(1) {}", "expected next token to be 'IDENT', got INT instead"},
+		{"fn([a, ...b, c]) {}", "expected next token to be ']', got , instead"},
+	}
+
+	for _, test := range tests {
+		testSyntaxError(t, test.input, test.expected)
+	}
+}
+
+func TestEvaluatedDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = [1, 2]; [b, a]", "[2, 1]"},
+		{"let [a, b, ...rest] = [1, 2, 3, 4]; rest", "[3, 4]"},
+		{"let [a, ...rest] = [1]; rest", "[]"},
+		{"let [_, b, ..._] = [1, 2, 3]; b", "2"},
+		{"let [a, [b, c]] = [1, [2, 3]]; a + b + c", "6"},
+		{"let [a, b = 10] = [1]; a + b", "11"},
+		{"let [a, b = a * 2] = [4]; b", "8"},
+		{`let {name, version} = {"name": "monkey", "version": 2, "license": "MIT"}; [name, version]`, `["monkey", 2]`},
+		{`let {"name": n} = {"name": "monkey"}; n`, `"monkey"`},
+		{`let {port = 8080} = {}; port`, "8080"},
+		{`let {port = 8080} = {"port": 80}; port`, "80"},
+		{`let {"db": {host, port = 5432}} = {"db": {"host": "local"}}; [host, port]`, `["local", 5432]`},
+		{`let {"items": [first, ...others]} = {"items": [1, 2]}; others`, "[2]"},
+		{"let x = 1; let [x, y] = [2, 3]; x", "2"},
+		{"let [a, b] = [1, 2]; let [a, b] = [b, a]; [a, b]", "[2, 1]"},
+		{"let f = fn() { let [a, b] = [1, 2]; return a + b }; f()", "3"},
+		{"let f = fn() { if (true) { let {a} = {\"a\": 5}; return a } }; f()", "5"},
+		// Parameters destructure the arguments.
+		{"let sum = fn([a, b]) { return a + b }; sum([1, 2])", "3"},
+		{`let greet = fn({name, greeting = "Hello"}) { return greeting + " " + name }; greet({"name": "you"})`, `"Hello you"`},
+		{"let f = fn(x, [y, ...ys], {z}) { return [x, y, ys, z] }; f(1, [2, 3], {\"z\": 4})", "[1, 2, [3], 4]"},
+		{"let first = fn([head, ..._]) { return head }; first([7, 8, 9])", "7"},
+		{"let f = fn(_, x) { return x }; f(1, 2)", "2"},
+		{"let adder = fn([a, b]) { return fn(c) { return a + b + c } }; adder([1, 2])(3)", "6"},
+		{"let f = fn([a]) { if (a > 0) { return f([a - 1]) } return a }; f([3])", "0"},
+		{"let f = fn(x) { return fn([x]) { return x } }; f(1)([2])", "2"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = 5", "1:5: expected an array, got 5"},
+		{"let [a, b] = [1]", "1:5: expected an array of 2 items, got [1]"},
+		{"let [a] = [1, 2]", "1:5: expected an array of 1 item, got [1, 2]"},
+		{"let [a, b = 1] = [1, 2, 3]", "1:5: expected an array of at most 2 items, got [1, 2, 3]"},
+		{"let [a, b, ...c] = [1]", "1:5: expected an array of at least 2 items, got [1]"},
+		{"let [a, [b]] = [1, 2]", "1:9: expected an array, got 2"},
+		{`let {name} = [1]`, "1:5: expected a hash table, got [1]"},
+		{`let {name} = {"nam": 1}`, `1:6: expected a hash table with the key "name", got {"nam": 1}`},
+		{"let\n  {\"a\": [x, 1]} = {\"a\": [1, 2]}", "2:13: expected 1, got 2"},
+		{"let f = fn([a, b]) { return a }; f([1])", "1:12: expected an array of 2 items, got [1]"},
+		{"let f = fn({a}) { return a }; f(1)", "1:12: expected a hash table, got 1"},
+		{"let [a = missing] = []", "identifier not found: missing"},
+	}
+
+	for _, test := range tests {
+		testErrorObject(t, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestResolvedDestructuring(t *testing.T) {
+	res := resolver.Resolve(getProgram(t, "let f = fn([a, b = a], {c}) { return b + c }; const [x, ...xs] = [1]"))
+
+	kinds := map[string]resolver.Kind{}
+	for _, d := range res.Declarations {
+		kinds[d.Name.Literal] = d.Kind
+	}
+	assert.Equal(t, map[string]resolver.Kind{
+		"f": resolver.Variable, "a": resolver.Parameter, "b": resolver.Parameter, "c": resolver.Parameter,
+		"x": resolver.Constant, "xs": resolver.Constant,
+	}, kinds)
+
+	// The default reads the parameter before it.
+	reference := res.References[0]
+	assert.Equal(t, "a", reference.Token.Literal)
+	assert.Equal(t, resolver.Parameter, reference.Declaration.Kind)
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+func TestEvaluatedEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[] == []", true},
+		{"[1, 2] != [1, 2]", false},
+		{`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{} != {}`, false},
+		{`[{"a": [1, {"b": "c"}]}, [true]] == [{"a": [1, {"b": "c"}]}, [true]]`, true},
+		{`[{"a": [1, {"b": "c"}]}, [true]] == [{"a": [1, {"b": "d"}]}, [true]]`, false},
+		{`let a = [1, 2]; let b = a; a == b`, true},
+		{`let a = [1]; let b = copy(a); b[0] = 2; a == b`, false},
+		{`let a = {"x": [1]}; deepCopy(a) == a`, true},
+		{`freeze([1, [2]]) == [1, [2]]`, true},
+		// Functions are equal only to themselves.
+		{"let f = fn(x) { x }; f == f", true},
+		{"let f = fn(x) { x }; let g = f; f == g", true},
+		{"fn(x) { x } == fn(x) { x }", false},
+		{"let make = fn() { return fn() { 1 } }; make() == make()", false},
+		{"[len] == [len]", true},
+		{"len == log", false},
+		// Values of different types are never equal.
+		{`1 == "1"`, false},
+		{`1 != "1"`, true},
+		{"[1] == 1", false},
+		{`[] == {}`, false},
+		{"true == 1", false},
+		{"let f = fn() {}; f() == [1]", false},
+		{"let f = fn() {}; f() == f()", true},
+		{"len == fn(x) { x }", false},
+	}
+
+	for _, test := range tests {
+		testBooleanObject(t, testEvalWithError(t, test.input), test.expected)
+	}
+}
+
+func TestEqualityOfCycles(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let a = [1, 2]; a[1] = a; let b = [1, 2]; b[1] = b; a == b", true},
+		{"let a = [1, 2]; a[1] = a; let b = [3, 2]; b[1] = b; a == b", false},
+		{"let a = [1, 2]; a[1] = a; let b = [1, 2]; b[1] = a; a == b", true},
+		{"let a = [1, 2]; a[1] = a; a == deepCopy(a)", true},
+		{`let h = {"n": 1}; h["self"] = h; let g = {"n": 1}; g["self"] = g; h == g`, true},
+		{`let h = {"n": 1}; h["self"] = h; let g = {"n": 2}; g["self"] = g; h == g`, false},
+		// Mutually recursive structures of different shapes.
+		{"let a = [0]; let b = [a]; a[0] = b; let c = [0]; c[0] = c; a == c", true},
+		{"let a = [0, 1]; let b = [a, 2]; a[0] = b; let c = [0, 1]; c[0] = c; a == c", false},
+	}
+
+	for _, test := range tests {
+		testBooleanObject(t, testEvalWithError(t, test.input), test.expected)
+	}
+}
+
+func TestInspectCycles(t *testing.T) {
+	evaluated := testEvalWithError(t, `let a = [1, 2]; let h = {"a": a}; a[1] = h; h["self"] = h; a`)
+	assert.Equal(t, `[1, {"a": [...], "self": {...}}]`, evaluator.Inspect(evaluated))
+
+	assert.True(t, evaluator.Equal(evaluated, object.Identifier{Name: "a", Value: evaluated}))
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -62,6 +63,7 @@ func TestEvaluatedBooleans(t *testing.T) {
 		{"true", true}, {"false", false}, {"1 > 2", false}, {"1 + 1 == 2", true},
 		{"(1 - 2) * 4 < 10", true}, {"true == true", true}, {"false != true", true},
 		{"(1 < 2) == true", true}, {"(1 > 2) == true", false},
+		{"let x = 2; x == 2", true}, {"let x = 2; 1 < x", true}, {"let t = true; !t", false},
 	}
 
 	for _, test := range tests {
@@ -101,6 +103,7 @@ func TestEvaluatedIfElse(t *testing.T) {
 		{"if (1 > 2) { 5 } else { 1 }", 1},
 		{"if (1 > 2) { 5 } else if (2 > 1) { 15 } else { 1 }", 15},
 		{"if (1 > 2) { 5 } else if (2 == 1) { 15 } else if (2 > 1) { 25 } else { 1 }", 25},
+		{"let flag = true; if (flag) { 5 } else { 1 }", 5},
 	}
 
 	for _, test := range tests {
@@ -158,6 +161,10 @@ func TestEvaluatedErrors(t *testing.T) {
 			"unknown operator: BOOLEAN + BOOLEAN",
 		},
 		{"z", "identifier not found: z"},
+		{"let f = fn() { -true; return 1 }; f()", "unknown operator: -BOOLEAN"},
+		{"let f = fn(x, y) { x }; f(1)", "wrong number of arguments: got=1, want=2"},
+		{"1 / 0", "division by zero"},
+		{"let flag = true; while (flag) { flag = z }", "identifier not found: z"},
 	}
 
 	for _, test := range tests {
@@ -166,6 +173,35 @@ func TestEvaluatedErrors(t *testing.T) {
 	}
 }
 
+func TestEvaluatedAssertions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"assert(1 > 2)", "assertion failed"},
+		{`assert(false, "custom")`, "assertion failed: custom"},
+		{`assertEqual("a", "b")`, "assertEqual failed\n    expected: \"a\"\n    actual:   \"b\""},
+		{`assertEqual({"a": 1}, {"a": 1, "b": 2})`, "assertEqual failed\n    expected: {\"a\": 1}\n    actual:   {\"a\": 1, \"b\": 2}\n    unexpected key \"b\""},
+		{"assertThrows(fn() { return 1 })", "assertThrows failed\n    expected an error, got: 1"},
+		{`assertThrows(fn() { return -true }, "mismatch")`, "assertThrows failed\n    expected an error containing: \"mismatch\"\n    got: \"unknown operator: -BOOLEAN\""},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(t, test.input)
+		testErrorObject(t, evaluated, test.expected)
+	}
+
+	passing := []string{
+		"assert(true)",
+		"let xs = [1, 2]; assertEqual([1, 2], xs)",
+		`assertEqual({"a": [true, "b"]}, {"a": [true, "b"]})`,
+		"assertThrows(fn() { return -true })",
+	}
+	for _, input := range passing {
+		testNullObject(t, testEvalWithError(t, input))
+	}
+}
+
 func testErrorObject(t *testing.T, o object.Object, message string) {
 	obj, ok := o.(object.Error)
 	assert.Equal(t, true, ok)
@@ -182,6 +218,10 @@ func TestEvaluatedLets(t *testing.T) {
 		{"let y = 15 + 5; y", 20},
 		{"let z = -11 * (10 * -1); let zz = z + z; zz", 220},
 		{"let z = -11 * (10 * -1); let zz = z + z; zz = 10; zz", 10},
+		{"let x = 5; -x", -5},
+		{"let x = 1; if (true) { let x = 2; }; x", 1},
+		{"let x = 1; let f = fn(x) { x = 3; x }; f(2); x", 1},
+		{"let x = 1; if (true) { x = 2; }; x", 2},
 	}
 
 	for _, test := range tests {
@@ -207,13 +247,13 @@ func TestEvaluatedFunctions(t *testing.T) {
 }
 
 func testFunctionObject(t *testing.T, o object.Object, parameters []string, body string) {
-	obj, ok := o.(object.Function)
+	obj, ok := o.(*object.Function)
 	assert.Equal(t, true, ok)
 	assert.Equal(t, object.FunctionType, obj.Type())
 
 	assert.Len(t, obj.Parameters, len(parameters))
-	for i, ident := range obj.Parameters {
-		assert.Equal(t, ident.Value, parameters[i])
+	for i, parameter := range obj.Parameters {
+		assert.Equal(t, parameter.String(), parameters[i])
 	}
 
 	assert.Equal(t, obj.Body.String(), body)
@@ -226,6 +266,10 @@ func TestEvaluatedFunctionCalls(t *testing.T) {
 	}{
 		{"let add = fn(x) {return x + 2}; add(2)", 4},
 		{"let mul = fn(x, y) {return x * y}; mul(3, 3)", 9},
+		{"let apply = fn(f, x) {return f(x)}; let inc = fn(x) {return x + 1}; apply(inc, 1)", 2},
+		{"let x = 1; let y = 5; x = y; x", 5},
+		{"let id = fn(x) {return x}; let y = 3; len([id(y), y])", 2},
+		{`let h = {"a": [1, 7]}; h["a"][1]`, 7},
 	}
 
 	for _, test := range tests {
@@ -244,6 +288,11 @@ func TestEvaluatedBuiltinFunctions(t *testing.T) {
 		{`len([1, 2, 3, 4])`, 4},
 		{"len(1)", "argument type is not supported: got INTEGER"},
 		{"len(\"four\", \"three\")", "wrong number of arguments: got=2, want=1"},
+		{"len(append([], 1, 2))", 2},
+		{"let a = [1]; let b = append(a, 2); len(a)", 1},
+		{"[len][0]([1, 2])", 2},
+		{`let h = {"f": len}; h["f"]("abc")`, 3},
+		{"let fs = [len]; fs[0](1)", "argument type is not supported: got INTEGER"},
 	}
 
 	for _, test := range tests {
@@ -270,7 +319,7 @@ func TestEvaluatedArrays(t *testing.T) {
 	for _, test := range tests {
 		evaluated := testEval(t, test.input)
 
-		array, ok := evaluated.(object.Array)
+		array, ok := evaluated.(*object.Array)
 		assert.Equal(t, true, ok)
 
 		for i, item := range array.Items {
@@ -292,6 +341,10 @@ func TestEvaluatedAccessByExpression(t *testing.T) {
 		{"let x = [1, 2, 3]; x[1]", 2},
 		{"let y = [10, 22, 33]; y[1 + 1]", 33},
 		{`let words = ["hello", "world"]; words[(100 + 100) * 0]`, "hello"},
+		{"let x = [1, 2, 3]; let i = 2; x[i]", 3},
+		{`let h = {"a": "b"}; let k = "a"; h[k]`, "b"},
+		{`let h = {}; let k = "a"; h[k] = 1; h[k]`, 1},
+		{`{"a": 1, "a": 2}["a"]`, 2},
 	}
 
 	for _, test := range tests {
@@ -319,7 +372,7 @@ func TestEvaluatedHashTables(t *testing.T) {
 	for _, test := range tests {
 		evaluated := testEvalWithError(t, test.input)
 
-		hashTable, ok := evaluated.(object.HashTable)
+		hashTable, ok := evaluated.(*object.HashTable)
 		assert.Equal(t, true, ok)
 
 		for key, val := range hashTable.Items {
@@ -351,3 +404,87 @@ func testEvalWithError(t *testing.T, input string) object.Object {
 
 	return evaluated
 }
+
+func TestEvaluatedTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let count = fn(n, acc) { if (n < 1) { return acc } return count(n - 1, acc + 1) }; count(100000, 0)", 100000},
+		{`let even = fn(n) { if (n < 1) { return true } return odd(n - 1) };
+		  let odd = fn(n) { if (n < 1) { return false } return even(n - 1) };
+		  even(50001)`, false},
+		{"let f = fn() { while (true) { return 5 } }; f()", 5},
+		{"let f = fn(x) { return x * 2 }; return f(3)", 6},
+		{"let f = fn() { return len([1, 2]) }; f()", 2},
+		{"let deep = fn(n) { if (n < 1) { return 0 } return 1 + deep(n - 1) }; deep(1000)", 1000},
+		{
+			"let deep = fn(n) { if (n < 1) { return 0 } return 1 + deep(n - 1) }; deep(100000)",
+			"stack overflow: more than 10000 nested calls",
+		},
+		{
+			`let deep = fn(n) { if (n < 1) { return 0 } return 1 + deep(n - 1) };
+			 assertThrows(fn() { deep(100000) }, "stack overflow"); deep(10)`,
+			10,
+		},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(t, test.input)
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testErrorObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	input := "let deep = fn(n) { if (n < 1) { return 0 } return 1 + deep(n - 1) }; deep(%d)"
+	limited := func(n int) object.Object {
+		env := object.NewEnvironment()
+		evaluator.LimitCallDepth(env, 50)
+		return evaluator.Eval(getProgram(t, fmt.Sprintf(input, n)), env)
+	}
+
+	testIntegerObject(t, limited(49), 49)
+	testErrorObject(t, limited(50), "stack overflow: more than 50 nested calls")
+	// The limit is that of the evaluation, not of every one.
+	testIntegerObject(t, testEval(t, fmt.Sprintf(input, 50)), 50)
+
+	env := object.NewEnvironment()
+	evaluator.LimitCallDepth(env, 50)
+	evaluated := evaluator.Eval(getProgram(t, "let deep = fn(n) { if (n < 1) { return 0 } return 1 + deep(n - 1) }; wait(spawn deep(60))"), env)
+	testErrorObject(t, evaluated, "stack overflow: more than 50 nested calls")
+}
+
+// Builtins calling back the functions they are given nest the calls in
+// their own, so that recursion through them is limited like any other.
+func TestCallDepthOfCallbacks(t *testing.T) {
+	tests := []string{
+		"let cb = fn(x) { return f(x + 1) }; let f = fn(n) { return [n].map(cb) }; f(0)",
+		"let cb = fn(x) { return f(x + 1) }; let f = fn(n) { return [n].filter(cb) }; f(0)",
+		"let cb = fn(a, x) { return f(x + 1) }; let f = fn(n) { return [n].reduce(cb, 0) }; f(0)",
+		"let cb = fn(x) { return f(x + 1) }; let f = fn(n) { return iter([n]).map(cb).collect() }; f(0)",
+		"let f = fn(n) { let xs = [n].map(fn(x) { return f(x + 1) }); return xs }; f(0)",
+	}
+
+	limited := func(input string) object.Object {
+		env := object.NewEnvironment()
+		evaluator.LimitCallDepth(env, 50)
+		return evaluator.Eval(getProgram(t, input), env)
+	}
+	for _, input := range tests {
+		testErrorObject(t, limited(input), "stack overflow: more than 50 nested calls")
+	}
+
+	// The innermost call fails, and each assertThrows around it fails in
+	// turn when the call it makes does not.
+	input := "let f = fn() { return assertThrows(f) }; f()"
+	testErrorObject(t, limited(input), "assertThrows failed\n    expected an error, got: null")
+	testErrorObject(t, testEval(t, input), "assertThrows failed\n    expected an error, got: null")
+	testErrorObject(t, testEval(t, tests[0]), "stack overflow: more than 10000 nested calls")
+}
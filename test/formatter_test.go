@@ -0,0 +1,54 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/formatter"
+)
+
+func TestFormatFixtures(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/format/*.monkey")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, inputs)
+
+	for _, input := range append(inputs, "../example.monkey") {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			source := readFile(t, input)
+
+			formatted, err := formatter.Format(source)
+			assert.NoError(t, err)
+
+			golden := strings.TrimSuffix(input, ".monkey") + ".golden"
+			if _, err := os.Stat(golden); err == nil {
+				assert.Equal(t, readFile(t, golden), formatted)
+			}
+
+			again, err := formatter.Format(formatted)
+			assert.NoError(t, err)
+			assert.Equal(t, formatted, again, "formatting is not idempotent")
+
+			assert.Equal(t, getProgram(t, source).String(), getProgram(t, formatted).String())
+		})
+	}
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	_, err := formatter.Format("let = 5")
+
+	var syntaxError formatter.SyntaxError
+	assert.ErrorAs(t, err, &syntaxError)
+	assert.NotEmpty(t, syntaxError.Errors)
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,241 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/formatter"
+	"github.com/timur-makarov/monkey-interpreter/internal/lexer"
+	"github.com/timur-makarov/monkey-interpreter/internal/linter"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/optimizer"
+	"github.com/timur-makarov/monkey-interpreter/internal/parser"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+	"github.com/timur-makarov/monkey-interpreter/internal/token"
+)
+
+// Inputs that crashed the lexer, parser or evaluator before, on top of the
+// crashers kept in testdata/fuzz.
+var fuzzSeeds = []string{
+	"",
+	"let five = 5; let add = fn(x, y) { x + y }; add(five, 10);",
+	`let h = {"a": [1, 2], "b": {"c": true}}; h["a"][1] = h["b"]["c"];`,
+	"let i = 0; while (i < 3) { i = i + 1 }",
+	"if (1 > 2) { 1 } else if (2 > 1) { 2 } else { 3 }",
+	"x =",
+	"!",
+	"if (x) {} else if",
+	"fn(1) {}",
+	"fn(x, 2) {}",
+	"\xff\xfe=",
+	"\"\xc3",
+	"let f = fn(x, y) { x }; f(1)",
+	"1 / 0",
+	"[1] == [1]",
+	"let flag = true; while (flag) { flag = 1 }",
+	"{len(1): 2}",
+	"const x = 1; let f = fn() { x = 2 }",
+	`let a = [1]; a[0] = a; freeze(a)[0][0] = 1`,
+	"struct P { x, y }; let p = P{x: 1}; p.y = p; p.x = p.y.x",
+	"x.y{",
+	`match ([1, {"a": 2}]) { [x, {"a": y}] if x < y => x + y, [_, ...rest] => rest, _ => 0 }`,
+	`let [a, {b, "c": [d = a]}, ...rest] = [1, {"b": 2, "c": []}]; let f = fn([x], {y = x}) { return x + y }; f([a], {})`,
+	"let f = fn(a, b = a, ...rest) { return [a, b, rest] }; f(...[1, 2, 3], 4); f(); f(...1)",
+	"let g = fn*(n) { let i = 0; while (i < n) { if (i > 1) { yield i } i = i + 1 } }; for ([x] in [[1]]) { collect(g(x).map(fn(v) { return v }).take(2)) }",
+	"let it = 0; let g = fn*() { yield it.next(); for (c in \"ab\") { yield c } }; it = g(); it.next(); iter(5)",
+	"let c = channel(1); let t = spawn fn(x) { c.send(x); return c }(1); select { v = c.receive() => v, c.send(2) => 0, _ => wait(t) }; c.close(); for (v in c) { spawn v }",
+	"let c = channel(); spawn fn() { c.receive() }; select { [x] = c.receive() => x }; wait([spawn len(\"a\")])",
+}
+
+func addFuzzSeeds(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+}
+
+func FuzzLexer(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := lexer.New(input)
+
+		// Every token consumes at least one byte, so a lexer that keeps
+		// going past the length of the input is stuck.
+		for i := 0; ; i++ {
+			if i > len(input)+1 {
+				t.Fatalf("no EOF after %d tokens", i)
+			}
+			if l.NextToken().Type == token.EOF {
+				break
+			}
+		}
+	})
+}
+
+func FuzzParser(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+
+		if len(p.Errors()) > 0 {
+			return
+		}
+
+		_ = program.String()
+		resolver.Resolve(program)
+		linter.Lint(program)
+		optimizer.Optimize(program)
+		resolver.Bind(program)
+		if _, err := formatter.Format(input); err != nil {
+			t.Fatalf("format of a program that parses failed: %v", err)
+		}
+	})
+}
+
+// fuzzBudget stops evaluation after a number of statements, so that
+// endless loops and recursion end with an error.
+type fuzzBudget struct {
+	statements int
+}
+
+func (b *fuzzBudget) Statement(ast.Statement, []*evaluator.Frame) object.Object {
+	b.statements--
+	if b.statements < 0 {
+		return object.Error{Message: "statement budget exhausted"}
+	}
+	return nil
+}
+
+func (b *fuzzBudget) Error(object.Error, []*evaluator.Frame) {}
+
+// hasEmptyLoop reports whether a program has a while loop without
+// statements, the only way to loop that the budget cannot stop.
+func hasEmptyLoop(node ast.Node) bool {
+	var nodes []ast.Node
+
+	switch n := node.(type) {
+	case *ast.Program:
+		nodes = n.Statements
+	case ast.BlockStatement:
+		nodes = n.Statements
+	case ast.ExpressionStatement:
+		nodes = []ast.Node{n.Expression}
+	case ast.LetStatement:
+		nodes = []ast.Node{n.Value}
+		nodes = append(nodes, defaults(n.Pattern)...)
+	case ast.ReturnStatement:
+		nodes = []ast.Node{n.Value}
+	case ast.YieldStatement:
+		nodes = []ast.Node{n.Value}
+	case ast.While:
+		if len(n.Body.Statements) == 0 {
+			return true
+		}
+		nodes = []ast.Node{n.Condition, n.Body}
+	case ast.For:
+		nodes = []ast.Node{n.Iterable, n.Body}
+		nodes = append(nodes, defaults(n.Pattern)...)
+	case ast.If:
+		nodes = append(nodes, n.Conditions...)
+		for _, consequence := range n.Consequences {
+			nodes = append(nodes, consequence)
+		}
+		nodes = append(nodes, n.Alternative)
+	case ast.Match:
+		nodes = []ast.Node{n.Subject}
+		for _, arm := range n.Arms {
+			nodes = append(nodes, defaults(arm.Pattern)...)
+			if arm.Guard != nil {
+				nodes = append(nodes, arm.Guard)
+			}
+			nodes = append(nodes, arm.Result)
+		}
+	case ast.Function:
+		nodes = []ast.Node{n.Body}
+		for _, parameter := range n.Parameters {
+			nodes = append(nodes, defaults(parameter)...)
+		}
+	case ast.Call:
+		nodes = append([]ast.Node{n.Function}, n.Arguments...)
+	case ast.Spread:
+		nodes = []ast.Node{n.Value}
+	case ast.Spawn:
+		nodes = []ast.Node{n.Value}
+	case ast.Select:
+		for _, c := range n.Cases {
+			if c.Operation != nil {
+				nodes = append(nodes, *c.Operation)
+			}
+			if c.Pattern != nil {
+				nodes = append(nodes, defaults(c.Pattern)...)
+			}
+			nodes = append(nodes, c.Result)
+		}
+	case ast.Array:
+		nodes = n.Items
+	case ast.HashTable:
+		for _, key := range n.Keys {
+			nodes = append(nodes, key, n.Items[key])
+		}
+	case ast.AccessByExpression:
+		nodes = []ast.Node{n.Left, n.Index}
+	case ast.StructLiteral:
+		nodes = n.Values
+	case ast.FieldAccess:
+		nodes = []ast.Node{n.Left}
+	case ast.Prefix:
+		nodes = []ast.Node{n.Right}
+	case ast.Infix:
+		nodes = []ast.Node{n.Left, n.Right}
+	}
+
+	for _, child := range nodes {
+		if hasEmptyLoop(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func defaults(pattern ast.Node) []ast.Node {
+	var nodes []ast.Node
+	for _, value := range ast.Defaults(pattern) {
+		nodes = append(nodes, value)
+	}
+	return nodes
+}
+
+func FuzzEval(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+
+		if len(p.Errors()) > 0 || hasEmptyLoop(program) {
+			return
+		}
+
+		// Tasks run one at a time, in the same order in both evaluations,
+		// and count against the same budget.
+		env := object.NewEnvironment()
+		evaluator.Attach(env, &fuzzBudget{statements: 1000})
+		scheduler := evaluator.NewScheduler(0)
+		evaluator.Schedule(env, scheduler)
+		expected := evaluator.Inspect(evaluator.Eval(program, env))
+		scheduler.Stop()
+
+		// Locating variables by slot must not change what a program does.
+		bound := object.NewEnvironment()
+		evaluator.Attach(bound, &fuzzBudget{statements: 1000})
+		scheduler = evaluator.NewScheduler(0)
+		evaluator.Schedule(bound, scheduler)
+		defer scheduler.Stop()
+		if actual := evaluator.Inspect(evaluator.Eval(resolver.Bind(program), bound)); actual != expected {
+			t.Fatalf("bound program evaluated to %s, want %s", actual, expected)
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/optimizer"
+)
+
+func TestParsedGenerators(t *testing.T) {
+	program := getProgram(t, "let g = fn*(xs) { for ([a, b] in xs) { yield a + b } }")
+	assert.Len(t, program.Statements, 1)
+
+	fn, ok := program.Statements[0].(ast.LetStatement).Value.(ast.Function)
+	assert.True(t, ok)
+	assert.True(t, fn.Generator)
+
+	loop, ok := fn.Body.Statements[0].(ast.ExpressionStatement).Expression.(ast.For)
+	assert.True(t, ok)
+	assert.Equal(t, "[a, b]", loop.Pattern.String())
+	assert.Equal(t, "xs", loop.Iterable.String())
+
+	yield, ok := loop.Body.Statements[0].(ast.YieldStatement)
+	assert.True(t, ok)
+	assert.Equal(t, "yield a + b", yield.String())
+}
+
+func TestGeneratorSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"yield 1", "yield outside of a generator"},
+		{"let f = fn() { yield 1 }", "yield outside of a generator"},
+		{"let g = fn*() { let f = fn() { yield 1 } }", "yield outside of a generator"},
+		{"let g = fn*() { let x = if (true) { yield 1 } }", "cannot yield inside an expression"},
+		{"let g = fn*() { f(while (true) { yield 1 }) }", "cannot yield inside an expression"},
+		{"let g = fn*() { if (if (true) { yield 1 }) { } }", "cannot yield inside an expression"},
+		{"for (1 in [1]) { }", "expected next token to be 'IDENT', got INT instead"},
+		{"for (x of [1]) { }", "expected next token to be 'IN', got IDENT instead"},
+		{"for (x in [1]) log(x)", "expected next token to be '{', got IDENT instead"},
+	}
+
+	for _, test := range tests {
+		testSyntaxError(t, test.input, test.expected)
+	}
+}
+
+const rangeGenerator = "let range = fn*(n) { let i = 0; while (i < n) { yield i; i = i + 1 } }; "
+
+func TestEvaluatedForLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let s = 0; for (x in [1, 2, 3]) { s = s + x }; s", "6"},
+		{`let s = ""; for (c in "abc") { s = c + s }; s`, `"cba"`},
+		{`let s = ""; for (c in "héllo") { s = s + c + "." }; s`, `"h.é.l.l.o."`},
+		{`let ks = []; for (k in {"b": 1, "a": 2}) { ks.push(k) }; ks`, `["a", "b"]`},
+		{"let s = 0; for ([a, b] in [[1, 2], [3, 4]]) { s = s + a * b }; s", "14"},
+		{`let s = 0; for ({x, y = 1} in [{"x": 2}, {"x": 3, "y": 4}]) { s = s + x * y }; s`, "14"},
+		{"let n = 0; for (_ in [1, 2, 3]) { n = n + 1 }; n", "3"},
+		{"for (x in []) { x }", "null"},
+		// The items are those the array holds when the loop starts.
+		{"let a = [1, 2]; for (x in a) { a.push(x) }; a", "[1, 2, 1, 2]"},
+		// Names bound by the pattern are scoped to the body.
+		{"let x = 0; for (x in [1, 2]) { x }; x", "0"},
+		{"let f = fn(xs) { for (x in xs) { if (x > 1) { return x } }; return 0 }; f([1, 5, 7])", "5"},
+		{"let fs = []; for (x in [1, 2]) { fs.push(fn() { return x }) }; [fs[0](), fs[1]()]", "[1, 2]"},
+		{rangeGenerator + "let s = 0; for (x in range(4)) { s = s + x }; s", "6"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestEvaluatedGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{rangeGenerator + "collect(range(4))", "[0, 1, 2, 3]"},
+		{rangeGenerator + "let it = range(1); [it.next(), it.next(), it.next()]",
+			`[{"done": false, "value": 0}, {"done": true, "value": null}, {"done": true, "value": null}]`},
+		// The body runs only as values are asked for.
+		{`let log = []; let g = fn*() { log.push("start"); yield 1; log.push("end") }; let it = g(); let a = log.len(); it.next(); let b = log.len(); it.next(); [a, b, log.len()]`,
+			"[0, 1, 2]"},
+		{"let g = fn*(xs) { for (x in xs) { if (x > 1) { yield x * 10 } else { yield x } } }; collect(g([1, 2, 3]))", "[1, 20, 30]"},
+		{"let g = fn*() { yield 1; return 2; yield 3 }; collect(g())", "[1]"},
+		{"let g = fn*() { yield 1; if (true) { return 0 }; yield 2 }; collect(g())", "[1]"},
+		{"let g = fn*(n) { if (n > 0) { yield n; for (x in g(n - 1)) { yield x } } }; collect(g(3))", "[3, 2, 1]"},
+		{"let g = fn*(a, b = 2, ...rest) { yield a; yield b; yield rest }; collect(g(1))", "[1, 2, []]"},
+		{"let g = fn*() { let x = 1; while (x < 100) { yield x; x = x * 3 } }; collect(g())", "[1, 3, 9, 27, 81]"},
+		{"let g = fn*() { }; collect(g())", "[]"},
+		// Generators are independent of each other.
+		{rangeGenerator + "let a = range(3); let b = range(3); a.next(); [a.next()[\"value\"], b.next()[\"value\"]]", "[1, 0]"},
+		{rangeGenerator + "let f = fn(a, b, c) { return a + b + c }; f(...range(3))", "3"},
+		{rangeGenerator + "let it = range(3); it == it", "true"},
+		{rangeGenerator + "range(3) == range(3)", "false"},
+		// A generator that failed is done.
+		{"let g = fn*() { yield 1; yield missing; yield 2 }; let it = g(); it.next(); assertThrows(fn() { return it.next() }); it.next()",
+			`{"done": true, "value": null}`},
+		{rangeGenerator + "range", "fn*(n)"},
+		{rangeGenerator + "range(1)", "iterator"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestEvaluatedIteratorMethods(t *testing.T) {
+	naturals := "let naturals = fn*() { let i = 0; while (true) { yield i; i = i + 1 } }; "
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{naturals + "naturals().take(3).collect()", "[0, 1, 2]"},
+		{naturals + "naturals().skip(2).take(2).collect()", "[2, 3]"},
+		{naturals + "naturals().map(fn(x) { return x * x }).take(4).collect()", "[0, 1, 4, 9]"},
+		{naturals + "naturals().filter(fn(x) { return x / 2 * 2 == x }).take(3).collect()", "[0, 2, 4]"},
+		{naturals + "naturals().take(5).reduce(fn(sum, x) { return sum + x }, 0)", "10"},
+		{naturals + `naturals().take(3).join("-")`, `"0-1-2"`},
+		{naturals + "naturals().take(3).contains(2)", "true"},
+		{naturals + "naturals().take(0).collect()", "[]"},
+		{naturals + "let it = naturals(); it.take(2).collect(); it.next()[\"value\"]", "2"},
+		// Combinators take values only as theirs are asked for.
+		{"let seen = []; let it = iter([1, 2, 3]).map(fn(x) { seen.push(x); return x }); it.next(); seen", "[1]"},
+		{"iter([1, 2, 3]).skip(5).collect()", "[]"},
+		{`collect("ab")`, `["a", "b"]`},
+		{`collect({"b": 1, "a": 2})`, `["a", "b"]`},
+		{"let it = iter([1, 2]); collect(it); collect(it)", "[]"},
+		{"iter([1, 2]).map(fn(x) { return x + 1 }).collect().map(fn(x) { return x * 2 })", "[4, 6]"},
+		// len uses an iterator up, append and shift return lazy ones.
+		{rangeGenerator + "len(range(4))", "4"},
+		{rangeGenerator + "let it = range(3); len(it); collect(it)", "[]"},
+		{rangeGenerator + "collect(shift(range(3)))", "[1, 2]"},
+		{rangeGenerator + "collect(append(range(2), 7, 8))", "[0, 1, 7, 8]"},
+		{"collect(shift(iter([])))", "[]"},
+		{naturals + "append(naturals(), -1).skip(2).take(2).collect()", "[2, 3]"},
+		{naturals + "shift(naturals()).next()[\"value\"]", "1"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let g = fn*() { yield 1; yield missing }; collect(g())", "identifier not found: missing"},
+		{"let g = fn*() { yield 1; yield missing }; let it = g(); it.next(); it.next()", "identifier not found: missing"},
+		{"let g = fn*(x) { yield x }; g()", "wrong number of arguments: got=0, want=1"},
+		{"let g = fn*() { for (x in 5) { yield x } }; collect(g())", "cannot iterate over INTEGER"},
+		{"let it = 0; let g = fn*() { yield it.next() }; it = g(); it.next()", "generator g is already running"},
+		{"for (x in 5) { }", "cannot iterate over INTEGER"},
+		{"for ([a] in [1]) { }", "1:6: expected an array, got 1"},
+		{"iter(true)", "cannot iterate over BOOLEAN"},
+		{"iter([1]).take(true)", "argument type is not supported: got BOOLEAN"},
+		{"iter([1, 0]).map(fn(x) { return 1 / x }).collect()", "division by zero"},
+		{"let g = fn*() { yield 1; yield missing }; len(g())", "identifier not found: missing"},
+		{"let g = fn*() { yield missing }; collect(append(g(), 1))", "identifier not found: missing"},
+	}
+
+	for _, test := range tests {
+		testErrorObject(t, testEval(t, test.input), test.expected)
+	}
+}
+
+// An iterator holds no goroutine, so one abandoned before it is used up is
+// collected like any other value.
+func TestAbandonedGeneratorIsCollected(t *testing.T) {
+	env := object.NewEnvironment()
+	evaluator.Eval(getProgram(t, `
+let naturals = fn*() { let i = 0; while (true) { yield i; i = i + 1 } };
+let it = naturals();
+it.next();
+`), env)
+
+	it, ok := env.Get("it")
+	assert.True(t, ok)
+	assert.Equal(t, object.IteratorType, it.Type())
+
+	collected := make(chan struct{})
+	runtime.AddCleanup(it.(*object.Iterator), func(done chan struct{}) { close(done) }, collected)
+
+	goroutines := runtime.NumGoroutine()
+	evaluator.Eval(getProgram(t, "it = 0"), env)
+	it = nil
+
+	for range 10 {
+		runtime.GC()
+		select {
+		case <-collected:
+			assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("abandoned iterator was not collected")
+}
+
+// The code a generator yields to may change what the generator reads, so
+// nothing is hoisted out of loops that yield.
+func TestOptimizerKeepsLoopsThatYield(t *testing.T) {
+	program := getProgram(t, `
+let k = 1;
+let g = fn*() {
+    let i = 0;
+    while (i < 3) {
+        yield i + k * k;
+        i = i + 1;
+    }
+};
+let it = g();
+let a = it.next()["value"];
+k = 10;
+[a, it.next()["value"], it.next()["value"]]
+`)
+
+	expected := evaluator.Eval(program, object.NewEnvironment())
+	actual := evaluator.Eval(optimizer.Optimize(program), object.NewEnvironment())
+	assert.Equal(t, "[1, 101, 102]", evaluator.Inspect(expected))
+	assert.Equal(t, evaluator.Inspect(expected), evaluator.Inspect(actual))
+}
@@ -157,3 +157,34 @@ func TestNextToken(t *testing.T) {
 		)
 	}
 }
+
+func TestTokenPositionsAndComments(t *testing.T) {
+	input := "let x = 5 // five\n// alone\n  x"
+
+	tests := []struct {
+		expectedType   token.Type
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.IDENT, 3, 3},
+		{token.EOF, 3, 4},
+	}
+
+	l := lexer.New(input)
+
+	for _, et := range tests {
+		nextToken := l.NextToken()
+		assert.Equal(t, et.expectedType, nextToken.Type)
+		assert.Equal(t, et.expectedLine, nextToken.Line, nextToken.Literal)
+		assert.Equal(t, et.expectedColumn, nextToken.Column, nextToken.Literal)
+	}
+
+	comments := l.Comments()
+	assert.Len(t, comments, 2)
+	assert.Equal(t, token.Token{Type: token.COMMENT, Literal: "// five", Line: 1, Column: 11}, comments[0])
+	assert.Equal(t, token.Token{Type: token.COMMENT, Literal: "// alone", Line: 2, Column: 1}, comments[1])
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/linter"
+)
+
+func TestLintDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []linter.Diagnostic
+	}{
+		{
+			"let x = 1; log(y)",
+			[]linter.Diagnostic{
+				{Line: 1, Column: 5, Severity: linter.Warning, Check: linter.UnusedVariable},
+				{Line: 1, Column: 16, Severity: linter.Error, Check: linter.UndefinedIdentifier},
+			},
+		},
+		{
+			"let f = fn(a, b) { return a }; f(1, 2)",
+			[]linter.Diagnostic{
+				{Line: 1, Column: 15, Severity: linter.Warning, Check: linter.UnusedParameter},
+			},
+		},
+		{
+			"let len = fn(x) { return x }; len(1)",
+			[]linter.Diagnostic{
+				{Line: 1, Column: 5, Severity: linter.Warning, Check: linter.ShadowedBuiltin},
+			},
+		},
+		{
+			"let f = fn() {\n return 1\n log(2)\n}\nf()",
+			[]linter.Diagnostic{
+				{Line: 3, Column: 2, Severity: linter.Warning, Check: linter.UnreachableCode},
+			},
+		},
+		{
+			"count = 1",
+			[]linter.Diagnostic{
+				{Line: 1, Column: 1, Severity: linter.Error, Check: linter.UndeclaredAssignment},
+			},
+		},
+		{
+			"let add = fn(a, b) { return a + b }; add(1); len(1, 2)",
+			[]linter.Diagnostic{
+				{Line: 1, Column: 38, Severity: linter.Error, Check: linter.WrongArity},
+				{Line: 1, Column: 46, Severity: linter.Error, Check: linter.WrongArity},
+			},
+		},
+		{
+			"let f = fn(a, b = 1, ...rest) { return [a, b, rest] }; f(); f(1, 2, 3); f(...[])",
+			[]linter.Diagnostic{
+				{Line: 1, Column: 56, Severity: linter.Error, Check: linter.WrongArity},
+			},
+		},
+		{
+			"let x = 0; while (true) { x = x + 1 }",
+			[]linter.Diagnostic{
+				{Line: 1, Column: 12, Severity: linter.Warning, Check: linter.ConstantCondition},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		diagnostics := linter.Lint(getProgram(t, test.input))
+
+		assert.Len(t, diagnostics, len(test.expected), test.input)
+		for i, expected := range test.expected {
+			if i >= len(diagnostics) {
+				break
+			}
+			actual := diagnostics[i]
+			actual.Message = ""
+			assert.Equal(t, expected, actual, test.input)
+		}
+	}
+}
+
+func TestLintAcceptsValidPrograms(t *testing.T) {
+	inputs := []string{
+		readFile(t, "../example.monkey"),
+		"let isEven = fn(n) { if (n == 0) { return true } return isOdd(n - 1) }\n" +
+			"let isOdd = fn(n) { if (n == 0) { return false } return isEven(n - 1) }\n" +
+			"log(isEven(4))",
+		"let f = fn(_unused) { return 1 }; log(f(2))",
+		"let naturals = fn*() { let i = 0; while (true) { if (i > 0) { yield i } i = i + 1 } }\n" +
+			"for (n in naturals().take(3)) { log(n) }",
+	}
+
+	for _, input := range inputs {
+		assert.Empty(t, linter.Lint(getProgram(t, input)), input)
+	}
+}
+
+func TestLintArityMessages(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(a, b) { return [a, b] }; f(1)", "f expects 2 arguments, got 1"},
+		{"let f = fn(a, b = 1) { return [a, b] }; f()", "f expects 1 to 2 arguments, got 0"},
+		{"let f = fn(a, ...rest) { return [a, rest] }; f()", "f expects at least 1 arguments, got 0"},
+		{"len()", "builtin len expects 1 arguments, got 0"},
+	}
+
+	for _, test := range tests {
+		diagnostics := linter.Lint(getProgram(t, test.input))
+
+		if assert.Len(t, diagnostics, 1, test.input) {
+			assert.Equal(t, test.expected, diagnostics[0].Message, test.input)
+		}
+	}
+}
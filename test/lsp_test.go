@@ -0,0 +1,185 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/lsp"
+)
+
+const lspURI = "file:///workspace/main.monkey"
+
+const lspSource = `let limit = 10
+let add = fn(a, b) {
+    let sum = a + b
+    return sum
+}
+log(add(limit, 1))
+`
+
+func startLspServer(t *testing.T, text string) *lsp.Client {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- lsp.NewServer().Serve(serverIn, serverOut)
+		_ = serverOut.Close()
+	}()
+
+	client := lsp.NewClient(clientIn, clientOut)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Notify("exit", nil))
+		assert.NoError(t, <-done)
+	})
+
+	var result lsp.InitializeResult
+	assert.NoError(t, client.Call("initialize", map[string]any{}, &result))
+	assert.True(t, result.Capabilities.DefinitionProvider)
+	assert.NoError(t, client.Notify("initialized", map[string]any{}))
+
+	assert.NoError(t, client.Notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: lspURI, LanguageID: "monkey", Version: 1, Text: text},
+	}))
+
+	return client
+}
+
+func positionParams(line, character int) lsp.TextDocumentPositionParams {
+	return lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+		Position:     lsp.Position{Line: line, Character: character},
+	}
+}
+
+func TestLspDiagnostics(t *testing.T) {
+	client := startLspServer(t, "let unused = 1\nlog(missing)\n")
+
+	var published lsp.PublishDiagnosticsParams
+	assert.NoError(t, client.Notification("textDocument/publishDiagnostics", &published))
+	assert.Equal(t, lspURI, published.URI)
+	assert.Len(t, published.Diagnostics, 2)
+	assert.Equal(t, "unused-variable", published.Diagnostics[0].Code)
+	assert.Equal(t, lsp.Range{Start: lsp.Position{Line: 0, Character: 4}, End: lsp.Position{Line: 0, Character: 10}},
+		published.Diagnostics[0].Range)
+	assert.Equal(t, "undefined-identifier", published.Diagnostics[1].Code)
+	assert.Equal(t, lsp.SeverityError, published.Diagnostics[1].Severity)
+
+	assert.NoError(t, client.Notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: lspURI, Version: 2},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: "let = 5"}},
+	}))
+	assert.NoError(t, client.Notification("textDocument/publishDiagnostics", &published))
+	assert.Equal(t, 2, published.Version)
+	assert.NotEmpty(t, published.Diagnostics)
+	assert.Equal(t, "syntax", published.Diagnostics[0].Code)
+}
+
+func TestLspNavigation(t *testing.T) {
+	client := startLspServer(t, lspSource)
+
+	var location lsp.Location
+	assert.NoError(t, client.Call("textDocument/definition", positionParams(5, 9), &location))
+	assert.Equal(t, lsp.Position{Line: 0, Character: 4}, location.Range.Start)
+
+	assert.NoError(t, client.Call("textDocument/definition", positionParams(2, 14), &location))
+	assert.Equal(t, lsp.Position{Line: 1, Character: 13}, location.Range.Start)
+
+	var hover lsp.Hover
+	assert.NoError(t, client.Call("textDocument/hover", positionParams(0, 5), &hover))
+	assert.Contains(t, hover.Contents.Value, "(variable) limit: integer")
+
+	assert.NoError(t, client.Call("textDocument/hover", positionParams(5, 5), &hover))
+	assert.Contains(t, hover.Contents.Value, "(function) add(a, b)")
+
+	assert.NoError(t, client.Call("textDocument/hover", positionParams(5, 1), &hover))
+	assert.Contains(t, hover.Contents.Value, "(builtin) log")
+
+	var symbols []lsp.DocumentSymbol
+	assert.NoError(t, client.Call("textDocument/documentSymbol", lsp.DocumentSymbolParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+	}, &symbols))
+	assert.Len(t, symbols, 2)
+	assert.Equal(t, "limit", symbols[0].Name)
+	assert.Equal(t, lsp.SymbolFunction, symbols[1].Kind)
+	assert.Equal(t, "sum", symbols[1].Children[0].Name)
+}
+
+func TestLspCompletion(t *testing.T) {
+	client := startLspServer(t, lspSource)
+
+	var items []lsp.CompletionItem
+	assert.NoError(t, client.Call("textDocument/completion", positionParams(2, 4), &items))
+
+	labels := make([]string, len(items))
+	for i, item := range items {
+		labels[i] = item.Label
+	}
+
+	assert.Contains(t, labels, "a")
+	assert.Contains(t, labels, "limit")
+	assert.Contains(t, labels, "add")
+	assert.Contains(t, labels, "len")
+	assert.NotContains(t, labels, "sum")
+}
+
+func TestLspRenameAndFormatting(t *testing.T) {
+	client := startLspServer(t, lspSource)
+
+	var edit lsp.WorkspaceEdit
+	assert.NoError(t, client.Call("textDocument/rename", lsp.RenameParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+		Position:     lsp.Position{Line: 2, Character: 18},
+		NewName:      "second",
+	}, &edit))
+	assert.Len(t, edit.Changes[lspURI], 2)
+
+	err := client.Call("textDocument/rename", lsp.RenameParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+		Position:     lsp.Position{Line: 2, Character: 18},
+		NewName:      "let",
+	}, &edit)
+	var responseError *lsp.ResponseError
+	assert.True(t, errors.As(err, &responseError))
+
+	var edits []lsp.TextEdit
+	assert.NoError(t, client.Call("textDocument/formatting", lsp.DocumentFormattingParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+	}, &edits))
+	assert.Empty(t, edits)
+
+	assert.NoError(t, client.Notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: lspURI, Version: 2},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: "let x=1;log(x)"}},
+	}))
+	assert.NoError(t, client.Call("textDocument/formatting", lsp.DocumentFormattingParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+	}, &edits))
+	assert.Len(t, edits, 1)
+	assert.Equal(t, "let x = 1\nlog(x)\n", edits[0].NewText)
+	assert.Equal(t, lsp.Position{Line: 0, Character: 14}, edits[0].Range.End)
+
+	err = client.Call("textDocument/unknown", map[string]any{}, nil)
+	assert.True(t, errors.As(err, &responseError))
+}
+
+func TestLspDestructuringSymbols(t *testing.T) {
+	client := startLspServer(t, "let [first, {name}] = [1, {\"name\": 2}]\nlog(first, name)\n")
+
+	var symbols []lsp.DocumentSymbol
+	assert.NoError(t, client.Call("textDocument/documentSymbol", lsp.DocumentSymbolParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+	}, &symbols))
+	if assert.Len(t, symbols, 2) {
+		assert.Equal(t, "first", symbols[0].Name)
+		assert.Equal(t, "name", symbols[1].Name)
+		assert.Equal(t, lsp.Position{Line: 0, Character: 13}, symbols[1].SelectionRange.Start)
+	}
+
+	var location lsp.Location
+	assert.NoError(t, client.Call("textDocument/definition", positionParams(1, 12), &location))
+	assert.Equal(t, lsp.Position{Line: 0, Character: 13}, location.Range.Start)
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+)
+
+func TestParsedMatches(t *testing.T) {
+	program := getProgram(t, `match (x) { 0 => "zero", [a, ...rest] if a > 0 => rest, {name, "age": -1} => name, _ => x, }`)
+	assert.Len(t, program.Statements, 1)
+
+	match, ok := program.Statements[0].(ast.ExpressionStatement).Expression.(ast.Match)
+	assert.True(t, ok)
+	assert.Len(t, match.Arms, 4)
+
+	array, ok := match.Arms[1].Pattern.(ast.ArrayPattern)
+	assert.True(t, ok)
+	assert.Len(t, array.Items, 1)
+	assert.Equal(t, "rest", array.Rest.Value)
+	assert.NotNil(t, match.Arms[1].Guard)
+
+	hashTable, ok := match.Arms[2].Pattern.(ast.HashTablePattern)
+	assert.True(t, ok)
+	assert.Equal(t, "name", hashTable.Keys[0].Value)
+	assert.Equal(t, "-1", hashTable.Values[1].String())
+
+	assert.Equal(t, `match (x) {0 => "zero", [a, ...rest] if a > 0 => rest, {"name": name, "age": -1} => name, _ => x}`,
+		match.String())
+}
+
+func TestMatchSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 1 2 }", "expected next token to be '=>', got INT instead"},
+		{"match (x) { 1 => 1 2 => 2 }", "expected next token to be '}', got INT instead"},
+		{"match (x) { a + 1 => 1 }", "expected next token to be '=>', got + instead"},
+		{"match (x) { fn() {} => 1 }", "expected a pattern, got FUNCTION instead"},
+		{"match (x) { [...rest, a] => 1 }", "expected next token to be ']', got , instead"},
+		{`match (x) { {1: a} => 1 }`, "expected next token to be 'STRING', got INT instead"},
+		{"match x { _ => 1 }", "expected next token to be '(', got IDENT instead"},
+	}
+
+	for _, test := range tests {
+		testSyntaxError(t, test.input, test.expected)
+	}
+}
+
+func TestEvaluatedMatches(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (1) { 0 => "zero", 1 => "one", _ => "many" }`, `"one"`},
+		{`match (7) { 0 => "zero", _ => "many" }`, `"many"`},
+		{`match (-1) { -1 => "minus one", _ => "other" }`, `"minus one"`},
+		{`match ("hi") { "hi" => 1, _ => 2 }`, "1"},
+		{`match (false) { true => 1, false => 2 }`, "2"},
+		{"match (5) { n => n * 2 }", "10"},
+		{"match ([]) { [] => 0, [x] => x, _ => -1 }", "0"},
+		{"match ([3]) { [] => 0, [x] => x, _ => -1 }", "3"},
+		{"match ([1, 2]) { [x] => x, _ => -1 }", "-1"},
+		{"match ([1, 2, 3]) { [first, ...rest] => [first, rest] }", "[1, [2, 3]]"},
+		{"match ([1]) { [first, ...rest] => rest }", "[]"},
+		{"match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }", "6"},
+		{"match ([1, 2]) { [1, x] => x, _ => 0 }", "2"},
+		{`match ({"name": "monkey", "age": 3}) { {name} => name }`, `"monkey"`},
+		{`match ({"kind": "circle", "r": 2}) { {"kind": "square"} => 0, {"kind": "circle", "r": r} => r * 3 }`, "6"},
+		{`match ({"a": 1}) { {"b": b} => b, _ => "no b" }`, `"no b"`},
+		{`match ({"point": [1, 2]}) { {"point": [x, y]} => x + y }`, "3"},
+		{`match ("text") { [x] => 1, {"a": a} => 2, _ => 3 }`, "3"},
+		// Guards see the bindings of their arm and fall through when false.
+		{"match (5) { n if n > 10 => \"big\", n if n > 0 => \"small\", _ => \"none\" }", `"small"`},
+		{"match ([1, 2]) { [a, b] if a > b => a, [a, b] => b }", "2"},
+		// Bindings are scoped to their arm.
+		{"let x = 1; let y = match (5) { x => x + 1 }; [x, y]", "[1, 6]"},
+		{"let limit = 3; match (4) { n if n > limit => limit, n => n }", "3"},
+		{"let f = fn(v) { return match (v) { [x, ...xs] => x + f(xs), [] => 0 } }; f([1, 2, 3])", "6"},
+		{"let a = [1, 2]; match (a) { [_, ...rest] => rest }; a", "[1, 2]"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (5) { 1 => 1, 2 => 2 }", "no match for value: 5"},
+		{`match ([1, "a"]) { [] => 0 }`, `no match for value: [1, "a"]`},
+		{"match (missing) { _ => 1 }", "identifier not found: missing"},
+		{"match (1) { x if missing => 1 }", "identifier not found: missing"},
+		{"match (1) { x => x + true }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, test := range tests {
+		testErrorObject(t, testEval(t, test.input), test.expected)
+	}
+}
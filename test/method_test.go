@@ -0,0 +1,96 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+func TestEvaluatedMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3].len()", "3"},
+		{"let a = [1]; a.push(2, 3); a", "[1, 2, 3]"},
+		{"let a = []; a.push(1).push(2)", "[1, 2]"},
+		{"[1, 2, 3].map(fn(x) { return x * 2 })", "[2, 4, 6]"},
+		{"[1, 2, 3, 4].filter(fn(x) { return x > 2 })", "[3, 4]"},
+		{"[1, 2, 3].reduce(fn(sum, x) { return sum + x }, 10)", "16"},
+		{`[1, "a", [2]].join("-")`, `"1-a-[2]"`},
+		{`[[1], 2].contains([1])`, "true"},
+		{`[1, 2].contains(3)`, "false"},
+		{`"a,b,c".split(",")`, `["a", "b", "c"]`},
+		{`"monkey".len()`, "6"},
+		{`"monkey".contains("key")`, "true"},
+		{`" Monkey ".trim().upper()`, `"MONKEY"`},
+		{`"Monkey".lower()`, `"monkey"`},
+		{`{"b": 1, "a": 2}.keys()`, `["a", "b"]`},
+		{`{"b": 1, "a": 2}.values()`, "[2, 1]"},
+		{`{"a": 1}.has("a")`, "true"},
+		{`{"a": 1}.len()`, "1"},
+		// Pipelines read left to right.
+		{`"3,1,2".split(",").map(fn(s) { return s + "!" }).join(" ")`, `"3! 1! 2!"`},
+		// Methods are values bound to what they were looked up on.
+		{"let a = [1, 2]; let size = a.len; a.push(3); size()", "3"},
+		{`let has = {"a": 1}.has; ["a", "b"].map(has)`, "[true, false]"},
+		{"let f = fn(a) { return a.len() }; f([1, 2])", "2"},
+		// Fields holding functions are called, not shadowed by methods.
+		{"struct Box { len }; let b = Box{len: fn() { return 42 }}; b.len()", "42"},
+		{"[fn(x) { return x + 1 }][0](1)", "2"},
+		{`{"f": fn() { return 1 }}["f"]()`, "1"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestMethodErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1].nope()", "unknown method: ARRAY.nope"},
+		{"5.len()", "unknown method: INTEGER.len"},
+		{"[1].len(1)", "wrong number of arguments to ARRAY.len: got=1, want=0"},
+		{"[1].push()", "wrong number of arguments to ARRAY.push: got=0, want=at least 1"},
+		{"[1].reduce(fn(a, b) { return a })", "wrong number of arguments to ARRAY.reduce: got=1, want=2"},
+		{"freeze([1]).push(2)", "cannot push to a frozen array"},
+		{`[1].map(fn(x) { return x + "a" })`, "type mismatch: INTEGER + STRING"},
+		{`"a".split(1)`, "argument type is not supported: got INTEGER"},
+		{"struct Point { x }; Point{x: 1}.y()", "unknown field: Point.y"},
+	}
+
+	for _, test := range tests {
+		testErrorObject(t, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestMethodsRegisteredByHosts(t *testing.T) {
+	evaluator.RegisterMethod(object.StringType, "repeat", object.Builtin{
+		Function: func(args ...object.Object) object.Object {
+			count := args[1].(object.Integer)
+			return object.String{Value: strings.Repeat(args[0].(object.String).Value, count.Value)}
+		},
+		Arity: object.Arity{Min: 1, Max: 1},
+	})
+	evaluator.RegisterMethod(object.IntegerType, "double", object.Builtin{
+		Function: func(args ...object.Object) object.Object {
+			return object.Integer{Value: args[0].(object.Integer).Value * 2}
+		},
+		Arity: object.Arity{Min: 0, Max: 0},
+	})
+
+	testInspect(t, `"ab".repeat(3)`, `"ababab"`)
+	testInspect(t, "let n = 4; n.double().double()", "16")
+	testErrorObject(t, testEval(t, `"ab".repeat()`), "wrong number of arguments to STRING.repeat: got=0, want=1")
+
+	_, ok := evaluator.LookupMethod(object.IntegerType, "double")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"double"}, evaluator.MethodNames(object.IntegerType))
+}
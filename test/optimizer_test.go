@@ -0,0 +1,174 @@
+package test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/optimizer"
+)
+
+// evalLogging evaluates a program and returns its value and what it logged.
+func evalLogging(program *ast.Program) (object.Object, string) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	flags := log.Flags()
+	log.SetFlags(0)
+	defer log.SetFlags(flags)
+
+	evaluated := evaluator.Eval(program, object.NewEnvironment())
+	return evaluated, output.String()
+}
+
+func TestOptimizerFoldsConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ast.Expression
+	}{
+		{"1 + 2 * 3", ast.Integer{Value: 7}},
+		{"-(10 - 4) / 2", ast.Integer{Value: -3}},
+		{`"mon" + "key"`, ast.String{Value: "monkey"}},
+		{"1 < 2 == true", ast.Boolean{Value: true}},
+		{`"a" != "a"`, ast.Boolean{Value: false}},
+		{"!true", ast.Boolean{Value: false}},
+		{"!5", ast.Boolean{Value: false}},
+		{`1 == "1"`, ast.Boolean{Value: false}},
+		{"true != 1", ast.Boolean{Value: true}},
+		{"let x = 4; let y = x * 2; y + 1", ast.Integer{Value: 9}},
+	}
+
+	for _, test := range tests {
+		program := optimizer.Optimize(getProgram(t, test.input))
+		last := program.Statements[len(program.Statements)-1].(ast.ExpressionStatement)
+
+		switch expected := test.expected.(type) {
+		case ast.Integer:
+			actual, ok := last.Expression.(ast.Integer)
+			assert.True(t, ok, test.input)
+			assert.Equal(t, expected.Value, actual.Value, test.input)
+		case ast.String:
+			actual, ok := last.Expression.(ast.String)
+			assert.True(t, ok, test.input)
+			assert.Equal(t, expected.Value, actual.Value, test.input)
+		case ast.Boolean:
+			actual, ok := last.Expression.(ast.Boolean)
+			assert.True(t, ok, test.input)
+			assert.Equal(t, expected.Value, actual.Value, test.input)
+		}
+	}
+}
+
+func TestOptimizerKeepsWhatMayFail(t *testing.T) {
+	inputs := []string{
+		"1 / 0",
+		`1 + "a"`,
+		"true + false",
+		"-true",
+		// x is reassigned, so it is not a constant.
+		"let x = 1; x = 2; x + 1",
+	}
+
+	for _, input := range inputs {
+		program := optimizer.Optimize(getProgram(t, input))
+		last := program.Statements[len(program.Statements)-1].(ast.ExpressionStatement)
+
+		_, folded := last.Expression.(ast.Integer)
+		assert.False(t, folded, input)
+	}
+}
+
+func TestOptimizerRemovesDeadCode(t *testing.T) {
+	program := optimizer.Optimize(getProgram(t, `
+let f = fn() {
+    if (false) { log("never"); } else if (1 > 2) { log("never"); } else if (true) { log("always"); } else { log("never"); }
+    return 1;
+    log("after return");
+};
+`))
+
+	fn := program.Statements[0].(ast.LetStatement).Value.(ast.Function)
+	assert.Len(t, fn.Body.Statements, 2)
+
+	branches := fn.Body.Statements[0].(ast.ExpressionStatement).Expression.(ast.If)
+	assert.Empty(t, branches.Conditions)
+	assert.Equal(t, `call fn log with args (["always"])`, branches.Alternative.String())
+}
+
+func TestOptimizerHoistsLoopInvariants(t *testing.T) {
+	program := optimizer.Optimize(getProgram(t, `
+let n = 3;
+n = n + 1;
+let i = 0;
+let total = 0;
+while (i < n * 2) {
+    total = total + n * n;
+    i = i + 1;
+}
+`))
+
+	var lets []string
+	for _, statement := range program.Statements {
+		if let, ok := statement.(ast.LetStatement); ok {
+			lets = append(lets, let.Name.Value)
+		}
+	}
+	assert.Equal(t, []string{"n", "i", "total", "loop$1", "loop$2"}, lets)
+
+	// Loops calling functions and expressions of names assigned in the loop
+	// are left alone.
+	for _, input := range []string{
+		"let n = 0; n = 1; let i = 0; while (i < n * 2) { log(i); i = i + 1; }",
+		"let n = 0; let i = 0; while (i < 3) { n = i * 2; i = i + 1; }",
+		`let n = "a"; n = "b"; let i = 0; while (i < 3) { log(n + n); i = i + 1; }`,
+	} {
+		program := optimizer.Optimize(getProgram(t, input))
+		for _, statement := range program.Statements {
+			if let, ok := statement.(ast.LetStatement); ok {
+				assert.NotContains(t, let.Name.Value, "$", input)
+			}
+		}
+	}
+}
+
+func TestOptimizerPreservesSemantics(t *testing.T) {
+	inputs := []string{
+		"let x = 5; let y = x * 2 + 1; log(x, y, -x, !x, x == 5, y != 11)",
+		`let greeting = "hello"; let name = "monkey"; log(greeting + " " + name)`,
+		"let t = true; if (t) { log(1) } else { log(2) }; if (!t) { log(3) }",
+		"let x = 1; let f = fn() { return x + 1 }; x = 10; log(f())",
+		"let f = fn() { return y }; let y = 3; log(f())",
+		"let x = 1; if (true) { let x = 2; log(x) }; log(x)",
+		"let x = 1; let f = fn(x) { return x * 2 }; log(f(21), x)",
+		"let a = 2; let b = a; log(b * b * b)",
+		`let key = "k"; let h = {key: 1, "v": key}; log(h["k"], h["v"])`,
+		"let f = fn(n) { if (n < 1) { return 0 } return n + f(n - 1); log(n) }; log(f(10))",
+		"let x = 1; 10 / (x - 1)",
+		"let s = 0; let i = 0; while (i < 10) { s = s + i * 3; i = i + 1 }; log(s)",
+		`let n = 7; n = n - 2; let i = 0; let s = 0;
+		 while (i < n * n) { let j = 0; while (j < n + 1) { s = s + n * 2 - j; j = j + 1 }; i = i + 1 }
+		 log(s)`,
+		"let n = 3; n = n * 2; let i = 0; while (false) { i = n * 100 }; log(i)",
+		"let i = 0; while (i < 3) { if (i == 1) { return i * 100 }; i = i + 1 }",
+		"let arr = [1, 2 + 3, 4 * 4]; log(arr[1], len(arr))",
+		"let x = 1; x",
+		"undefined + 1",
+	}
+
+	for _, input := range inputs {
+		program := getProgram(t, input)
+
+		expected, expectedOutput := evalLogging(program)
+		actual, actualOutput := evalLogging(optimizer.Optimize(program))
+
+		assert.Equal(t, evaluator.Inspect(expected), evaluator.Inspect(actual), input)
+		assert.Equal(t, expectedOutput, actualOutput, input)
+	}
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+)
+
+func TestParsedParameters(t *testing.T) {
+	program := getProgram(t, "fn(x, y = 10, ...rest) { return x }; f(...args, 1)")
+
+	fn := program.Statements[0].(ast.ExpressionStatement).Expression.(ast.Function)
+	assert.Len(t, fn.Parameters, 2)
+	withDefault, ok := fn.Parameters[1].(ast.DefaultPattern)
+	assert.True(t, ok)
+	assert.Equal(t, "y = 10", withDefault.String())
+	assert.Equal(t, "rest", fn.Rest.Value)
+
+	call := program.Statements[1].(ast.ExpressionStatement).Expression.(ast.Call)
+	spread, ok := call.Arguments[0].(ast.Spread)
+	assert.True(t, ok)
+	assert.Equal(t, "...args", spread.String())
+}
+
+func TestParameterSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(...rest, x) {}", "expected next token to be ')', got , instead"},
+		{"fn(...) {}", "expected next token to be 'IDENT', got ) instead"},
+		{"fn(x = ) {}", "parse function for token type ')' is not implemented"},
+		{"f(...)", "parse function for token type ')' is not implemented"},
+		{"let x = ...y", "parse function for token type '...' is not implemented"},
+	}
+
+	for _, test := range tests {
+		testSyntaxError(t, test.input, test.expected)
+	}
+}
+
+func TestEvaluatedParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(x, y = 10) { return x + y }; [f(1), f(1, 2)]", "[11, 3]"},
+		{"let f = fn(x, y = x * 2) { return y }; f(4)", "8"},
+		{"let f = fn(x = 1, y = 2) { return [x, y] }; [f(), f(5)]", "[[1, 2], [5, 2]]"},
+		{"let f = fn(x = missing) { return x }; f(1)", "1"},
+		{"let n = 1; let f = fn(x = n) { return x }; n = 2; f()", "2"},
+		{"let f = fn(first, ...rest) { return [first, rest] }; f(1, 2, 3)", "[1, [2, 3]]"},
+		{"let f = fn(first, ...rest) { return rest }; f(1)", "[]"},
+		{"let f = fn(...all) { return len(all) }; [f(), f(1, 2)]", "[0, 2]"},
+		{"let f = fn(x, y = 2, ...rest) { return [x, y, rest] }; [f(1), f(1, 3, 4)]", "[[1, 2, []], [1, 3, [4]]]"},
+		{"let f = fn({name = \"anonymous\"} = {}) { return name }; f()", `"anonymous"`},
+		{"let f = fn(...rest) { rest[0] = 9; return rest }; let a = [1]; f(...a); a", "[1]"},
+		// Spreading passes the items of arrays as arguments.
+		{"let add = fn(a, b, c) { return a + b + c }; add(...[1, 2, 3])", "6"},
+		{"let add = fn(a, b, c) { return a + b + c }; add(1, ...[2], ...[3])", "6"},
+		{"let f = fn(...rest) { return rest }; f(...[], 1, ...[2, 3])", "[1, 2, 3]"},
+		{"len(...[[1, 2]])", "2"},
+		{"[1, 2].push(...[3, 4])", "[1, 2, 3, 4]"},
+		{"let sum = fn(total, ...xs) { return match (xs) { [] => total, [x, ...more] => sum(total + x, ...more) } }; sum(0, 1, 2, 3)", "6"},
+		{"let apply = fn(f, ...args) { return f(...args) }; apply(fn(a, b) { return a * b }, 3, 4)", "12"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestParameterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(x, y) { return x }; f(1)", "wrong number of arguments: got=1, want=2"},
+		{"let f = fn(x) { return x }; f(1, 2)", "wrong number of arguments: got=2, want=1"},
+		{"let f = fn() { return 1 }; f(1)", "wrong number of arguments: got=1, want=0"},
+		{"let f = fn(x, y = 1) { return x }; f()", "wrong number of arguments: got=0, want=1 to 2"},
+		{"let f = fn(x, y = 1) { return x }; f(1, 2, 3)", "wrong number of arguments: got=3, want=1 to 2"},
+		{"let f = fn(x, y, ...rest) { return x }; f(1)", "wrong number of arguments: got=1, want=at least 2"},
+		{"let f = fn(x) { return x }; f(...[1, 2])", "wrong number of arguments: got=2, want=1"},
+		{"let f = fn(x = missing) { return x }; f()", "identifier not found: missing"},
+		{"let f = fn(...rest) { return rest }; f(...5)", "cannot spread INTEGER: not an array or an iterator"},
+		{"let f = fn(...rest) { return rest }; f(...missing)", "identifier not found: missing"},
+	}
+
+	for _, test := range tests {
+		testErrorObject(t, testEval(t, test.input), test.expected)
+	}
+}
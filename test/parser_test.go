@@ -682,3 +682,42 @@ func getProgram(t *testing.T, input string) *ast.Program {
 
 	return program
 }
+
+// testSyntaxError parses input and checks that the first syntax error it
+// reports has the expected message.
+func testSyntaxError(t *testing.T, input string, expected string) {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	p.ParseProgram()
+	if assert.NotEmpty(t, p.Errors(), input) {
+		assert.Equal(t, expected, p.Errors()[0].Message, input)
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	p := parser.New(lexer.New("let x = 5\nlet = 10"))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors())
+	assert.Equal(t, 2, p.Errors()[0].Line)
+	assert.Equal(t, 5, p.Errors()[0].Column)
+}
+
+func TestParserErrorsInsteadOfPanics(t *testing.T) {
+	inputs := []string{
+		"if (x) {} else if",
+		"fn(1) {}",
+		"fn(x, 2) {}",
+		`{["a"]`,
+		`{"a": 1`,
+		"{len(1): 2}",
+	}
+
+	for _, input := range inputs {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+
+		assert.NotEmpty(t, p.Errors(), input)
+	}
+}
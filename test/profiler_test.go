@@ -0,0 +1,115 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/profiler"
+)
+
+const profiledProgram = `let fib = fn(n) {
+    if (n < 2) {
+        return n;
+    }
+    return fib(n - 1) + fib(n - 2);
+};
+let words = fn() {
+    return ["a" + "b", "c"];
+};
+fib(5);
+words();
+`
+
+func profile(t *testing.T) *profiler.Profiler {
+	prof := profiler.New("profiled.monkey")
+	env := object.NewEnvironment()
+	prof.Attach(env)
+
+	evaluated := evaluator.Eval(getProgram(t, profiledProgram), env)
+	prof.Stop()
+
+	assert.NotEqual(t, object.ErrorType, evaluated.Type())
+	return prof
+}
+
+func TestProfilerFunctions(t *testing.T) {
+	prof := profile(t)
+
+	functions := make(map[string]profiler.FunctionStats)
+	for _, f := range prof.Functions() {
+		functions[f.Name] = f
+		assert.LessOrEqual(t, f.Self, f.Cumulative, f.Name)
+	}
+
+	assert.Equal(t, "main", prof.Functions()[0].Name)
+	assert.Equal(t, prof.Duration(), functions["main"].Cumulative)
+
+	assert.Equal(t, 1, functions["main"].Calls)
+	assert.Equal(t, 15, functions["fib"].Calls)
+	assert.Equal(t, 1, functions["words"].Calls)
+
+	// The two function literals in main, and the string and array in words.
+	assert.Equal(t, 2, functions["main"].Allocations)
+	assert.Equal(t, 0, functions["fib"].Allocations)
+	assert.Equal(t, 2, functions["words"].Allocations)
+}
+
+func TestProfilerLines(t *testing.T) {
+	prof := profile(t)
+
+	hits := make(map[int]int)
+	for _, l := range prof.Lines() {
+		hits[l.Line] = l.Hits
+	}
+
+	assert.Equal(t, map[int]int{1: 1, 2: 15, 3: 8, 5: 7, 7: 1, 8: 1, 10: 1, 11: 1}, hits)
+}
+
+func TestProfilerReports(t *testing.T) {
+	prof := profile(t)
+
+	var text strings.Builder
+	assert.NoError(t, prof.WriteText(&text, profiledProgram))
+	assert.Contains(t, text.String(), "Total time:")
+	assert.Regexp(t, `\s15\s.*\sfib\n`, text.String())
+	assert.Regexp(t, `\s7\s.*\s5: return fib\(n - 1\) \+ fib\(n - 2\);\n`, text.String())
+
+	var pprof bytes.Buffer
+	assert.NoError(t, prof.WritePprof(&pprof))
+
+	reader, err := gzip.NewReader(&pprof)
+	assert.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"time", "nanoseconds", "allocations", "main", "fib", "words", "profiled.monkey"} {
+		assert.Contains(t, string(content), name)
+	}
+}
+
+// Tasks running in parallel can share a profiler. Run with -race, nothing
+// of it races.
+func TestProfilerOfParallelTasks(t *testing.T) {
+	prof := profiler.New("parallel.monkey")
+	env := object.NewEnvironment()
+	prof.Attach(env)
+
+	evaluated := evaluator.Eval(getProgram(t, parallelProgram), env)
+	prof.Stop()
+	assert.Equal(t, "[100, 100, 100]", evaluator.Inspect(evaluated))
+
+	functions := make(map[string]profiler.FunctionStats)
+	for _, f := range prof.Functions() {
+		functions[f.Name] = f
+	}
+	assert.Equal(t, 300, functions["add"].Calls)
+	assert.Equal(t, 3, functions["worker"].Calls)
+	assert.NoError(t, prof.WritePprof(io.Discard))
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/resolver"
+)
+
+// testInspect evaluates a program as written and with its variables bound
+// to slots, and checks that both evaluate to the expected value.
+func testInspect(t *testing.T, input, expected string) {
+	t.Helper()
+
+	assert.Equal(t, expected, evaluator.Inspect(testEvalWithError(t, input)), input)
+
+	bound := evaluator.Eval(resolver.Bind(getProgram(t, input)), object.NewEnvironment())
+	assert.Equal(t, expected, evaluator.Inspect(bound), input)
+}
+
+func TestCollectionsAreReferences(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2]; let b = a; b[0] = 5; a", "[5, 2]"},
+		{`let h = {"x": 1}; let g = h; g["y"] = 2; h`, `{"x": 1, "y": 2}`},
+		{"let a = [1, 2]; let set = fn(array) { array[1] = 7 }; set(a); a", "[1, 7]"},
+		{`let inner = [1]; let outer = {"inner": inner}; outer["inner"][0] = 3; inner`, "[3]"},
+		{`let inner = {"n": 1}; let outer = [inner, inner]; outer[0]["n"] = 4; outer[1]["n"]`, "4"},
+		{"let items = [0]; let bump = fn() { items[0] = items[0] + 1 }; bump(); bump(); items", "[2]"},
+		{"let a = [[0], [0]]; let row = a[1]; row[0] = 9; a", "[[0], [9]]"},
+		{"let a = [1]; let b = a; a = [2]; b", "[1]"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestAppendNeverCorruptsSiblings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2]; let b = append(a, 3); let c = append(a, 4); [a, b, c]", "[[1, 2], [1, 2, 3], [1, 2, 4]]"},
+		{"let a = append([1], 2); let b = append(a, 3); let c = append(a, 4); b[0] = 9; [a, b, c]", "[[1, 2], [9, 2, 3], [1, 2, 4]]"},
+		{"let a = [1]; let b = append(a, 2); a[0] = 5; b", "[1, 2]"},
+		{"let a = [[1]]; let b = append(a, 2); b[0][0] = 5; a", "[[5]]"},
+		{"let a = [1, 2]; let b = shift(a); b[0] = 7; [a, b]", "[[1, 2], [7]]"},
+		{"let a = []; let b = shift(a); b = append(b, 1); a", "[]"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, [2]]; let b = copy(a); b[0] = 5; b[1][0] = 6; [a, b]", "[[1, [6]], [5, [6]]]"},
+		{`let h = {"x": {"y": 1}}; let g = copy(h); g["z"] = 2; g["x"]["y"] = 3; h`, `{"x": {"y": 3}}`},
+		{"let a = freeze([1]); let b = copy(a); b[0] = 2; [a, b]", "[[1], [2]]"},
+		{`copy("text")`, `"text"`},
+		{"let a = [1, [2]]; let b = deepCopy(a); b[0] = 5; b[1][0] = 6; [a, b]", "[[1, [2]], [5, [6]]]"},
+		{`let h = {"x": {"y": 1}}; let g = deepCopy(h); g["x"]["y"] = 3; h`, `{"x": {"y": 1}}`},
+		{`let a = freeze({"x": [1]}); let b = deepCopy(a); b["x"][0] = 2; [a, b]`, `[{"x": [1]}, {"x": [2]}]`},
+		// Items held twice are copied once, and stay shared in the copy.
+		{"let item = [1]; let a = [item, item]; let b = deepCopy(a); b[0][0] = 2; [a, b]", "[[[1], [1]], [[2], [2]]]"},
+		{"deepCopy(5)", "5"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestDeepCopyOfCycles(t *testing.T) {
+	evaluated := testEvalWithError(t, "let a = [1, 2]; a[1] = a; let b = deepCopy(a); b[0] = 3; [a, b]")
+
+	pair := evaluated.(*object.Array)
+	a, b := pair.Items[0].(*object.Array), pair.Items[1].(*object.Array)
+
+	assert.NotSame(t, a, b)
+	assert.Same(t, a, a.Items[1])
+	assert.Same(t, b, b.Items[1])
+	assert.Equal(t, object.Integer{Value: 1}, a.Items[0])
+	assert.Equal(t, object.Integer{Value: 3}, b.Items[0])
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/ast"
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+)
+
+func TestParsedStructs(t *testing.T) {
+	program := getProgram(t, "struct Point { x, y, }\nlet p = Point{x: 1, y: 2}\np.x = p.y")
+	assert.Len(t, program.Statements, 3)
+
+	declaration, ok := program.Statements[0].(ast.StructStatement)
+	assert.True(t, ok)
+	assert.Equal(t, "Point", declaration.Name.Value)
+	assert.Len(t, declaration.Fields, 2)
+	assert.Equal(t, "struct Point { x, y }", declaration.String())
+
+	let := program.Statements[1].(ast.LetStatement)
+	literal, ok := let.Value.(ast.StructLiteral)
+	assert.True(t, ok)
+	assert.Equal(t, "Point{x: 1, y: 2}", literal.String())
+
+	assignment := program.Statements[2].(ast.ExpressionStatement).Expression.(ast.Infix)
+	assert.Equal(t, "(p).x = (p).y", assignment.String())
+}
+
+func TestStructSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, x }", "duplicate field: x"},
+		{"struct Point { x, y }; Point{x: 1, x: 2}", "duplicate field: x"},
+		{"struct { x }", "expected next token to be 'IDENT', got { instead"},
+		{"struct Point { 1 }", "expected next token to be 'IDENT', got INT instead"},
+		{"struct Point { x y }", "expected next token to be '}', got IDENT instead"},
+		{"p.1", "expected next token to be 'IDENT', got INT instead"},
+		{`Point{"x": 1}`, "expected next token to be 'IDENT', got STRING instead"},
+	}
+
+	for _, test := range tests {
+		testSyntaxError(t, test.input, test.expected)
+	}
+}
+
+func TestEvaluatedStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y }; Point{x: 1, y: 2}", "Point{x: 1, y: 2}"},
+		{"struct Point { x, y }; Point{y: 2, x: 1}", "Point{x: 1, y: 2}"},
+		{"struct Point { x, y }; Point{x: 1}", "Point{x: 1, y: null}"},
+		{"struct Point { x, y }; let p = Point{x: 1, y: 2}; p.x + p.y", "3"},
+		{"struct Point { x, y }; let p = Point{x: 1, y: 2}; p.x = 5; p", "Point{x: 5, y: 2}"},
+		{`struct User { name }; let u = User{name: "Monkey"}; u.name = u.name + "!"; u.name`, `"Monkey!"`},
+		{"struct Node { value, next }; let list = Node{value: 1, next: Node{value: 2}}; list.next.value", "2"},
+		{"struct Node { value, next }; let n = Node{value: 1}; n.next = n; n", "Node{value: 1, next: Node{...}}"},
+		{`struct Point { x, y }; let points = [Point{x: 1}]; points[0].x = 7; points`, "[Point{x: 7, y: null}]"},
+		{`struct Box { items }; let b = Box{items: [1]}; b.items[0] = 2; b`, "Box{items: [2]}"},
+		// Structs are references, like arrays and hash tables.
+		{"struct Point { x, y }; let a = Point{x: 1}; let b = a; b.x = 2; a.x", "2"},
+		{"struct Point { x, y }; let a = Point{x: 1}; let b = copy(a); b.x = 2; a.x", "1"},
+		{"struct Point { x, y }; let move = fn(p) { p.x = p.x + 1 }; let p = Point{x: 1}; move(p); p.x", "2"},
+		{"let f = fn() { struct Pair { left, right }; return Pair{left: 1, right: 2} }; f().right", "2"},
+		{"struct Point { x, y }; Point", "struct Point { x, y }"},
+		{"struct Point { x, y }; Point{x: 1, y: 2} == Point{x: 1, y: 2}", "true"},
+		{"struct Point { x, y }; Point{x: 1, y: 2} == Point{x: 1, y: 3}", "false"},
+		{"struct A { x }; struct B { x }; A{x: 1} == B{x: 1}", "false"},
+		{`struct A { x }; A{x: 1} == {"x": 1}`, "false"},
+	}
+
+	for _, test := range tests {
+		testInspect(t, test.input, test.expected)
+	}
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y }; let p = Point{x: 1}; p.z", "unknown field: Point.z"},
+		{"struct Point { x, y }; let p = Point{x: 1}; p.z = 1", "unknown field: Point.z"},
+		{"struct Point { x, y }; Point{x: 1, z: 2}", "unknown field: Point.z"},
+		{`let h = {"x": 1}; h.x`, "unknown method: HASHTABLE.x"},
+		{"5.x", "unknown method: INTEGER.x"},
+		{"let Point = 5; Point{x: 1}", "not a struct type: Point"},
+		{"Point{x: 1}", "identifier not found: Point"},
+		{"struct Point { x, y }; Point{x: missing}", "identifier not found: missing"},
+		{"struct Point { x, y }; let p = freeze(Point{x: [1]}); p.x = 2", "cannot assign to a field of a frozen struct"},
+		{"struct Point { x, y }; let p = freeze(Point{x: [1]}); p.x[0] = 2", "cannot assign to an index of a frozen array"},
+	}
+
+	for _, test := range tests {
+		testErrorObject(t, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestStructsAreCopiedDeeply(t *testing.T) {
+	evaluated := testEvalWithError(t, "struct Node { next }; let a = Node{}; a.next = a; let b = deepCopy(a); [a, b]")
+
+	pair := evaluated.(*object.Array)
+	a, b := pair.Items[0].(*object.Struct), pair.Items[1].(*object.Struct)
+
+	assert.NotSame(t, a, b)
+	assert.Same(t, a, a.Fields["next"])
+	assert.Same(t, b, b.Fields["next"])
+	assert.True(t, evaluator.Equal(a, b))
+}
+
+func TestStructAssertions(t *testing.T) {
+	evaluated := testEval(t, `
+struct Point { x, y }
+assertEqual(Point{x: 1, y: [2]}, Point{x: 1, y: [3]})
+`)
+	testErrorObject(t, evaluated, `assertEqual failed
+    expected: Point{x: 1, y: [2]}
+    actual:   Point{x: 1, y: [3]}
+    at .y[0]: expected 2, actual 3`)
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/testrunner"
+)
+
+var testFiles = []testrunner.File{
+	{Path: "counter_test.monkey", Source: `let counter = {"count": 0};
+let increment = fn() { counter["count"] = counter["count"] + 1; return counter["count"]; };
+
+let testFirstIncrement = fn() {
+    assertEqual(1, increment());
+};
+
+let testIsolated = fn() {
+    assertEqual(1, increment(), "each test evaluates the file again");
+};
+
+let testLists = fn() {
+    assertEqual([1, [2, 3]], [1, [2, 4]]);
+};
+
+let testThrows = fn() {
+    assertThrows(fn() { return -true; }, "unknown operator");
+};
+
+let helper = fn() { assert(false); };
+let testWithArgument = fn(x) { assert(false); };
+`},
+	{Path: "broken_test.monkey", Source: "let testBroken = fn() { let = 1 };"},
+}
+
+func TestRunnerResults(t *testing.T) {
+	results := testrunner.Run(testFiles, testrunner.Options{Parallel: 4})
+
+	names := make([]string, len(results))
+	for i, result := range results {
+		names[i] = result.Test.Name
+	}
+	assert.Equal(t, []string{"broken_test.monkey", "testFirstIncrement", "testIsolated", "testLists", "testThrows"}, names)
+
+	assert.False(t, results[0].Passed)
+	assert.Contains(t, results[0].Message, "syntax errors:")
+	assert.True(t, results[1].Passed)
+	assert.True(t, results[2].Passed, results[2].Message)
+	assert.Equal(t, 8, results[2].Test.Line)
+	assert.False(t, results[3].Passed)
+	assert.Equal(t, `assertEqual failed
+    expected: [1, [2, 3]]
+    actual:   [1, [2, 4]]
+    at [1][1]: expected 3, actual 4`, results[3].Message)
+	assert.True(t, results[4].Passed, results[4].Message)
+	assert.Equal(t, 2, testrunner.Failed(results))
+}
+
+func TestRunnerFilterAndReports(t *testing.T) {
+	results := testrunner.Run(testFiles[:1], testrunner.Options{Filter: regexp.MustCompile("Lists|Throws")})
+	assert.Len(t, results, 2)
+
+	var text strings.Builder
+	assert.NoError(t, testrunner.WriteText(&text, results, false))
+	assert.True(t, strings.HasPrefix(text.String(), "--- FAIL: testLists (counter_test.monkey:12)"))
+	assert.True(t, strings.HasSuffix(text.String(), "FAIL: 1 of 2 tests failed\n"))
+
+	var tap strings.Builder
+	assert.NoError(t, testrunner.WriteTAP(&tap, results))
+	assert.Contains(t, tap.String(), "TAP version 13\n1..2\nnot ok 1 - counter_test.monkey: testLists\n")
+	assert.Contains(t, tap.String(), "ok 2 - counter_test.monkey: testThrows\n")
+
+	var junit strings.Builder
+	assert.NoError(t, testrunner.WriteJUnit(&junit, results))
+	assert.Contains(t, junit.String(), `<testsuite name="counter_test.monkey" tests="2" failures="1"`)
+	assert.Contains(t, junit.String(), `<failure message="assertEqual failed">`)
+}
+
+func TestRunnerDeterministic(t *testing.T) {
+	files := []testrunner.File{{Path: "tasks_test.monkey", Source: `let testOrder = fn() {
+    let order = [];
+    let c = channel();
+    let tasks = [spawn fn() { order.push(1); c.send(1) }, spawn fn() { order.push(2); c.send(2) }];
+    assertEqual([1, 2], [c.receive(), c.receive()]);
+    wait(tasks);
+    assertEqual([1, 2], order);
+};
+
+let testDeadlock = fn() {
+    channel().receive();
+};
+`}}
+
+	results := testrunner.Run(files, testrunner.Options{Deterministic: true})
+	assert.Len(t, results, 2)
+	assert.True(t, results[0].Passed, results[0].Message)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, "deadlock: all tasks are blocked", results[1].Message)
+}
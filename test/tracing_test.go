@@ -0,0 +1,238 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/timur-makarov/monkey-interpreter/internal/evaluator"
+	"github.com/timur-makarov/monkey-interpreter/internal/object"
+	"github.com/timur-makarov/monkey-interpreter/internal/tracing"
+)
+
+const tracedProgram = `let add = fn(a, b) {
+    return a + b;
+};
+let total = len([add(1, 2)]);
+let fail = fn() {
+    return 1 + true;
+};
+fail();
+`
+
+func trace(t *testing.T, tracer evaluator.Tracer) object.Object {
+	env := object.NewEnvironment()
+	evaluator.Trace(env, tracer)
+	return evaluator.Eval(getProgram(t, tracedProgram), env)
+}
+
+func TestJSONTrace(t *testing.T) {
+	var out bytes.Buffer
+	writer := tracing.NewJSONWriter(&out)
+	trace(t, writer)
+	assert.NoError(t, writer.Err())
+
+	var events []tracing.Event
+	var enters, exits int
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event tracing.Event
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		assert.NotEmpty(t, event.Time)
+
+		switch event.Event {
+		case "enter":
+			enters++
+		case "exit":
+			exits++
+		default:
+			event.Time = ""
+			events = append(events, event)
+		}
+	}
+
+	assert.Equal(t, enters, exits)
+	assert.Equal(t, []tracing.Event{
+		{Event: "call", Depth: 1, Function: "add", Arguments: []string{"1", "2"}},
+		{Event: "return", Depth: 1, Function: "add", Value: "3"},
+		{Event: "builtin", Function: "len", Arguments: []string{"[3]"}, Value: "1"},
+		{Event: "call", Depth: 1, Function: "fail"},
+		{Event: "error", Depth: 1, Node: "Infix", Line: 6, Column: 12, Error: "type mismatch: INTEGER + BOOLEAN"},
+		{Event: "return", Depth: 1, Function: "fail", Value: "ERROR: type mismatch: INTEGER + BOOLEAN"},
+	}, events)
+}
+
+// A function ending with a call in tail position returns no value of its
+// own: the call is made in its place.
+func TestJSONTraceOfTailCalls(t *testing.T) {
+	var out bytes.Buffer
+	writer := tracing.NewJSONWriter(&out)
+
+	env := object.NewEnvironment()
+	evaluator.Trace(env, writer)
+	evaluator.Eval(getProgram(t, "let count = fn(n) { if (n < 1) { return 7 } return count(n - 1) }; count(2)"), env)
+	assert.NoError(t, writer.Err())
+
+	var returns []tracing.Event
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event tracing.Event
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		if event.Event == "return" {
+			event.Time = ""
+			returns = append(returns, event)
+		}
+	}
+	assert.Equal(t, []tracing.Event{
+		{Event: "return", Depth: 1, Function: "count"},
+		{Event: "return", Depth: 1, Function: "count"},
+		{Event: "return", Depth: 1, Function: "count", Value: "7"},
+	}, returns)
+}
+
+// The function assertThrows calls is traced as a call nested in the test
+// calling it, as functions passed to builtins are, anonymously.
+func TestJSONTraceOfAssertThrows(t *testing.T) {
+	var out bytes.Buffer
+	writer := tracing.NewJSONWriter(&out)
+
+	env := object.NewEnvironment()
+	evaluator.Trace(env, writer)
+	evaluator.Eval(getProgram(t, "let fail = fn() { return -true }; let test = fn() { assertThrows(fail) }; test()"), env)
+	assert.NoError(t, writer.Err())
+
+	var calls []tracing.Event
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event tracing.Event
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		if event.Event == "call" || event.Event == "builtin" {
+			event.Time = ""
+			calls = append(calls, event)
+		}
+	}
+	assert.Equal(t, []tracing.Event{
+		{Event: "call", Depth: 1, Function: "test"},
+		{Event: "call", Depth: 2, Function: "anonymous"},
+		{Event: "builtin", Depth: 1, Function: "assertThrows", Arguments: []string{"fn()"}, Value: "null"},
+	}, calls)
+}
+
+func TestSpanExporter(t *testing.T) {
+	var out bytes.Buffer
+	exporter := tracing.NewSpanExporter(&out, "traced.monkey")
+	trace(t, exporter)
+	assert.NoError(t, exporter.Close())
+
+	batches := readSpans(t, out.String())
+	assert.Len(t, batches, 1)
+	spans := batches[0]
+	assert.Len(t, spans, 3)
+
+	// Spans are written in the order they end.
+	add, fail, root := spans[0], spans[1], spans[2]
+	assert.Equal(t, "traced.monkey", root.Name)
+	assert.Empty(t, root.ParentSpanID)
+	assert.Equal(t, tracing.StatusError, root.Status.Code)
+	assert.Equal(t, "builtin len", root.Events[0].Name)
+
+	assert.Equal(t, "add", add.Name)
+	assert.Equal(t, root.SpanID, add.ParentSpanID)
+	assert.Equal(t, root.TraceID, add.TraceID)
+	assert.Equal(t, tracing.StatusUnset, add.Status.Code)
+	assert.Contains(t, add.Attributes, tracing.Attribute{Key: "code.lineno", Value: intValue("4")})
+
+	assert.Equal(t, "fail", fail.Name)
+	assert.Equal(t, tracing.Status{Code: tracing.StatusError, Message: "type mismatch: INTEGER + BOOLEAN"}, fail.Status)
+	assert.Equal(t, "exception", fail.Events[0].Name)
+}
+
+// The spans of a long running script are written while it runs, not all
+// of them kept until it ends.
+func TestSpanExporterWritesBatches(t *testing.T) {
+	var out bytes.Buffer
+	exporter := tracing.NewSpanExporter(&out, "loop.monkey")
+
+	env := object.NewEnvironment()
+	evaluator.Trace(env, exporter)
+	evaluator.Eval(getProgram(t, `
+let id = fn(x) { return x };
+let i = 0;
+while (i < 600) { id(i); i = i + 1 }`), env)
+
+	written := readSpans(t, out.String())
+	assert.Len(t, written, 2)
+	for _, batch := range written {
+		assert.Len(t, batch, tracing.SpanBatchSize)
+	}
+
+	assert.NoError(t, exporter.Close())
+	batches := readSpans(t, out.String())
+	assert.Len(t, batches, 3)
+	assert.Len(t, batches[2], 600+1-2*tracing.SpanBatchSize)
+	assert.Equal(t, "loop.monkey", batches[2][len(batches[2])-1].Name)
+}
+
+// readSpans decodes the lines a SpanExporter wrote into their spans.
+func readSpans(t *testing.T, out string) [][]tracing.Span {
+	var batches [][]tracing.Span
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var document struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []tracing.Span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(line), &document))
+		batches = append(batches, document.ResourceSpans[0].ScopeSpans[0].Spans)
+	}
+	return batches
+}
+
+func intValue(s string) tracing.Value {
+	return tracing.Value{IntValue: &s}
+}
+
+const parallelProgram = `let add = fn(a, b) { return a + b };
+let worker = fn() {
+    let i = 0;
+    while (i < 100) { i = add(i, 1) }
+    return i;
+};
+wait([spawn worker(), spawn worker(), spawn worker()])`
+
+// Tasks running in parallel can share tracers, which write whole lines.
+// Run with -race, nothing of it races.
+func TestTracersOfParallelTasks(t *testing.T) {
+	var lines, spans bytes.Buffer
+	writer := tracing.NewJSONWriter(&lines)
+	exporter := tracing.NewSpanExporter(&spans, "parallel.monkey")
+
+	env := object.NewEnvironment()
+	evaluator.Trace(env, tracing.Multi{writer, exporter})
+	evaluated := evaluator.Eval(getProgram(t, parallelProgram), env)
+	assert.Equal(t, "[100, 100, 100]", evaluator.Inspect(evaluated))
+	assert.NoError(t, writer.Err())
+	assert.NoError(t, exporter.Close())
+
+	calls := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(lines.String()), "\n") {
+		var event tracing.Event
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		calls[event.Event+" "+event.Function]++
+	}
+	assert.Equal(t, 300, calls["call add"])
+	assert.Equal(t, 300, calls["return add"])
+	assert.Equal(t, 3, calls["return worker"])
+
+	ended := 0
+	for _, batch := range readSpans(t, spans.String()) {
+		ended += len(batch)
+	}
+	assert.Equal(t, 300+3+1, ended)
+}